@@ -25,7 +25,7 @@ func main() {
 	envs := bpenv.ReadEnvs()
 	// Set Logger
 	logger := zap.Must(zap.NewProduction())
-	if envs.AppMode != "release" {
+	if envs.AppMode != bpenv.ReleaseMode {
 		logger = zap.Must(zap.NewDevelopment())
 	}
 	zap.ReplaceGlobals(logger)
@@ -47,6 +47,95 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:   "seed",
+			Action: commands.SeedCommand(envs),
+			Usage:  "Load the fixtures of an environment into the database and optionally generate fake users",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "env",
+					Usage: "The fixture set to load: dev, demo or test",
+					Value: "dev",
+				},
+				&cli.StringFlag{
+					Name:  "path",
+					Usage: "The folder containing the fixture sets",
+					Value: "./scripts/fixtures",
+				},
+				&cli.IntFlag{
+					Name:  "fake-users",
+					Usage: "The number of fake users to generate for load testing",
+				},
+			},
+		},
+		{
+			Name:   "quota-report",
+			Action: commands.QuotaReportCommand(envs),
+			Usage:  "Print the quota usage per subject, by default for the current period",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "period",
+					Usage: "The period type: day or month",
+					Value: "day",
+				},
+				&cli.StringFlag{
+					Name:  "from",
+					Usage: "The first period to include, in the format YYYY-MM-DD",
+				},
+				&cli.StringFlag{
+					Name:  "to",
+					Usage: "The last period to include, in the format YYYY-MM-DD",
+				},
+				&cli.StringFlag{
+					Name:  "subject",
+					Usage: "Filter the usage of a single subject, e.g. user:<uuid> or apikey:<id>",
+				},
+				&cli.BoolFlag{
+					Name:  "no-flush",
+					Usage: "Do not flush the counters from Redis before the report",
+				},
+			},
+		},
+		{
+			Name:   "openapi",
+			Action: commands.OpenAPICommand(envs),
+			Usage:  "Write the OpenAPI document of the webapp APIs to a file",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "output",
+					Usage: "The file where the document is written",
+					Value: "./api/openapi.json",
+				},
+			},
+		},
+		{
+			Name:   "env-example",
+			Action: commands.EnvExampleCommand,
+			Usage:  "Write the example of the env variables of the application to a file",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "output",
+					Usage: "The file where the example is written",
+					Value: "./.env.example",
+				},
+			},
+		},
+		{
+			Name:   "encrypt-secrets",
+			Action: commands.EncryptSecretsCommand(envs),
+			Usage:  "Encrypt a JSON file of secrets by reference for the encrypted-file secret provider",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "input",
+					Usage: "The JSON file of the secrets to encrypt, e.g. {\"database/password\": \"...\"}",
+				},
+				&cli.StringFlag{
+					Name:  "output",
+					Usage: "The file where the encrypted secrets are written",
+					Value: "./secrets.enc",
+				},
+			},
+		},
 	}
 
 	err := app.Run(os.Args)
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpenv"
+	"github.com/urfave/cli"
+	"go.uber.org/zap"
+)
+
+/*
+EncryptSecretsCommand encrypts a JSON object of secrets by reference with the SECRETS_FILE_KEY,
+writing the file read by the encrypted-file secret provider. The plain file must not be committed.
+*/
+func EncryptSecretsCommand(envs *bpenv.Envs) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if envs.SecretsFileKey == "" {
+			return errors.New("SECRETS_FILE_KEY is not set, generate one via `openssl rand -base64 32`")
+		}
+		key, err := base64.StdEncoding.DecodeString(envs.SecretsFileKey)
+		if err != nil {
+			return errors.New("SECRETS_FILE_KEY is not base64 encoded")
+		}
+		content, err := os.ReadFile(c.String("input"))
+		if err != nil {
+			return err
+		}
+		secrets := map[string]string{}
+		if err := json.Unmarshal(content, &secrets); err != nil {
+			return err
+		}
+		encrypted, err := bpenv.EncryptSecrets(secrets, key)
+		if err != nil {
+			return err
+		}
+		output := c.String("output")
+		if err := os.WriteFile(output, encrypted, 0o600); err != nil {
+			return err
+		}
+		zap.L().Info(fmt.Sprintf("%d secrets encrypted to %s", len(secrets), output), zap.String("service", "cli-encrypt-secrets-command"))
+		return nil
+	}
+}
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpenv"
+	"github.com/urfave/cli"
+	"go.uber.org/zap"
+)
+
+/*
+EnvExampleCommand writes the `.env.example` file listing all the environment variables of the application,
+generated from the tags of the bpenv.Envs struct.
+*/
+func EnvExampleCommand(c *cli.Context) error {
+	output := c.String("output")
+	if err := os.WriteFile(output, bpenv.Example(bpenv.Envs{}), 0o644); err != nil {
+		return err
+	}
+	zap.L().Info(fmt.Sprintf("Env example written to %s", output), zap.String("service", "cli-env-example-command"))
+	return nil
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/besasch88/blueprint/internal/app"
+	"github.com/besasch88/blueprint/internal/pkg/bpenv"
+	"github.com/besasch88/blueprint/internal/pkg/bpopenapi"
+	"github.com/besasch88/blueprint/internal/pkg/bppubsub"
+	"github.com/gin-gonic/gin"
+	"github.com/urfave/cli"
+	"go.uber.org/zap"
+)
+
+/*
+OpenAPICommand writes the OpenAPI document of the webapp APIs to the given file, e.g. to generate clients.
+Routes are registered as in the webapp, via app.RegisterRoutes, without connecting to the database.
+*/
+func OpenAPICommand(envs *bpenv.Envs) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		gin.SetMode(gin.ReleaseMode)
+		engine := gin.New()
+		pubSubAgent := bppubsub.NewPubSubAgent()
+		defer pubSubAgent.Close()
+
+		v1Api := engine.Group("api/v1")
+		app.RegisterRoutes(envs, nil, pubSubAgent, v1Api)
+
+		document, err := bpopenapi.Generate()
+		if err != nil {
+			return err
+		}
+		content, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			return err
+		}
+		output := c.String("output")
+		if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(output, append(content, '\n'), 0o644); err != nil {
+			return err
+		}
+		zap.L().Info(fmt.Sprintf("OpenAPI document written to %s", output), zap.String("service", "cli-openapi-command"))
+		return nil
+	}
+}
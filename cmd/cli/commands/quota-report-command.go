@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpdb"
+	"github.com/besasch88/blueprint/internal/pkg/bpenv"
+	"github.com/besasch88/blueprint/internal/pkg/bpquota"
+	"github.com/urfave/cli"
+)
+
+/*
+QuotaReportCommand prints the quota usage of the given period type, optionally filtered by subject.
+By default it reports the current period. Counters are flushed from Redis before reading them,
+unless the no-flush flag is set.
+*/
+func QuotaReportCommand(envs *bpenv.Envs) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		period := bpquota.Period(c.String("period"))
+		if !slices.Contains(bpquota.AvailablePeriods, interface{}(period)) {
+			return errors.New("period must be one of: day, month")
+		}
+		now := time.Now().UTC()
+		from, to := now, now
+		var err error
+		if c.String("from") != "" {
+			if from, err = time.Parse("2006-01-02", c.String("from")); err != nil {
+				return errors.New("from must be a date in the format YYYY-MM-DD")
+			}
+		}
+		if c.String("to") != "" {
+			if to, err = time.Parse("2006-01-02", c.String("to")); err != nil {
+				return errors.New("to must be a date in the format YYYY-MM-DD")
+			}
+		}
+		if period == bpquota.Monthly {
+			// Monthly usages are stored on the first day of the month
+			from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+		}
+		if to.Before(from) {
+			return errors.New("from cannot be after to")
+		}
+
+		dbConnection := bpdb.NewDatabaseConnection(
+			envs.DbHost,
+			envs.DbUsername,
+			envs.DbPassword,
+			envs.DbName,
+			envs.DbPort,
+			envs.DbSslMode,
+			envs.DbLogSlowQueryThreshold,
+			string(envs.AppMode),
+			bpdb.ConnectionConfig{
+				MaxOpenConns:     envs.DbMaxOpenConns,
+				MaxIdleConns:     envs.DbMaxIdleConns,
+				ConnMaxLifetime:  time.Duration(envs.DbConnMaxLifetimeSeconds) * time.Second,
+				ConnMaxIdleTime:  time.Duration(envs.DbConnMaxIdleTimeSeconds) * time.Second,
+				StatementTimeout: time.Duration(envs.DbStatementTimeoutSeconds) * time.Second,
+				ApplicationName:  envs.DbApplicationName,
+				RetryTimeout:     time.Duration(envs.DbConnectRetryTimeoutSeconds) * time.Second,
+			},
+		)
+		defer bpdb.CloseDatabaseConnection(dbConnection)
+
+		if !c.Bool("no-flush") {
+			bpquota.Init(envs.QuotaRedisConnectionURI, envs.QuotaRedisConnectRetryTimeoutSeconds, envs.QuotaFile)
+			if _, err := bpquota.Flush(context.Background(), dbConnection); err != nil {
+				return err
+			}
+		}
+
+		items, err := bpquota.GetUsageReport(dbConnection, period, from, to, c.String("subject"))
+		if err != nil {
+			return err
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "PERIOD\tSTART\tSUBJECT\tUNITS\tUPDATED AT")
+		for _, item := range items {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\n", item.Period, item.PeriodStart.Format("2006-01-02"), item.Subject, item.Units, item.UpdatedAt.Format(time.RFC3339))
+		}
+		return writer.Flush()
+	}
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/app/user"
+	"github.com/besasch88/blueprint/internal/pkg/bpdb"
+	"github.com/besasch88/blueprint/internal/pkg/bpenv"
+	"github.com/besasch88/blueprint/internal/pkg/bppubsub"
+	"github.com/besasch88/blueprint/internal/pkg/bpseed"
+	"github.com/urfave/cli"
+)
+
+/*
+SeedCommand loads the fixtures of the requested environment into the database,
+following the dependency order between modules. Optionally, it generates fake users for load testing.
+*/
+func SeedCommand(envs *bpenv.Envs) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		env := bpseed.SeedEnv(c.String("env"))
+		if !slices.Contains(bpseed.AvailableSeedEnvs, interface{}(env)) {
+			return errors.New("env must be one of: dev, demo, test")
+		}
+		fakeUsers := c.Int("fake-users")
+		if fakeUsers < 0 {
+			return errors.New("fake-users cannot be negative")
+		}
+
+		dbConnection := bpdb.NewDatabaseConnection(
+			envs.DbHost,
+			envs.DbUsername,
+			envs.DbPassword,
+			envs.DbName,
+			envs.DbPort,
+			envs.DbSslMode,
+			envs.DbLogSlowQueryThreshold,
+			string(envs.AppMode),
+			bpdb.ConnectionConfig{
+				MaxOpenConns:     envs.DbMaxOpenConns,
+				MaxIdleConns:     envs.DbMaxIdleConns,
+				ConnMaxLifetime:  time.Duration(envs.DbConnMaxLifetimeSeconds) * time.Second,
+				ConnMaxIdleTime:  time.Duration(envs.DbConnMaxIdleTimeSeconds) * time.Second,
+				StatementTimeout: time.Duration(envs.DbStatementTimeoutSeconds) * time.Second,
+				ApplicationName:  envs.DbApplicationName,
+				RetryTimeout:     time.Duration(envs.DbConnectRetryTimeoutSeconds) * time.Second,
+			},
+		)
+		defer bpdb.CloseDatabaseConnection(dbConnection)
+		pubSubAgent := bppubsub.NewPubSubAgent()
+		defer pubSubAgent.Close()
+
+		// Register all the modules with fixtures
+		seeder := bpseed.NewSeeder()
+		user.InitSeeder(envs, dbConnection, pubSubAgent, seeder)
+
+		if err := seeder.Seed(c.String("path"), env); err != nil {
+			return err
+		}
+		if fakeUsers > 0 {
+			return seeder.Fake("user", fakeUsers)
+		}
+		return nil
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,11 +9,16 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/besasch88/blueprint/internal/app/user"
+	"github.com/besasch88/blueprint/internal/app"
+	"github.com/besasch88/blueprint/internal/pkg/bpcache"
 	"github.com/besasch88/blueprint/internal/pkg/bpcors"
 	"github.com/besasch88/blueprint/internal/pkg/bpdb"
 	"github.com/besasch88/blueprint/internal/pkg/bpenv"
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"github.com/besasch88/blueprint/internal/pkg/bpidempotency"
+	"github.com/besasch88/blueprint/internal/pkg/bpopenapi"
 	"github.com/besasch88/blueprint/internal/pkg/bppubsub"
+	"github.com/besasch88/blueprint/internal/pkg/bpquota"
 	"github.com/besasch88/blueprint/internal/pkg/bpratelimit"
 	"github.com/besasch88/blueprint/internal/pkg/bprouter"
 	"go.uber.org/zap"
@@ -35,11 +39,21 @@ func main() {
 	envs := bpenv.ReadEnvs()
 	// Set Logger
 	logger := zap.Must(zap.NewProduction())
-	if envs.AppMode != "release" {
+	if envs.AppMode != bpenv.ReleaseMode {
 		logger = zap.Must(zap.NewDevelopment())
 	}
 	zap.ReplaceGlobals(logger)
+	zap.L().Debug("Env variables loaded", zap.String("service", "webapp"), zap.Any("envs", envs))
 	// DB Connection
+	dbConfig := bpdb.ConnectionConfig{
+		MaxOpenConns:     envs.DbMaxOpenConns,
+		MaxIdleConns:     envs.DbMaxIdleConns,
+		ConnMaxLifetime:  time.Duration(envs.DbConnMaxLifetimeSeconds) * time.Second,
+		ConnMaxIdleTime:  time.Duration(envs.DbConnMaxIdleTimeSeconds) * time.Second,
+		StatementTimeout: time.Duration(envs.DbStatementTimeoutSeconds) * time.Second,
+		ApplicationName:  envs.DbApplicationName,
+		RetryTimeout:     time.Duration(envs.DbConnectRetryTimeoutSeconds) * time.Second,
+	}
 	dbConnection := bpdb.NewDatabaseConnection(
 		envs.DbHost,
 		envs.DbUsername,
@@ -48,38 +62,90 @@ func main() {
 		envs.DbPort,
 		envs.DbSslMode,
 		envs.DbLogSlowQueryThreshold,
-		envs.AppMode,
+		string(envs.AppMode),
+		dbConfig,
+	)
+	// DB Read replicas, if any
+	bpdb.AddReadReplicas(
+		dbConnection,
+		envs.DbReplicaHosts,
+		envs.DbUsername,
+		envs.DbPassword,
+		envs.DbName,
+		envs.DbSslMode,
+		envs.DbReplicaHealthCheckIntervalSeconds,
+		dbConfig,
 	)
 	// PUB-SUB agent
 	pubSubAgent := bppubsub.NewPubSubAgent()
 	// Rate Limit initialization
 	bpratelimit.Init(
+		envs.RateLimitStore,
 		envs.RateLimitRedisConnectionURI,
+		envs.RateLimitRedisConnectRetryTimeoutSeconds,
+		envs.RateLimitFailureMode,
+		envs.RateLimitMemoryMaxKeys,
+		envs.RateLimitAlgorithm,
+		envs.RateLimitHeadersStyle,
+		envs.RateLimitPoliciesFile,
 		envs.RateLimitAnonymousTimeRangeSeconds,
 		envs.RateLimitAnonymousMaxRequestsInRange,
 		envs.RateLimitAuthUserTimeRangeSeconds,
 		envs.RateLimitAuthUserMaxRequestsInRange,
 	)
+	// Quota initialization, counters are periodically flushed to the DB
+	bpquota.Init(
+		envs.QuotaRedisConnectionURI,
+		envs.QuotaRedisConnectRetryTimeoutSeconds,
+		envs.QuotaFile,
+	)
+	stopQuotaFlusher := bpquota.StartFlusher(dbConnection, time.Duration(envs.QuotaFlushIntervalSeconds)*time.Second)
+	// Idempotency initialization, responses are stored for the retries of mutating requests
+	bpidempotency.Init(
+		envs.IdempotencyRedisConnectionURI,
+		envs.IdempotencyConnectRetryTimeoutSeconds,
+		envs.IdempotencyTTLSeconds,
+	)
+	// Cache initialization, values are invalidated by the events of the PUB-SUB agent
+	bpcache.Init(
+		envs.CacheStore,
+		envs.CacheRedisConnectionURI,
+		envs.CacheRedisConnectRetryTimeoutSeconds,
+		envs.CacheMemoryMaxKeys,
+		envs.CacheTTLSeconds,
+	)
 
 	// Start Server
 	zap.L().Info("Starting HTTP Server...", zap.String("service", "webapp"))
-	gin.SetMode(envs.AppMode)
+	gin.SetMode(string(envs.AppMode))
 	r := gin.Default()
 	r.SetTrustedProxies(nil)
+	bprouter.Init(int64(envs.AppMaxBodyBytes), envs.AppStrictBinding, envs.AppCursorSecret)
 	// Cors Middleware
 	allowOrigins := []string{envs.AppCorsOrigin}
-	if envs.AppMode != "release" {
+	if envs.AppMode != bpenv.ReleaseMode {
 		allowOrigins = append(allowOrigins, bpcors.LocalhostOrigin)
 	}
 	r.Use(bpcors.CorsMiddleware(allowOrigins))
 
 	r.NoRoute(func(ctx *gin.Context) {
-		bprouter.ReturnNotFoundError(ctx, errors.New("endpoint-not-found"))
+		bprouter.ReturnError(ctx, bperr.ErrEndpointNotFound)
 	})
 
 	// Init moduels that will start exposing endpoints and consumers of internal events
 	v1Api := r.Group("api/v1")
-	user.Init(envs, dbConnection, pubSubAgent, v1Api)
+	// Responses are compressed last, so the following middlewares handle them uncompressed
+	v1Api.Use(bprouter.CompressionMiddleware(envs.AppCompressionMinBytes))
+	// Requests, and responses in debug mode, are validated against the checked-in OpenAPI document
+	if envs.AppOpenAPIValidation {
+		v1Api.Use(bpopenapi.ValidationMiddleware(envs.AppOpenAPIFile, envs.AppMode == bpenv.DebugMode))
+	}
+	// ETag and Last-Modified of the responses, answering Not Modified to conditional requests
+	v1Api.Use(bprouter.ConditionalMiddleware())
+	app.RegisterRoutes(envs, dbConnection, pubSubAgent, v1Api)
+
+	// API documentation of all the routes registered above
+	bpopenapi.Init(r)
 
 	// Start the application
 	srv := &http.Server{
@@ -109,6 +175,7 @@ func main() {
 	zap.L().Info("Shutdown Server in 3 seconds...", zap.String("service", "webapp"))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	stopQuotaFlusher()
 	bpdb.CloseDatabaseConnection(dbConnection)
 	pubSubAgent.Close()
 	defer cancel()
@@ -0,0 +1,26 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/besasch88/blueprint/internal/app/user"
+	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"github.com/besasch88/blueprint/internal/pkg/bpcache"
+	"github.com/besasch88/blueprint/internal/pkg/bpenv"
+	"github.com/besasch88/blueprint/internal/pkg/bpopenapi"
+	"github.com/besasch88/blueprint/internal/pkg/bppubsub"
+	"github.com/besasch88/blueprint/internal/pkg/bpratelimit"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+/*
+RegisterRoutes initializes the modules exposing the v1 APIs, together with the shared routes.
+It is used by both the webapp and the OpenAPI command, so the generated document always
+describes the served APIs. The OpenAPI command passes a nil dbStorage, as no query is performed.
+*/
+func RegisterRoutes(envs *bpenv.Envs, dbStorage *gorm.DB, pubSubAgent *bppubsub.PubSubAgent, v1Api *gin.RouterGroup) {
+	user.Init(envs, dbStorage, pubSubAgent, v1Api)
+	bpopenapi.Handle(v1Api, http.MethodGet, "/me/rate-limit", bpratelimit.UsageRoute([]string{bpauth.RateLimitGet}), bpratelimit.UsageHandler())
+	bpopenapi.Handle(v1Api, http.MethodGet, "/cache/stats", bpcache.StatsRoute([]string{bpauth.CacheStatsGet}), bpcache.StatsHandler())
+}
@@ -1,25 +1,62 @@
 package user
 
 import (
+	"github.com/besasch88/blueprint/internal/pkg/bpdb"
+	"github.com/besasch88/blueprint/internal/pkg/bpfilter"
+	"github.com/besasch88/blueprint/internal/pkg/bputils"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/google/uuid"
 )
 
+/*
+Users are listed by cursor, unless a page is requested. Cursors are returned by the previous pages
+and they are valid only with the same order. Filters are parsed separately from the `filter[...]` Query params.
+*/
+type listUsersInputDto struct {
+	Page      int     `form:"page" json:"page"`
+	PageSize  int     `form:"pageSize" json:"pageSize"`
+	Cursor    string  `form:"cursor" json:"cursor"`
+	OrderBy   string  `form:"orderBy" json:"orderBy"`
+	OrderDir  string  `form:"orderDir" json:"orderDir"`
+	SearchKey *string `form:"searchKey" json:"searchKey"`
+	SkipCount bool    `form:"skipCount" json:"skipCount"`
+	filters   []bpfilter.Filter
+}
+
+func newListUsersInputDto() listUsersInputDto {
+	return listUsersInputDto{
+		PageSize: 20,
+		OrderBy:  string(userOrderByLastname),
+		OrderDir: string(bpdb.Asc),
+	}
+}
+
+func (r listUsersInputDto) validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Page, validation.Min(0), validation.When(r.Cursor != "", validation.Empty)),
+		validation.Field(&r.PageSize, validation.Required, validation.Min(1), validation.Max(100)),
+		validation.Field(&r.OrderBy, validation.Required, validation.In(bputils.TransformToStrings(availableUserOrderBy)...)),
+		validation.Field(&r.OrderDir, validation.Required, validation.In(bputils.TransformToStrings(bpdb.AvailableOrderDir)...)),
+		validation.Field(&r.SearchKey, validation.NilOrNotEmpty, validation.Length(1, 255)),
+	)
+}
+
 type getUserInputDto struct {
-	id string `uri:"userID"`
+	ID string `uri:"userID" json:"-" openapi:"format=uuid"`
 }
 
 func (r getUserInputDto) validate() error {
 	return validation.ValidateStruct(&r,
-		validation.Field(&r.id, validation.Required, is.UUID),
+		validation.Field(&r.ID, validation.Required, is.UUID),
 	)
 }
 
 type createUserInputDto struct {
-	ID        string
-	Firstname string
-	Lastname  string
-	Email     string
+	ID        string `json:"id"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Email     string `json:"email"`
 }
 
 func (r createUserInputDto) validate() error {
@@ -30,3 +67,73 @@ func (r createUserInputDto) validate() error {
 		validation.Field(&r.Email, validation.Required, is.Email),
 	)
 }
+
+/*
+Payload of the users created via the APIs, whose ID is generated by the server.
+*/
+type createUserBodyDto struct {
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Email     string `json:"email" openapi:"format=email"`
+}
+
+func (r createUserBodyDto) toInput() createUserInputDto {
+	return createUserInputDto{
+		ID:        uuid.NewString(),
+		Firstname: r.Firstname,
+		Lastname:  r.Lastname,
+		Email:     r.Email,
+	}
+}
+
+type updateUserInputDto struct {
+	ID        string `uri:"userID" json:"-" openapi:"format=uuid"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Email     string `json:"email" openapi:"format=email"`
+}
+
+func (r updateUserInputDto) validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, is.UUID),
+		validation.Field(&r.Firstname, validation.Required, validation.Length(3, 255)),
+		validation.Field(&r.Lastname, validation.Required, validation.Length(3, 255)),
+		validation.Field(&r.Email, validation.Required, is.Email),
+	)
+}
+
+/*
+Representation of a user returned to clients. Internal fields, e.g. the deletion ones, are never returned,
+while the version is returned via the ETag header.
+*/
+type userOutputDto struct {
+	ID        string `json:"id" openapi:"format=uuid"`
+	Email     string `json:"email" openapi:"format=email"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	CreatedAt string `json:"createdAt" openapi:"format=date-time"`
+	UpdatedAt string `json:"updatedAt" openapi:"format=date-time"`
+	CreatedBy string `json:"createdBy" openapi:"format=uuid"`
+	UpdatedBy string `json:"updatedBy" openapi:"format=uuid"`
+}
+
+func newUserOutputDto(e userEntity) userOutputDto {
+	return userOutputDto{
+		ID:        bputils.GetStringFromUUID(e.id),
+		Email:     e.email,
+		Firstname: e.firstname,
+		Lastname:  e.lastname,
+		CreatedAt: bputils.GetStringFromTime(e.createdAt),
+		UpdatedAt: bputils.GetStringFromTime(e.updatedAt),
+		CreatedBy: bputils.GetStringFromUUID(e.createdBy),
+		UpdatedBy: bputils.GetStringFromUUID(e.updatedBy),
+	}
+}
+
+func newUserOutputDtos(items []userEntity) []userOutputDto {
+	dtos := []userOutputDto{}
+	for _, item := range items {
+		dtos = append(dtos, newUserOutputDto(item))
+	}
+	return dtos
+}
@@ -0,0 +1,53 @@
+package user
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var camelCaseRegex = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`)
+
+/*
+Contract of the user returned to clients: any change to the exposed fields must be intentional.
+*/
+func TestUserOutputDtoContract(t *testing.T) {
+	deletedAt := time.Now()
+	deletedBy := uuid.New()
+	user := newTestUser("Alice", "Anderson", "alice.anderson@example.com")
+	user.createdAt = time.Date(2024, 5, 1, 10, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	user.deletedAt = &deletedAt
+	user.deletedBy = &deletedBy
+	user.version = 7
+
+	data, err := json.Marshal(newUserOutputDto(user))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []string{"createdAt", "createdBy", "email", "firstname", "id", "lastname", "updatedAt", "updatedBy"}
+	keys := []string{}
+	for key := range body {
+		keys = append(keys, key)
+		if !camelCaseRegex.MatchString(key) {
+			t.Errorf("expected camelCase fields, got %s", key)
+		}
+	}
+	slices.Sort(keys)
+	if !slices.Equal(keys, expected) {
+		t.Errorf("expected fields %v, got %v", expected, keys)
+	}
+	if body["createdAt"] != "2024-05-01T08:00:00Z" {
+		t.Errorf("expected RFC3339 UTC timestamps, got %v", body["createdAt"])
+	}
+	if body["id"] != user.id.String() {
+		t.Errorf("expected id %s, got %v", user.id, body["id"])
+	}
+}
@@ -3,6 +3,7 @@ package user
 import (
 	"time"
 
+	"github.com/besasch88/blueprint/internal/pkg/bppubsub"
 	"github.com/google/uuid"
 )
 
@@ -17,4 +18,25 @@ type userEntity struct {
 	createdBy uuid.UUID
 	updatedBy uuid.UUID
 	deletedBy *uuid.UUID
+	version   int64
+}
+
+func newUserEvent(eventType bppubsub.PubSubEventType, user userEntity) bppubsub.PubSubEvent {
+	return bppubsub.PubSubEvent{
+		EventID:   uuid.New(),
+		EventTime: time.Now(),
+		EventType: eventType,
+		EventEntity: bppubsub.UserEventEntity{
+			ID:        user.id,
+			Firstname: user.firstname,
+			Lastname:  user.lastname,
+			Email:     user.email,
+			CreatedAt: user.createdAt,
+			UpdatedAt: user.updatedAt,
+			DeletedAt: user.deletedAt,
+			CreatedBy: user.createdBy,
+			UpdatedBy: user.updatedBy,
+			DeletedBy: user.deletedBy,
+		},
+	}
 }
@@ -1,5 +1,11 @@
 package user
 
-import "errors"
+import (
+	"net/http"
 
-var errUserNotFound = errors.New("user-not-found")
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+)
+
+var errUserNotFound = bperr.New(http.StatusNotFound, "user-not-found", "The user does not exist")
+
+var errUserVersionConflict = bperr.New(http.StatusPreconditionFailed, "user-version-conflict", "The user has been changed since it was read")
@@ -1,8 +1,11 @@
 package user
 
 import (
+	"github.com/besasch88/blueprint/internal/pkg/bpcache"
+	"github.com/besasch88/blueprint/internal/pkg/bpdb"
 	"github.com/besasch88/blueprint/internal/pkg/bpenv"
 	"github.com/besasch88/blueprint/internal/pkg/bppubsub"
+	"github.com/besasch88/blueprint/internal/pkg/bpseed"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -18,11 +21,32 @@ func Init(envs *bpenv.Envs, dbStorage *gorm.DB, pubSubAgent *bppubsub.PubSubAgen
 	var router userRouterInterface
 	var consumer userConsumerInterface
 
-	repository = newUserRepository(envs.SearchRelevanceThreshold)
-	service = newUserService(dbStorage, pubSubAgent, repository)
+	txManager := bpdb.NewTxManager(dbStorage, envs.DbTransactionMaxRetries)
+	repository = newUserRepository(dbStorage, envs.SearchRelevanceThreshold)
+	cache := bpcache.New[userModel]("user", 0)
+	cache.InvalidateOn(pubSubAgent, bppubsub.TopicUserV1, changedUserKey)
+	service = newUserService(txManager, pubSubAgent, repository, cache)
 	router = newUserRouter(service)
 	consumer = newUserConsumer(pubSubAgent, service)
 	consumer.subscribe()
 	router.register(routerGroup)
 	zap.L().Info("User package initialized")
 }
+
+/*
+InitSeeder registers the module fixtures loader and fake data generator in the Seeder.
+*/
+func InitSeeder(envs *bpenv.Envs, dbStorage *gorm.DB, pubSubAgent *bppubsub.PubSubAgent, seeder *bpseed.Seeder) {
+	var repository userRepositoryInterface
+	var userSeeder userSeederInterface
+
+	txManager := bpdb.NewTxManager(dbStorage, envs.DbTransactionMaxRetries)
+	repository = newUserRepository(dbStorage, envs.SearchRelevanceThreshold)
+	userSeeder = newUserSeeder(txManager, pubSubAgent, repository)
+	seeder.Register(bpseed.ModuleSeeder{
+		Module:       "user",
+		DependsOn:    []string{},
+		LoadFixture:  userSeeder.loadFixture,
+		GenerateFake: userSeeder.generateFake,
+	})
+}
@@ -0,0 +1,14 @@
+package user
+
+import (
+	"os"
+	"testing"
+
+	"github.com/besasch88/blueprint/internal/pkg/bptest"
+)
+
+var testDatabase *bptest.Database
+
+func TestMain(m *testing.M) {
+	os.Exit(bptest.Main(m, &testDatabase))
+}
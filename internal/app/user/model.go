@@ -1,31 +1,59 @@
 package user
 
 import (
-	"time"
-
 	"github.com/besasch88/blueprint/internal/pkg/bpdb"
+	"github.com/besasch88/blueprint/internal/pkg/bpfilter"
 	"github.com/google/uuid"
 )
 
 type userModel struct {
-	id        uuid.UUID  `gorm:"primaryKey;column:id;type:varchar(36)"`
-	email     string     `gorm:"column:email;type:varchar(255)"`
-	firstname string     `gorm:"column:firstname;type:varchar(255)"`
-	lastname  string     `gorm:"column:lastname;type:varchar(255)"`
-	createdAt time.Time  `gorm:"column:created_at;type:timestamp;autoCreateTime:false"`
-	updatedAt time.Time  `gorm:"column:updated_at;type:timestamp;autoUpdateTime:false"`
-	deletedAt *time.Time `gorm:"column:deleted_at;type:timestamp;autoDeleteTime:false"`
-	createdBy uuid.UUID  `gorm:"column:created_by;type:varchar(36)"`
-	updatedBy uuid.UUID  `gorm:"column:updated_by;type:varchar(36)"`
-	deletedBy *uuid.UUID `gorm:"column:deleted_by;type:varchar(36)"`
+	ID        uuid.UUID `gorm:"primaryKey;column:id;type:varchar(36)"`
+	Email     string    `gorm:"column:email;type:varchar(255)"`
+	Firstname string    `gorm:"column:firstname;type:varchar(255)"`
+	Lastname  string    `gorm:"column:lastname;type:varchar(255)"`
+	bpdb.AuditModel
+	bpdb.VersionModel
 }
 
-func (m userModel) tableName() string {
+// GORM maps only exported fields and methods, so the table name must be exported too.
+func (m userModel) TableName() string {
 	return "bp_user"
 }
 
+func newUserModel(e userEntity) userModel {
+	return userModel{
+		ID:        e.id,
+		Email:     e.email,
+		Firstname: e.firstname,
+		Lastname:  e.lastname,
+		AuditModel: bpdb.AuditModel{
+			CreatedAt: e.createdAt,
+			UpdatedAt: e.updatedAt,
+			DeletedAt: e.deletedAt,
+			CreatedBy: e.createdBy,
+			UpdatedBy: e.updatedBy,
+			DeletedBy: e.deletedBy,
+		},
+		VersionModel: bpdb.VersionModel{
+			Version: e.version,
+		},
+	}
+}
+
 func (m userModel) toEntity() userEntity {
-	return userEntity(m)
+	return userEntity{
+		id:        m.ID,
+		email:     m.Email,
+		firstname: m.Firstname,
+		lastname:  m.Lastname,
+		createdAt: m.CreatedAt,
+		updatedAt: m.UpdatedAt,
+		deletedAt: m.DeletedAt,
+		createdBy: m.CreatedBy,
+		updatedBy: m.UpdatedBy,
+		deletedBy: m.DeletedBy,
+		version:   m.Version,
+	}
 }
 
 type userOrderBy string
@@ -37,6 +65,22 @@ const (
 	userOrderByRelevance userOrderBy = bpdb.RelevanceField
 )
 
+// The ordering of these fields is important for the relevance order
+var userSearchFields = []string{"email", "lastname", "firstname"}
+
+/*
+Whitelist of the fields users can be filtered by, with the operators available for each of them.
+*/
+var userFilterableFields = bpfilter.Fields{
+	"email":     {Column: "email", Type: bpfilter.String, Operators: []bpfilter.Operator{bpfilter.Eq, bpfilter.Neq, bpfilter.Contains, bpfilter.StartsWith, bpfilter.In}},
+	"firstname": {Column: "firstname", Type: bpfilter.String, Operators: []bpfilter.Operator{bpfilter.Eq, bpfilter.Neq, bpfilter.Contains, bpfilter.StartsWith, bpfilter.In}},
+	"lastname":  {Column: "lastname", Type: bpfilter.String, Operators: []bpfilter.Operator{bpfilter.Eq, bpfilter.Neq, bpfilter.Contains, bpfilter.StartsWith, bpfilter.In}},
+	"createdAt": {Column: "created_at", Type: bpfilter.Time, Operators: []bpfilter.Operator{bpfilter.Gt, bpfilter.Gte, bpfilter.Lt, bpfilter.Lte}},
+	"updatedAt": {Column: "updated_at", Type: bpfilter.Time, Operators: []bpfilter.Operator{bpfilter.Gt, bpfilter.Gte, bpfilter.Lt, bpfilter.Lte}},
+	"createdBy": {Column: "created_by", Type: bpfilter.UUID, Operators: []bpfilter.Operator{bpfilter.Eq, bpfilter.Neq, bpfilter.In}},
+	"updatedBy": {Column: "updated_by", Type: bpfilter.UUID, Operators: []bpfilter.Operator{bpfilter.Eq, bpfilter.Neq, bpfilter.In}},
+}
+
 var availableUserOrderBy = []interface{}{
 	userOrderByFirstname,
 	userOrderByLastname,
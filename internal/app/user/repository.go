@@ -1,95 +1,48 @@
 package user
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/besasch88/blueprint/internal/pkg/bpdb"
+	"github.com/besasch88/blueprint/internal/pkg/bpfilter"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 type userRepositoryInterface interface {
-	listUsers(tx *gorm.DB, limit int, offset int, orderBy userOrderBy, orderDir bpdb.OrderDir, searchKey *string, includeDeleted bool, forUpdate bool) ([]userEntity, int64, error)
-	getUserByID(tx *gorm.DB, userID uuid.UUID, forUpdate bool) (userEntity, error)
-	saveUser(tx *gorm.DB, user userEntity) (userEntity, error)
+	listUsers(ctx context.Context, limit int, offset int, orderBy userOrderBy, orderDir bpdb.OrderDir, searchKey *string, filters []bpfilter.Filter, includeDeleted bool, forUpdate bool) ([]userEntity, int64, error)
+	listUsersByKeyset(ctx context.Context, limit int, orderBy userOrderBy, orderDir bpdb.OrderDir, keyset *bpdb.Keyset, searchKey *string, filters []bpfilter.Filter, includeDeleted bool, withCount bool) ([]userEntity, bpdb.KeysetPage, int64, error)
+	getUserByID(ctx context.Context, userID uuid.UUID, includeDeleted bool, forUpdate bool) (userEntity, error)
+	getUsersByIDs(ctx context.Context, userIDs []uuid.UUID, includeDeleted bool) ([]userEntity, error)
+	saveUser(ctx context.Context, user userEntity) (userEntity, error)
 }
 
 type userRepository struct {
-	relevanceThresholdConfig float64
+	repository bpdb.Repository[userModel, userEntity]
 }
 
-func newUserRepository(relevanceThresholdConfig float64) userRepository {
+func newUserRepository(storage *gorm.DB, relevanceThresholdConfig float64) userRepository {
 	return userRepository{
-		relevanceThresholdConfig: relevanceThresholdConfig,
+		repository: bpdb.NewRepository(storage, userModel.toEntity, newUserModel, userSearchFields, relevanceThresholdConfig),
 	}
 }
 
-func (r userRepository) listUsers(tx *gorm.DB, limit int, offset int, orderBy userOrderBy, orderDir bpdb.OrderDir, searchKey *string, includeDeleted bool, forUpdate bool) ([]userEntity, int64, error) {
-	var totalCount int64
-	var order string
-
-	var models []*userModel
-	query := tx.Model(userModel{})
-	queryCount := tx.Model(userModel{})
-
-	// The ordering of these fields is important for the relevance order
-	searchFields := []string{"email", "lastname", "firstname"}
-	// Add fuzzy search query based on the provided search key and table fields
-	if searchKey != nil {
-		bpdb.GenerateFuzzySearch(query, *searchKey, searchFields, r.relevanceThresholdConfig)
-		bpdb.GenerateFuzzySearch(queryCount, *searchKey, searchFields, r.relevanceThresholdConfig)
-	}
-	// Based on the order field, we apply it on different tables
-	if orderBy == userOrderByRelevance {
-		order = bpdb.GenerateFuzzySearchOrderQuery(searchFields, orderDir)
-	} else {
-		order = fmt.Sprintf("%s %s", orderBy, orderDir)
-	}
-
-	if !includeDeleted {
-		query.Where("deleted_at IS NULL")
-		queryCount.Where("deleted_at IS NULL")
-	}
+func (r userRepository) listUsers(ctx context.Context, limit int, offset int, orderBy userOrderBy, orderDir bpdb.OrderDir, searchKey *string, filters []bpfilter.Filter, includeDeleted bool, forUpdate bool) ([]userEntity, int64, error) {
+	return r.repository.List(ctx, limit, offset, string(orderBy), orderDir, searchKey, filters, includeDeleted, forUpdate)
+}
 
-	if forUpdate {
-		query.Clauses(clause.Locking{Strength: "UPDATE"})
-	}
-	result := query.Limit(limit).Offset(offset).Order(order).Find(&models)
-	queryCount.Count(&totalCount)
+func (r userRepository) listUsersByKeyset(ctx context.Context, limit int, orderBy userOrderBy, orderDir bpdb.OrderDir, keyset *bpdb.Keyset, searchKey *string, filters []bpfilter.Filter, includeDeleted bool, withCount bool) ([]userEntity, bpdb.KeysetPage, int64, error) {
+	return r.repository.ListKeyset(ctx, limit, string(orderBy), orderDir, keyset, searchKey, filters, includeDeleted, withCount)
+}
 
-	if result.Error != nil {
-		return []userEntity{}, 0, result.Error
-	}
-	var entities []userEntity = []userEntity{}
-	for _, model := range models {
-		fscInput := model.toEntity()
-		entities = append(entities, fscInput)
-	}
-	return entities, totalCount, nil
+func (r userRepository) getUserByID(ctx context.Context, userID uuid.UUID, includeDeleted bool, forUpdate bool) (userEntity, error) {
+	return r.repository.GetByID(ctx, userID, includeDeleted, forUpdate)
 }
 
-func (r userRepository) getUserByID(tx *gorm.DB, userID uuid.UUID, forUpdate bool) (userEntity, error) {
-	var model *userModel
-	query := tx.Where("id = ?", userID)
-	if forUpdate {
-		query.Clauses(clause.Locking{Strength: "UPDATE"})
-	}
-	result := query.Limit(1).Find(&model)
-	if result.Error != nil {
-		return userEntity{}, result.Error
-	}
-	if result.RowsAffected == 0 {
-		return userEntity{}, nil
-	}
-	return model.toEntity(), nil
+func (r userRepository) getUsersByIDs(ctx context.Context, userIDs []uuid.UUID, includeDeleted bool) ([]userEntity, error) {
+	return r.repository.GetByIDs(ctx, userIDs, includeDeleted)
 }
 
-func (r userRepository) saveUser(tx *gorm.DB, user userEntity) (userEntity, error) {
-	var model = userModel(user)
-	err := tx.Save(model).Error
-	if err != nil {
-		return userEntity{}, err
-	}
-	return user, nil
+func (r userRepository) saveUser(ctx context.Context, user userEntity) (userEntity, error) {
+	return r.repository.Save(ctx, user)
 }
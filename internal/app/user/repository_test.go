@@ -0,0 +1,130 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpdb"
+	"github.com/besasch88/blueprint/internal/pkg/bpfilter"
+	"github.com/besasch88/blueprint/internal/pkg/bptest"
+	"github.com/google/uuid"
+)
+
+func TestRepositoryListUsers(t *testing.T) {
+	tx := bptest.RequireDatabase(t, testDatabase)
+	repository := newUserRepository(tx, 0.05)
+	ctx := context.Background()
+	deletedAt := time.Now().UTC()
+	deletedBy := uuid.Nil
+	deleted := newTestUser("Carlo", "Colombo", "carlo.colombo@example.com")
+	deleted.deletedAt = &deletedAt
+	deleted.deletedBy = &deletedBy
+	for _, user := range []userEntity{
+		newTestUser("Alice", "Anderson", "alice.anderson@example.com"),
+		newTestUser("Marco", "Rossi", "marco.rossi@example.com"),
+		newTestUser("Benjamin", "Brown", "benjamin.brown@example.com"),
+		deleted,
+	} {
+		if _, err := repository.saveUser(ctx, user); err != nil {
+			t.Fatalf("unable to save user: %v", err)
+		}
+	}
+
+	t.Run("exclude deleted users", func(t *testing.T) {
+		items, count, err := repository.listUsers(ctx, 10, 0, userOrderByFirstname, bpdb.Asc, nil, nil, false, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertFirstnames(t, items, "Alice", "Benjamin", "Marco")
+		if count != 3 {
+			t.Errorf("expected 3 users, got %d", count)
+		}
+	})
+
+	t.Run("include deleted users", func(t *testing.T) {
+		_, count, err := repository.listUsers(ctx, 10, 0, userOrderByFirstname, bpdb.Asc, nil, nil, true, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if count != 4 {
+			t.Errorf("expected 4 users, got %d", count)
+		}
+	})
+
+	t.Run("paginate and order", func(t *testing.T) {
+		items, count, err := repository.listUsers(ctx, 2, 1, userOrderByLastname, bpdb.Desc, nil, nil, false, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertFirstnames(t, items, "Benjamin", "Alice")
+		if count != 3 {
+			t.Errorf("expected 3 users, got %d", count)
+		}
+	})
+
+	t.Run("paginate by keyset", func(t *testing.T) {
+		items, page, count, err := repository.listUsersByKeyset(ctx, 2, userOrderByFirstname, bpdb.Asc, nil, nil, nil, false, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertFirstnames(t, items, "Alice", "Benjamin")
+		if count != 0 || page.Prev != nil || page.Next == nil {
+			t.Fatalf("expected only the next page without count, got %+v, %d", page, count)
+		}
+		items, page, _, err = repository.listUsersByKeyset(ctx, 2, userOrderByFirstname, bpdb.Asc, page.Next, nil, nil, false, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertFirstnames(t, items, "Marco")
+		if page.Prev == nil || page.Next != nil {
+			t.Fatalf("expected only the previous page, got %+v", page)
+		}
+		items, page, count, err = repository.listUsersByKeyset(ctx, 2, userOrderByFirstname, bpdb.Asc, page.Prev, nil, nil, false, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertFirstnames(t, items, "Alice", "Benjamin")
+		if count != 3 || page.Prev != nil || page.Next == nil {
+			t.Errorf("expected only the next page with count, got %+v, %d", page, count)
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		filters := []bpfilter.Filter{
+			{Field: "email", Column: "email", Operator: bpfilter.Contains, Values: []interface{}{"EXAMPLE.com"}},
+			{Field: "lastname", Column: "lastname", Operator: bpfilter.In, Values: []interface{}{"Rossi", "Brown"}},
+		}
+		items, count, err := repository.listUsers(ctx, 10, 0, userOrderByFirstname, bpdb.Asc, nil, filters, false, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertFirstnames(t, items, "Benjamin", "Marco")
+		if count != 2 {
+			t.Errorf("expected 2 users, got %d", count)
+		}
+	})
+
+	t.Run("search by relevance", func(t *testing.T) {
+		searchKey := "rossi"
+		items, count, err := repository.listUsers(ctx, 10, 0, userOrderByRelevance, bpdb.Desc, &searchKey, nil, false, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if count == 0 || len(items) == 0 || items[0].lastname != "Rossi" {
+			t.Errorf("expected Rossi as most relevant user, got %+v", items)
+		}
+	})
+}
+
+func assertFirstnames(t *testing.T, items []userEntity, expected ...string) {
+	t.Helper()
+	if len(items) != len(expected) {
+		t.Fatalf("expected %d users, got %d", len(expected), len(items))
+	}
+	for i, item := range items {
+		if item.firstname != expected[i] {
+			t.Errorf("expected %s at position %d, got %s", expected[i], i, item.firstname)
+		}
+	}
+}
@@ -1,9 +1,15 @@
 package user
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"github.com/besasch88/blueprint/internal/pkg/bpdb"
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"github.com/besasch88/blueprint/internal/pkg/bpidempotency"
+	"github.com/besasch88/blueprint/internal/pkg/bpopenapi"
+	"github.com/besasch88/blueprint/internal/pkg/bpquota"
 	"github.com/besasch88/blueprint/internal/pkg/bpratelimit"
 	"github.com/besasch88/blueprint/internal/pkg/bprouter"
 	"github.com/besasch88/blueprint/internal/pkg/bptimeout"
@@ -28,15 +34,107 @@ func newUserRouter(service userServiceInterface) userRouter {
 
 // Implementation
 func (r userRouter) register(router *gin.RouterGroup) {
-	router.GET(
-		"/users/:userID",
-		bpauth.AuthMiddleware([]string{bpauth.UserGet}),
+	expanders := r.expanders()
+
+	bpopenapi.Handle(router, http.MethodGet, "/users", bpopenapi.Route{
+		OperationID: "listUsers",
+		Summary:     "List users",
+		Description: "Users are listed by cursor, unless a page is requested.",
+		Tags:        []string{"users"},
+		Claims:      []string{bpauth.UserList},
+		Request:     listUsersInputDto{},
+		Enums:       map[string][]interface{}{"orderBy": availableUserOrderBy, "orderDir": bpdb.AvailableOrderDir},
+		Response:    []userOutputDto{},
+		Meta:        []any{bprouter.CursorPageMeta{}, bprouter.OffsetPageMeta{}},
+		Filters:     userFilterableFields,
+		Expanders:   expanders,
+		Errors:      []*bperr.Error{bperr.ErrInvalidCursor, bperr.ErrRequestTimeout, bperr.ErrTooManyRequests, bperr.ErrQuotaExceeded},
+	},
+		bptimeout.TimeoutMiddleware(time.Duration(1)*time.Second),
+		bpratelimit.RateLimitMiddleware("user-read"),
+		bpquota.QuotaMiddleware(1),
+		bprouter.ShapeMiddleware(expanders),
+		func(ctx *gin.Context) {
+			// Input validation
+			request := newListUsersInputDto()
+			if err := bprouter.BindParameters(ctx, &request); err != nil {
+				bprouter.ReturnError(ctx, err)
+				return
+			}
+			if err := request.validate(); err != nil {
+				bprouter.ReturnValidationError(ctx, err)
+				return
+			}
+			filters, err := bprouter.ParseFilters(ctx, userFilterableFields)
+			if err != nil {
+				bprouter.ReturnError(ctx, err)
+				return
+			}
+			request.filters = filters
+			// Offset pagination, when a page is requested
+			if request.Page > 0 {
+				items, totalCount, err := r.service.listUsers(ctx, request)
+				if err != nil {
+					bprouter.ReturnError(ctx, err)
+					return
+				}
+				bprouter.ReturnOk(ctx, bprouter.ItemsResponse(newUserOutputDtos(items), bprouter.NewOffsetPageMeta(request.Page, request.PageSize, totalCount)))
+				return
+			}
+			// Keyset pagination
+			keyset, err := bprouter.DecodeOptionalCursor[bpdb.Keyset](request.Cursor)
+			if err != nil {
+				bprouter.ReturnError(ctx, err)
+				return
+			}
+			items, page, totalCount, err := r.service.listUsersByKeyset(ctx, request, keyset)
+			if err != nil {
+				bprouter.ReturnError(ctx, err)
+				return
+			}
+			nextCursor, err := bprouter.EncodeOptionalCursor(page.Next)
+			if err != nil {
+				bprouter.ReturnError(ctx, err)
+				return
+			}
+			prevCursor, err := bprouter.EncodeOptionalCursor(page.Prev)
+			if err != nil {
+				bprouter.ReturnError(ctx, err)
+				return
+			}
+			meta := bprouter.CursorPageMeta{
+				PageSize:   request.PageSize,
+				NextCursor: nextCursor,
+				PrevCursor: prevCursor,
+			}
+			if !request.SkipCount {
+				meta.TotalCount = &totalCount
+			}
+			bprouter.ReturnOk(ctx, bprouter.ItemsResponse(newUserOutputDtos(items), meta))
+		})
+
+	bpopenapi.Handle(router, http.MethodGet, "/users/:userID", bpopenapi.Route{
+		OperationID: "getUser",
+		Summary:     "Get a user",
+		Description: "The version of the user is returned via the ETag header.",
+		Tags:        []string{"users"},
+		Claims:      []string{bpauth.UserGet},
+		Request:     getUserInputDto{},
+		Response:    userOutputDto{},
+		Expanders:   expanders,
+		Errors:      []*bperr.Error{errUserNotFound, bperr.ErrRequestTimeout, bperr.ErrTooManyRequests, bperr.ErrQuotaExceeded},
+	},
 		bptimeout.TimeoutMiddleware(time.Duration(1)*time.Second),
-		bpratelimit.RateLimitMiddleware(),
+		bpratelimit.RateLimitMiddleware("user-read"),
+		bpquota.QuotaMiddleware(1),
+		bprouter.ShapeMiddleware(expanders),
 		func(ctx *gin.Context) {
 			// Input validation
 			var request getUserInputDto
-			bprouter.BindParameters(ctx, &request)
+			if err := bprouter.BindParameters(ctx, &request); err != nil {
+				bprouter.ReturnError(ctx, err)
+				return
+			}
 			if err := request.validate(); err != nil {
 				bprouter.ReturnValidationError(ctx, err)
 				return
@@ -53,6 +151,128 @@ func (r userRouter) register(router *gin.RouterGroup) {
 				bprouter.ReturnGenericError(ctx)
 				return
 			}
-			bprouter.ReturnOk(ctx, &gin.H{"item": item})
+			bprouter.SetETag(ctx, bprouter.VersionETag(item.version))
+			bprouter.ReturnOk(ctx, bprouter.ItemResponse(newUserOutputDto(item)))
+		})
+
+	bpopenapi.Handle(router, http.MethodPost, "/users", bpopenapi.Route{
+		OperationID: "createUser",
+		Summary:     "Create a user",
+		Description: "The ID of the user is generated by the server, so the Idempotency-Key header should be sent to retry the creation safely. The version of the user is returned via the ETag header.",
+		Tags:        []string{"users"},
+		Claims:      []string{bpauth.UserCreate},
+		Request:     createUserBodyDto{},
+		Status:      http.StatusCreated,
+		Response:    userOutputDto{},
+		Expanders:   expanders,
+		Idempotent:  true,
+		Errors:      []*bperr.Error{bperr.ErrRequestTimeout, bperr.ErrTooManyRequests, bperr.ErrQuotaExceeded},
+	},
+		bptimeout.TimeoutMiddleware(time.Duration(1)*time.Second),
+		bpratelimit.RateLimitMiddleware("user-write"),
+		bpidempotency.IdempotencyMiddleware(),
+		bpquota.QuotaMiddleware(1),
+		bprouter.ShapeMiddleware(expanders),
+		func(ctx *gin.Context) {
+			// Input validation
+			var body createUserBodyDto
+			if err := bprouter.BindParameters(ctx, &body); err != nil {
+				bprouter.ReturnError(ctx, err)
+				return
+			}
+			request := body.toInput()
+			if err := request.validate(); err != nil {
+				bprouter.ReturnValidationError(ctx, err)
+				return
+			}
+			// Business Logic
+			authUser := bpauth.GetAuthUserFromSession(ctx)
+			item, err := r.service.createUser(ctx, authUser.ID, request)
+			// Errors and output handler
+			if err != nil {
+				zap.L().Error("Something went wrong", zap.String("service", "user-router"), zap.Error(err))
+				bprouter.ReturnGenericError(ctx)
+				return
+			}
+			bprouter.SetETag(ctx, bprouter.VersionETag(item.version))
+			bprouter.ReturnCreated(ctx, bprouter.ItemResponse(newUserOutputDto(item)))
+		})
+
+	bpopenapi.Handle(router, http.MethodPut, "/users/:userID", bpopenapi.Route{
+		OperationID: "updateUser",
+		Summary:     "Update a user",
+		Description: "The If-Match header must contain the ETag of the user, so concurrent changes are not overwritten.",
+		Tags:        []string{"users"},
+		Claims:      []string{bpauth.UserUpdate},
+		Request:     updateUserInputDto{},
+		Response:    userOutputDto{},
+		Expanders:   expanders,
+		Idempotent:  true,
+		Errors:      []*bperr.Error{errUserNotFound, errUserVersionConflict, bperr.ErrPreconditionFailed, bperr.ErrPreconditionRequired, bperr.ErrRequestTimeout, bperr.ErrTooManyRequests, bperr.ErrQuotaExceeded},
+	},
+		bptimeout.TimeoutMiddleware(time.Duration(1)*time.Second),
+		bpratelimit.RateLimitMiddleware("user-write"),
+		bpidempotency.IdempotencyMiddleware(),
+		bpquota.QuotaMiddleware(1),
+		bprouter.ShapeMiddleware(expanders),
+		func(ctx *gin.Context) {
+			// Input validation
+			var request updateUserInputDto
+			if err := bprouter.BindParameters(ctx, &request); err != nil {
+				bprouter.ReturnError(ctx, err)
+				return
+			}
+			if err := request.validate(); err != nil {
+				bprouter.ReturnValidationError(ctx, err)
+				return
+			}
+			expectedVersion, ok := bprouter.GetIfMatchVersion(ctx)
+			if !ok {
+				return
+			}
+			// Business Logic
+			authUser := bpauth.GetAuthUserFromSession(ctx)
+			item, err := r.service.updateUser(ctx, authUser.ID, request, expectedVersion)
+			if err == errUserNotFound {
+				bprouter.ReturnNotFoundError(ctx, err)
+				return
+			}
+			if err == errUserVersionConflict {
+				bprouter.ReturnError(ctx, err)
+				return
+			}
+			// Errors and output handler
+			if err != nil {
+				zap.L().Error("Something went wrong", zap.String("service", "user-router"), zap.Error(err))
+				bprouter.ReturnGenericError(ctx)
+				return
+			}
+			bprouter.SetETag(ctx, bprouter.VersionETag(item.version))
+			bprouter.ReturnOk(ctx, bprouter.ItemResponse(newUserOutputDto(item)))
 		})
 }
+
+/*
+Related users can be expanded in the responses, e.g. `expand=createdBy,updatedBy`.
+*/
+func (r userRouter) expanders() bprouter.Expanders {
+	return bprouter.Expanders{
+		"createdBy": {KeyField: "createdBy", Resolve: r.resolveUsers},
+		"updatedBy": {KeyField: "updatedBy", Resolve: r.resolveUsers},
+	}
+}
+
+/*
+Resolve the users with the given IDs at once, by ID.
+*/
+func (r userRouter) resolveUsers(ctx *gin.Context, userIDs []string) (map[string]interface{}, error) {
+	items, err := r.service.getUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	users := map[string]interface{}{}
+	for _, item := range items {
+		users[item.id.String()] = newUserOutputDto(item)
+	}
+	return users, nil
+}
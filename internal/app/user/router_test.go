@@ -0,0 +1,204 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"github.com/besasch88/blueprint/internal/pkg/bpcache"
+	"github.com/besasch88/blueprint/internal/pkg/bpdb"
+	"github.com/besasch88/blueprint/internal/pkg/bpidempotency"
+	"github.com/besasch88/blueprint/internal/pkg/bprouter"
+	"github.com/besasch88/blueprint/internal/pkg/bptest"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func newTestEngine(t *testing.T, storage *gorm.DB) *gin.Engine {
+	envs := bptest.NewEnvs()
+	pubSubAgent := bptest.NewPubSubAgent(t)
+	return bptest.NewEngine(t, envs, func(group *gin.RouterGroup) {
+		Init(envs, storage, pubSubAgent, group)
+	})
+}
+
+func TestGetUserUnauthorized(t *testing.T) {
+	engine := newTestEngine(t, nil)
+	response := bptest.Request(t, engine, http.MethodGet, "/api/v1/users/7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d001", nil, nil)
+	bptest.AssertJSON(t, response, http.StatusUnauthorized, `{"type": "about:blank", "title": "Unauthorized", "status": 401, "code": "unauthorized", "detail": "Authentication is required", "instance": "/api/v1/users/7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d001"}`)
+}
+
+func TestGetUserForbidden(t *testing.T) {
+	engine := newTestEngine(t, nil)
+	authUser := bptest.MintAuthUser(bpauth.UserUpdate)
+	response := bptest.Request(t, engine, http.MethodGet, "/api/v1/users/7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d001", nil, &authUser)
+	bptest.AssertJSON(t, response, http.StatusForbidden, `{"type": "about:blank", "title": "Forbidden", "status": 403, "code": "forbidden", "detail": "You are not allowed to perform this operation", "instance": "/api/v1/users/7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d001"}`)
+}
+
+func TestGetUser(t *testing.T) {
+	tx := bptest.RequireDatabase(t, testDatabase)
+	user, err := newUserRepository(tx, 0.05).saveUser(context.Background(), newTestUser("Alice", "Anderson", "alice.anderson@example.com"))
+	if err != nil {
+		t.Fatalf("unable to save user: %v", err)
+	}
+	engine := newTestEngine(t, tx)
+	authUser := bptest.MintAuthUser(bpauth.UserGet)
+	response := bptest.Request(t, engine, http.MethodGet, "/api/v1/users/"+user.id.String(), nil, &authUser)
+	var body struct {
+		Item map[string]interface{} `json:"item"`
+	}
+	bptest.DecodeJSON(t, response, http.StatusOK, &body)
+	if body.Item["id"] != user.id.String() || body.Item["email"] != user.email || len(body.Item) != 8 {
+		t.Errorf("expected the user, got %v", body.Item)
+	}
+}
+
+func TestGetUserInvalidID(t *testing.T) {
+	authUser := bptest.MintAuthUser(bpauth.UserGet)
+	t.Run("OpenAPI validation", func(t *testing.T) {
+		engine := newTestEngine(t, nil)
+		response := bptest.Request(t, engine, http.MethodGet, "/api/v1/users/not-a-uuid", nil, &authUser)
+		bptest.AssertJSON(t, response, http.StatusUnprocessableEntity, `{"type": "about:blank", "title": "Unprocessable Entity", "status": 422, "code": "validation-error", "detail": "The request contains invalid parameters", "instance": "/api/v1/users/not-a-uuid", "errors": {"userID": ["invalid-format"]}}`)
+		if contentType := response.Header().Get("Content-Type"); contentType != bprouter.ProblemContentType {
+			t.Errorf("expected content type %s, got %s", bprouter.ProblemContentType, contentType)
+		}
+	})
+	t.Run("Handler validation", func(t *testing.T) {
+		envs := bptest.NewEnvs()
+		envs.AppOpenAPIValidation = false
+		engine := bptest.NewEngine(t, envs, func(group *gin.RouterGroup) {
+			Init(envs, nil, bptest.NewPubSubAgent(t), group)
+		})
+		response := bptest.Request(t, engine, http.MethodGet, "/api/v1/users/not-a-uuid", nil, &authUser)
+		bptest.AssertJSON(t, response, http.StatusUnprocessableEntity, `{"type": "about:blank", "title": "Unprocessable Entity", "status": 422, "code": "validation-error", "detail": "The request contains invalid parameters", "instance": "/api/v1/users/not-a-uuid", "errors": {"ID": ["is-uuid"]}}`)
+	})
+}
+
+func TestGetUserQuotaPerAPIKey(t *testing.T) {
+	envs := bptest.NewEnvs()
+	// The quota is consumed before the validation of the handler, so invalid IDs do not need a database,
+	// while the OpenAPI validation would reject them before
+	envs.AppOpenAPIValidation = false
+	envs.QuotaFile = filepath.Join(t.TempDir(), "quotas.yaml")
+	if err := os.WriteFile(envs.QuotaFile, []byte("default: { daily: 1, monthly: 0 }\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pubSubAgent := bptest.NewPubSubAgent(t)
+	engine := bptest.NewEngine(t, envs, func(group *gin.RouterGroup) {
+		Init(envs, nil, pubSubAgent, group)
+	})
+	authUser := bptest.MintAuthUser(bpauth.UserGet)
+	firstKey := bptest.MintAPIKey(authUser)
+	secondKey := bptest.MintAPIKey(authUser)
+	path := "/api/v1/users/not-a-uuid"
+
+	if response := bptest.Request(t, engine, http.MethodGet, path, nil, &firstKey); response.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected the first request of the key to proceed, got %d", response.Code)
+	}
+	if response := bptest.Request(t, engine, http.MethodGet, path, nil, &firstKey); response.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the quota of the key to be exceeded, got %d", response.Code)
+	}
+	if response := bptest.Request(t, engine, http.MethodGet, path, nil, &secondKey); response.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected another key of the user to have its own quota, got %d", response.Code)
+	}
+	if response := bptest.Request(t, engine, http.MethodGet, path, nil, &authUser); response.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected the user to have its own quota, got %d", response.Code)
+	}
+}
+
+func TestListUsersInvalidCursor(t *testing.T) {
+	engine := newTestEngine(t, nil)
+	authUser := bptest.MintAuthUser(bpauth.UserList)
+	response := bptest.Request(t, engine, http.MethodGet, "/api/v1/users?cursor=not-a-cursor", nil, &authUser)
+	bptest.AssertJSON(t, response, http.StatusBadRequest, `{"type": "about:blank", "title": "Bad Request", "status": 400, "code": "invalid-cursor", "detail": "The cursor is not valid for this request", "instance": "/api/v1/users"}`)
+
+	response = bptest.Request(t, engine, http.MethodGet, "/api/v1/users?cursor=not-a-cursor&page=2", nil, &authUser)
+	var body bprouter.Problem
+	bptest.DecodeJSON(t, response, http.StatusUnprocessableEntity, &body)
+	if len(body.Errors["page"]) == 0 {
+		t.Errorf("expected the page to be rejected along with a cursor, got %v", body)
+	}
+}
+
+func TestListUsersInvalidFilter(t *testing.T) {
+	engine := newTestEngine(t, nil)
+	authUser := bptest.MintAuthUser(bpauth.UserList)
+	response := bptest.Request(t, engine, http.MethodGet, "/api/v1/users?filter[version][gt]=1&filter[email][gt]=a", nil, &authUser)
+	bptest.AssertJSON(t, response, http.StatusUnprocessableEntity, `{"type": "about:blank", "title": "Unprocessable Entity", "status": 422, "code": "validation-error", "detail": "The request contains invalid parameters", "instance": "/api/v1/users", "errors": {"filter[version][gt]": ["unknown-field"], "filter[email][gt]": ["unsupported-operator"]}}`)
+}
+
+func TestCreateUserReplayed(t *testing.T) {
+	tx := bptest.RequireDatabase(t, testDatabase)
+	envs := bptest.NewEnvs()
+	pubSubAgent := bptest.NewPubSubAgent(t)
+	// The module is wired without its consumer, which would create the user again on the test transaction
+	engine := bptest.NewEngine(t, envs, func(group *gin.RouterGroup) {
+		repository := newUserRepository(tx, envs.SearchRelevanceThreshold)
+		service := newUserService(bpdb.NewTxManager(tx, envs.DbTransactionMaxRetries), pubSubAgent, repository, bpcache.New[userModel]("user", 0))
+		newUserRouter(service).register(group)
+	})
+	authUser := bptest.MintAuthUser(bpauth.UserCreate)
+	body := gin.H{"firstname": "Alice", "lastname": "Anderson", "email": "alice.anderson@example.com"}
+	headers := map[string]string{bpidempotency.IdempotencyKeyHeader: "create-alice"}
+
+	first := bptest.RequestWithHeaders(t, engine, http.MethodPost, "/api/v1/users", body, &authUser, headers)
+	var created struct {
+		Item userOutputDto `json:"item"`
+	}
+	bptest.DecodeJSON(t, first, http.StatusCreated, &created)
+	replayed := bptest.RequestWithHeaders(t, engine, http.MethodPost, "/api/v1/users", body, &authUser, headers)
+	bptest.AssertJSON(t, replayed, http.StatusCreated, first.Body.String())
+	if replayed.Header().Get(bpidempotency.ReplayedHeader) != "true" {
+		t.Errorf("expected the creation to be replayed, got %v", replayed.Header())
+	}
+	var count int64
+	if err := tx.Model(&userModel{}).Where("email = ?", "alice.anderson@example.com").Count(&count).Error; err != nil {
+		t.Fatalf("unable to count users: %v", err)
+	}
+	if count != 1 || created.Item.Email != "alice.anderson@example.com" {
+		t.Errorf("expected a single user to be created, got %d", count)
+	}
+}
+
+func TestUpdateUserPreconditions(t *testing.T) {
+	engine := newTestEngine(t, nil)
+	authUser := bptest.MintAuthUser(bpauth.UserUpdate)
+	body := gin.H{"firstname": "Alice", "lastname": "Anderson", "email": "alice.anderson@example.com"}
+	path := "/api/v1/users/7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d001"
+
+	response := bptest.Request(t, engine, http.MethodPut, path, body, &authUser)
+	bptest.AssertJSON(t, response, http.StatusPreconditionRequired, `{"type": "about:blank", "title": "Precondition Required", "status": 428, "code": "precondition-required", "detail": "The If-Match header is required", "instance": "/api/v1/users/7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d001"}`)
+
+	response = bptest.RequestWithHeaders(t, engine, http.MethodPut, path, body, &authUser, map[string]string{"If-Match": "\"one\""})
+	bptest.AssertJSON(t, response, http.StatusPreconditionFailed, `{"type": "about:blank", "title": "Precondition Failed", "status": 412, "code": "precondition-failed", "detail": "The resource has been changed since it was read", "instance": "/api/v1/users/7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d001"}`)
+}
+
+func TestUpdateUserWithCompressedETag(t *testing.T) {
+	tx := bptest.RequireDatabase(t, testDatabase)
+	user, err := newUserRepository(tx, 0.05).saveUser(context.Background(), newTestUser("Alice", "Anderson", "alice.anderson@example.com"))
+	if err != nil {
+		t.Fatalf("unable to save user: %v", err)
+	}
+	envs := bptest.NewEnvs()
+	envs.AppCompressionMinBytes = 0
+	pubSubAgent := bptest.NewPubSubAgent(t)
+	engine := bptest.NewEngine(t, envs, func(group *gin.RouterGroup) {
+		Init(envs, tx, pubSubAgent, group)
+	})
+	authUser := bptest.MintAuthUser(bpauth.UserGet, bpauth.UserUpdate)
+	path := "/api/v1/users/" + user.id.String()
+
+	response := bptest.RequestWithHeaders(t, engine, http.MethodGet, path, nil, &authUser, map[string]string{"Accept-Encoding": "gzip"})
+	etag := response.Header().Get("ETag")
+	if response.Code != http.StatusOK || response.Header().Get("Content-Encoding") != "gzip" || etag == "" {
+		t.Fatalf("expected a compressed response with an ETag, got %d %v", response.Code, response.Header())
+	}
+	body := gin.H{"firstname": "Alicia", "lastname": "Anderson", "email": "alice.anderson@example.com"}
+	response = bptest.RequestWithHeaders(t, engine, http.MethodPut, path, body, &authUser, map[string]string{"If-Match": etag})
+	if response.Code != http.StatusOK {
+		t.Errorf("expected the update with the ETag %s to succeed, got %d %s", etag, response.Code, response.Body.String())
+	}
+}
@@ -0,0 +1,105 @@
+package user
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpdb"
+	"github.com/besasch88/blueprint/internal/pkg/bppubsub"
+	"github.com/besasch88/blueprint/internal/pkg/bpseed"
+	"github.com/besasch88/blueprint/internal/pkg/bputils"
+	"github.com/google/uuid"
+)
+
+type userSeederInterface interface {
+	loadFixture(fixture bpseed.Fixture) (int, error)
+	generateFake(count int) (int, error)
+}
+
+type userSeeder struct {
+	txManager   bpdb.TxManager
+	pubSubAgent *bppubsub.PubSubAgent
+	repository  userRepositoryInterface
+}
+
+func newUserSeeder(txManager bpdb.TxManager, pubSubAgent *bppubsub.PubSubAgent, repository userRepositoryInterface) userSeeder {
+	return userSeeder{
+		txManager:   txManager,
+		pubSubAgent: pubSubAgent,
+		repository:  repository,
+	}
+}
+
+func (s userSeeder) loadFixture(fixture bpseed.Fixture) (int, error) {
+	var items []createUserInputDto
+	if err := fixture.Decode(&items); err != nil {
+		return 0, err
+	}
+	for i, item := range items {
+		if err := item.validate(); err != nil {
+			return 0, fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	return s.upsertUsers(items)
+}
+
+func (s userSeeder) generateFake(count int) (int, error) {
+	var items []createUserInputDto
+	for i := 0; i < count; i++ {
+		person := bpseed.NewFakePerson()
+		items = append(items, createUserInputDto{
+			ID:        uuid.NewString(),
+			Firstname: person.Firstname,
+			Lastname:  person.Lastname,
+			Email:     person.Email,
+		})
+	}
+	return s.upsertUsers(items)
+}
+
+/*
+Store all the users in a single transaction by using their ID as upsert key.
+The deletion of existing users is kept, so reloading fixtures does not restore soft deleted users.
+Events are published only once the transaction is committed, as it happens for the service.
+*/
+func (s userSeeder) upsertUsers(items []createUserInputDto) (int, error) {
+	// Fixtures are not loaded on behalf of any user, so the nil UUID is used as actor.
+	ctx := bpdb.WithActor(context.Background(), uuid.Nil)
+	errTransaction := s.txManager.Run(ctx, func(txCtx context.Context) error {
+		for _, item := range items {
+			eventType := bppubsub.UserCreatedEvent
+			user := userEntity{
+				id:        uuid.MustParse(item.ID),
+				firstname: item.Firstname,
+				lastname:  item.Lastname,
+				email:     item.Email,
+			}
+			existing, err := s.repository.getUserByID(txCtx, user.id, true, true)
+			if err != nil {
+				return err
+			}
+			if !bputils.IsEmpty(existing) {
+				eventType = bppubsub.UserUpdatedEvent
+				user.createdAt = existing.createdAt
+				user.createdBy = existing.createdBy
+				user.version = existing.version
+				// Soft deleted users stay deleted, fixtures do not restore them
+				user.deletedAt = existing.deletedAt
+				user.deletedBy = existing.deletedBy
+			}
+			if user, err = s.repository.saveUser(txCtx, user); err != nil {
+				return err
+			}
+			// Seeding runs outside of any HTTP request, so there is no request context to forward.
+			event := newUserEvent(eventType, user)
+			bpdb.AfterCommit(txCtx, func() {
+				s.pubSubAgent.Publish(bppubsub.TopicUserV1, bppubsub.PubSubMessage{Message: event})
+			})
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		return 0, errTransaction
+	}
+	return len(items), nil
+}
@@ -1,90 +1,194 @@
 package user
 
 import (
-	"time"
+	"context"
+	"errors"
 
+	"github.com/besasch88/blueprint/internal/pkg/bpcache"
+	"github.com/besasch88/blueprint/internal/pkg/bpdb"
 	"github.com/besasch88/blueprint/internal/pkg/bperr"
 	"github.com/besasch88/blueprint/internal/pkg/bppubsub"
 	"github.com/besasch88/blueprint/internal/pkg/bputils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
+	"go.uber.org/zap"
 )
 
 type userServiceInterface interface {
+	listUsers(ctx *gin.Context, input listUsersInputDto) ([]userEntity, int64, error)
+	listUsersByKeyset(ctx *gin.Context, input listUsersInputDto, keyset *bpdb.Keyset) ([]userEntity, bpdb.KeysetPage, int64, error)
 	getUserByID(ctx *gin.Context, input getUserInputDto) (userEntity, error)
+	getUsersByIDs(ctx *gin.Context, userIDs []string) ([]userEntity, error)
 	createUser(ctx *gin.Context, requesterID uuid.UUID, input createUserInputDto) (userEntity, error)
+	updateUser(ctx *gin.Context, requesterID uuid.UUID, input updateUserInputDto, expectedVersion int64) (userEntity, error)
 }
 
 type userService struct {
-	storage     *gorm.DB
+	txManager   bpdb.TxManager
 	pubSubAgent *bppubsub.PubSubAgent
 	repository  userRepositoryInterface
+	cache       bpcache.Cache[userModel]
 }
 
-func newUserService(storage *gorm.DB, pubSubAgent *bppubsub.PubSubAgent, repository userRepositoryInterface) userService {
+func newUserService(txManager bpdb.TxManager, pubSubAgent *bppubsub.PubSubAgent, repository userRepositoryInterface, cache bpcache.Cache[userModel]) userService {
 	return userService{
-		storage:     storage,
+		txManager:   txManager,
 		pubSubAgent: pubSubAgent,
 		repository:  repository,
+		cache:       cache,
 	}
 }
 
+func (s userService) listUsers(ctx *gin.Context, input listUsersInputDto) ([]userEntity, int64, error) {
+	limit, offset := bputils.PagePageSizeToLimitOffset(input.Page, input.PageSize)
+	items, totalCount, err := s.repository.listUsers(ctx, limit, offset, userOrderBy(input.OrderBy), bpdb.OrderDir(input.OrderDir), input.SearchKey, input.filters, false, false)
+	if err != nil {
+		return []userEntity{}, 0, bperr.ErrGeneric.WithCause(err)
+	}
+	return items, totalCount, nil
+}
+
+/*
+List the users starting from the keyset, or from the first page if nil.
+Counting the users is skipped if requested, since it becomes expensive as the users grow.
+*/
+func (s userService) listUsersByKeyset(ctx *gin.Context, input listUsersInputDto, keyset *bpdb.Keyset) ([]userEntity, bpdb.KeysetPage, int64, error) {
+	items, page, totalCount, err := s.repository.listUsersByKeyset(ctx, input.PageSize, userOrderBy(input.OrderBy), bpdb.OrderDir(input.OrderDir), keyset, input.SearchKey, input.filters, false, !input.SkipCount)
+	if err == bpdb.ErrInvalidKeyset {
+		return []userEntity{}, bpdb.KeysetPage{}, 0, bperr.ErrInvalidCursor.WithCause(err)
+	}
+	if err != nil {
+		return []userEntity{}, bpdb.KeysetPage{}, 0, bperr.ErrGeneric.WithCause(err)
+	}
+	return items, page, totalCount, nil
+}
+
+/*
+Get the user from the cache, reading it from the database on misses. Users are cached as models,
+since the fields of the entities are unexported, and invalidated by the user events.
+*/
 func (s userService) getUserByID(ctx *gin.Context, input getUserInputDto) (userEntity, error) {
-	userID := uuid.MustParse(input.id)
-	item, err := s.repository.getUserByID(s.storage, userID, false)
+	userID := uuid.MustParse(input.ID)
+	model, err := s.cache.Get(ctx, userID.String(), func(ctx context.Context) (userModel, error) {
+		item, err := s.repository.getUserByID(ctx, userID, false, false)
+		if err != nil {
+			return userModel{}, bperr.ErrGeneric
+		}
+		if bputils.IsEmpty(item) {
+			return userModel{}, errUserNotFound
+		}
+		return newUserModel(item), nil
+	})
 	if err != nil {
-		return userEntity{}, bperr.ErrGeneric
+		return userEntity{}, err
 	}
-	if bputils.IsEmpty(item) {
-		return userEntity{}, errUserNotFound
+	return model.toEntity(), nil
+}
+
+/*
+Return the key of the user changed by an event, so it is removed from the cache.
+*/
+func changedUserKey(event bppubsub.PubSubEvent) (string, bool) {
+	if event.EventType != bppubsub.UserUpdatedEvent && event.EventType != bppubsub.UserDeletedEvent {
+		return "", false
+	}
+	user, ok := event.EventEntity.(bppubsub.UserEventEntity)
+	if !ok {
+		return "", false
+	}
+	return user.ID.String(), true
+}
+
+/*
+Get the users with the given IDs at once, e.g. to expand the users related to a page of resources.
+Invalid IDs are skipped, as the ones of deleted users.
+*/
+func (s userService) getUsersByIDs(ctx *gin.Context, userIDs []string) ([]userEntity, error) {
+	ids := []uuid.UUID{}
+	for _, userID := range userIDs {
+		if id, err := uuid.Parse(userID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return []userEntity{}, nil
+	}
+	items, err := s.repository.getUsersByIDs(ctx, ids, false)
+	if err != nil {
+		return []userEntity{}, bperr.ErrGeneric.WithCause(err)
 	}
-	return item, nil
+	return items, nil
 }
 
 func (s userService) createUser(ctx *gin.Context, requesterID uuid.UUID, input createUserInputDto) (userEntity, error) {
-	now := time.Now()
 	user := userEntity{
 		id:        uuid.MustParse(input.ID),
 		firstname: input.Firstname,
 		lastname:  input.Lastname,
 		email:     input.Email,
-		createdAt: now,
-		updatedAt: now,
-		deletedAt: nil,
-		createdBy: requesterID,
-		updatedBy: requesterID,
-		deletedBy: nil,
 	}
-	errTransaction := s.storage.Transaction(func(tx *gorm.DB) error {
-		_, err := s.repository.saveUser(tx, user)
-		if err != nil {
-			return bperr.ErrGeneric
+	errTransaction := s.txManager.Run(bpdb.WithActor(ctx, requesterID), func(txCtx context.Context) error {
+		var err error
+		if user, err = s.repository.saveUser(txCtx, user); err != nil {
+			return err
 		}
+		bpdb.AfterCommit(txCtx, func() {
+			go s.pubSubAgent.Publish(bppubsub.TopicUserV1, bppubsub.PubSubMessage{
+				Context: ctx.Copy(),
+				Message: newUserEvent(bppubsub.UserCreatedEvent, user),
+			})
+		})
 		return nil
 	})
 	if errTransaction != nil {
-		return userEntity{}, errTransaction
+		return userEntity{}, bperr.ErrGeneric
 	}
-	go s.pubSubAgent.Publish(bppubsub.TopicUserV1, bppubsub.PubSubMessage{
-		Context: ctx.Copy(),
-		Message: bppubsub.PubSubEvent{
-			EventID:   uuid.New(),
-			EventTime: time.Now(),
-			EventType: bppubsub.UserCreatedEvent,
-			EventEntity: bppubsub.UserEventEntity{
-				ID:        user.id,
-				Firstname: user.firstname,
-				Lastname:  user.lastname,
-				Email:     user.email,
-				CreatedAt: user.createdAt,
-				UpdatedAt: user.updatedAt,
-				DeletedAt: user.deletedAt,
-				CreatedBy: user.createdBy,
-				UpdatedBy: user.updatedBy,
-				DeletedBy: user.deletedBy,
-			},
-		},
+	return user, nil
+}
+
+/*
+Update the user only if its version is still the one the requester read.
+A zero expected version skips the check, while concurrent updates are still detected when saving.
+*/
+func (s userService) updateUser(ctx *gin.Context, requesterID uuid.UUID, input updateUserInputDto, expectedVersion int64) (userEntity, error) {
+	var user userEntity
+	errTransaction := s.txManager.Run(bpdb.WithActor(ctx, requesterID), func(txCtx context.Context) error {
+		existing, err := s.repository.getUserByID(txCtx, uuid.MustParse(input.ID), false, false)
+		if err != nil {
+			return err
+		}
+		if bputils.IsEmpty(existing) {
+			return errUserNotFound
+		}
+		if expectedVersion != 0 && existing.version != expectedVersion {
+			return errUserVersionConflict
+		}
+		existing.firstname = input.Firstname
+		existing.lastname = input.Lastname
+		existing.email = input.Email
+		if user, err = s.repository.saveUser(txCtx, existing); err != nil {
+			return err
+		}
+		bpdb.AfterCommit(txCtx, func() {
+			// The change is visible to the next reads of the requester, without waiting for the event
+			if err := s.cache.Delete(ctx, user.id.String()); err != nil {
+				zap.L().Error("User not removed from the cache", zap.String("service", "user-service"), zap.Error(err))
+			}
+			go s.pubSubAgent.Publish(bppubsub.TopicUserV1, bppubsub.PubSubMessage{
+				Context: ctx.Copy(),
+				Message: newUserEvent(bppubsub.UserUpdatedEvent, user),
+			})
+		})
+		return nil
 	})
+	if errTransaction == errUserNotFound {
+		return userEntity{}, errUserNotFound
+	}
+	if errTransaction == errUserVersionConflict || errors.Is(errTransaction, bpdb.ErrVersionConflict) {
+		return userEntity{}, errUserVersionConflict
+	}
+	if errTransaction != nil {
+		return userEntity{}, bperr.ErrGeneric
+	}
 	return user, nil
 }
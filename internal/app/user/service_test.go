@@ -0,0 +1,117 @@
+package user
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpcache"
+	"github.com/besasch88/blueprint/internal/pkg/bpdb"
+	"github.com/besasch88/blueprint/internal/pkg/bppubsub"
+	"github.com/besasch88/blueprint/internal/pkg/bptest"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestServiceGetUserByID(t *testing.T) {
+	tx := bptest.RequireDatabase(t, testDatabase)
+	repository := newUserRepository(tx, 0.05)
+	service := newUserService(bpdb.NewTxManager(tx, 0), bptest.NewPubSubAgent(t), repository, bpcache.New[userModel]("user", 0))
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	user := newTestUser("Alice", "Anderson", "alice.anderson@example.com")
+	if _, err := repository.saveUser(ctx, user); err != nil {
+		t.Fatalf("unable to save user: %v", err)
+	}
+
+	item, err := service.getUserByID(ctx, getUserInputDto{ID: user.id.String()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if item.id != user.id || item.email != user.email {
+		t.Errorf("unexpected user: %+v", item)
+	}
+
+	_, err = service.getUserByID(ctx, getUserInputDto{ID: uuid.NewString()})
+	if err != errUserNotFound {
+		t.Errorf("expected %v, got %v", errUserNotFound, err)
+	}
+}
+
+func TestServiceCreateUser(t *testing.T) {
+	tx := bptest.RequireDatabase(t, testDatabase)
+	pubSubAgent := bptest.NewPubSubAgent(t)
+	events := pubSubAgent.Subscribe(bppubsub.TopicUserV1)
+	repository := newUserRepository(tx, 0.05)
+	service := newUserService(bpdb.NewTxManager(tx, 0), pubSubAgent, repository, bpcache.New[userModel]("user", 0))
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	requesterID := uuid.New()
+	input := createUserInputDto{
+		ID:        uuid.NewString(),
+		Firstname: "Marco",
+		Lastname:  "Rossi",
+		Email:     "marco.rossi@example.com",
+	}
+
+	user, err := service.createUser(ctx, requesterID, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stored, err := repository.getUserByID(ctx, user.id, false, false)
+	if err != nil {
+		t.Fatalf("unable to read user: %v", err)
+	}
+	if stored.email != input.Email || stored.createdBy != requesterID || stored.updatedBy != requesterID {
+		t.Errorf("unexpected stored user: %+v", stored)
+	}
+
+	select {
+	case msg := <-events:
+		if msg.Message.EventType != bppubsub.UserCreatedEvent {
+			t.Errorf("unexpected event type %s", msg.Message.EventType)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected a user created event")
+	}
+}
+
+func newTestUser(firstname string, lastname string, email string) userEntity {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	return userEntity{
+		id:        uuid.New(),
+		email:     email,
+		firstname: firstname,
+		lastname:  lastname,
+		createdAt: now,
+		updatedAt: now,
+		createdBy: uuid.Nil,
+		updatedBy: uuid.Nil,
+	}
+}
+
+func TestServiceUpdateUserVersionConflict(t *testing.T) {
+	tx := bptest.RequireDatabase(t, testDatabase)
+	repository := newUserRepository(tx, 0.05)
+	service := newUserService(bpdb.NewTxManager(tx, 0), bptest.NewPubSubAgent(t), repository, bpcache.New[userModel]("user", 0))
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	user, err := repository.saveUser(ctx, newTestUser("Alice", "Anderson", "alice.anderson@example.com"))
+	if err != nil {
+		t.Fatalf("unable to save user: %v", err)
+	}
+	input := updateUserInputDto{ID: user.id.String(), Firstname: "Alicia", Lastname: "Anderson", Email: user.email}
+
+	updated, err := service.updateUser(ctx, uuid.New(), input, user.version)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.version != user.version+1 || updated.firstname != "Alicia" {
+		t.Errorf("unexpected updated user: %+v", updated)
+	}
+	// The version read before the first update is now stale.
+	if _, err := service.updateUser(ctx, uuid.New(), input, user.version); err != errUserVersionConflict {
+		t.Errorf("expected %v, got %v", errUserVersionConflict, err)
+	}
+	// Saving a stale entity is rejected by the repository too.
+	if _, err := repository.saveUser(ctx, user); err != bpdb.ErrVersionConflict {
+		t.Errorf("expected %v, got %v", bpdb.ErrVersionConflict, err)
+	}
+}
@@ -26,6 +26,10 @@ type AuthUser struct {
 	UpdatedAt time.Time
 	CreatedBy uuid.UUID
 	UpdatedBy uuid.UUID
+	Claims    []string
+	// ID of the API key authenticating the request, set by the AuthUserProvider from the token,
+	// empty for the requests of the user itself
+	APIKeyID string
 }
 
 /*
@@ -33,7 +37,13 @@ List of claims we can leverage to evaluate if an authenticated user can perform
 before performing the API logic.
 */
 const (
+	UserList   = "user-l"
 	UserGet    = "user-g"
+	UserCreate = "user-c"
 	UserUpdate = "user-u"
 	UserDelete = "user-d"
+	// Rate limit usage of the authenticated user
+	RateLimitGet = "rate-limit-g"
+	// Stats of the caches
+	CacheStatsGet = "cache-stats-g"
 )
@@ -1,6 +1,8 @@
 package bpauth
 
 import (
+	"slices"
+
 	"github.com/besasch88/blueprint/internal/pkg/bprouter"
 	"github.com/besasch88/blueprint/internal/pkg/bputils"
 	"github.com/gin-gonic/gin"
@@ -19,7 +21,7 @@ implemented based on the auth service you will use.
 func AuthMiddleware(claimsToCheck []string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		// Retrieve the authenticated user
-		authUser, err := getAuthUserFromRequest(ctx)
+		authUser, err := authUserProvider(ctx)
 		// In case of error or if the user is not found, return Unauthorized
 		if err != nil || bputils.IsEmpty(authUser) {
 			bprouter.ReturnUnauthorizedError(ctx)
@@ -30,15 +32,38 @@ func AuthMiddleware(claimsToCheck []string) gin.HandlerFunc {
 			bprouter.ReturnForbiddenError(ctx)
 			return
 		}
-		ctx.Set(contextAuthUser, authUser)
+		for _, claim := range claimsToCheck {
+			if !slices.Contains(authUser.Claims, claim) {
+				bprouter.ReturnForbiddenError(ctx)
+				return
+			}
+		}
+		ctx.Set(contextAuthUser, &authUser)
 		ctx.Next()
 	}
 }
 
+/*
+AuthUserProvider retrieves the authenticated user from the request.
+It returns an empty user if the request is not authenticated.
+*/
+type AuthUserProvider func(ctx *gin.Context) (AuthUser, error)
+
+var authUserProvider AuthUserProvider = getAuthUserFromRequest
+
+/*
+SetAuthUserProvider replaces the logic used by the AuthMiddleware to retrieve the authenticated user.
+It is useful to plug a different auth system or to authenticate users in tests.
+*/
+func SetAuthUserProvider(provider AuthUserProvider) {
+	authUserProvider = provider
+}
+
 /*
 Retrieve the authenticated user from the request.
 Here you can implement the logic to retrieve the user info from the JWT
 and additional information from the database (or from external auth service like supertokens, auth0, etc.).
+When the token is an API key of the user, its ID must be set in APIKeyID, so each key has its own quotas.
 
 @TODO This logic needs to be implemented based on the Auth system you will use.
 */
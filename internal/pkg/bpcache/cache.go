@@ -0,0 +1,268 @@
+package bpcache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bppubsub"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+/*
+Store represents where the cached values are kept.
+*/
+type Store string
+
+/*
+List of available stores.
+*/
+const (
+	RedisStore  Store = "redis"
+	MemoryStore Store = "memory"
+)
+
+/*
+AvailableStores represents a list of available stores. It is generally used
+to validate inputs.
+*/
+var AvailableStores = []interface{}{RedisStore, MemoryStore}
+
+/*
+Max duration of a load from the source. Loads are shared among the callers, so they do not follow
+the context of the caller starting them, which may be cancelled while the others are still waiting.
+*/
+const loadTimeout = 30 * time.Second
+
+/*
+CacheStore represents the store of the encoded values, shared by all the caches.
+*/
+type cacheStore interface {
+	get(ctx context.Context, key string) ([]byte, bool, error)
+	set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	delete(ctx context.Context, keys ...string) error
+}
+
+/*
+Cache is a read-through cache of values of the same type, stored as JSON in the store selected on Init.
+Values are loaded from the source on misses, with concurrent misses of the same key sharing a single load,
+so an expired hot key does not hit the source once per request. Errors of the source are not cached,
+nor are the values loaded while their key is deleted, since they may precede the change causing the deletion.
+Without a store, e.g. in commands not calling Init, values are always loaded from the source.
+*/
+type Cache[V any] struct {
+	name  string
+	ttl   time.Duration
+	group *singleflight.Group
+	loads *inFlightLoads
+	stats *cacheStats
+}
+
+/*
+New creates a cache whose keys are prefixed by the given name, expiring its values after the given TTL,
+or after the default TTL set on Init if zero. Caches with the same name share their stats.
+*/
+func New[V any](name string, ttl time.Duration) Cache[V] {
+	return Cache[V]{
+		name:  name,
+		ttl:   ttl,
+		group: &singleflight.Group{},
+		loads: newInFlightLoads(),
+		stats: registerStats(name),
+	}
+}
+
+/*
+Get the value of the key, loading it from the source and caching it on misses. If the store cannot be reached,
+the value is loaded from the source, so the cache never makes a request fail. Shared loads run detached from
+the cancellation of the callers, each of them returning as soon as its own context is done. E.g.
+
+	item, err := s.cache.Get(ctx, userID.String(), func(ctx context.Context) (userModel, error) {
+		... // read from the database
+	})
+*/
+func (c Cache[V]) Get(ctx context.Context, key string, load func(ctx context.Context) (V, error)) (V, error) {
+	if store == nil {
+		return load(ctx)
+	}
+	storeKey := c.key(key)
+	data, found, err := store.get(ctx, storeKey)
+	if err != nil {
+		c.stats.errors.Add(1)
+		zap.L().Warn(fmt.Sprintf("Cache %s not readable. Loading from the source...", c.name), zap.String("service", "cache"), zap.Error(err))
+	}
+	if found {
+		var value V
+		if err := json.Unmarshal(data, &value); err == nil {
+			c.stats.hits.Add(1)
+			return value, nil
+		}
+		c.stats.errors.Add(1)
+	}
+	c.stats.misses.Add(1)
+	results := c.group.DoChan(storeKey, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+		c.stats.loads.Add(1)
+		generation := c.loads.start(storeKey)
+		defer c.loads.finish(storeKey)
+		value, err := load(loadCtx)
+		if err != nil {
+			return value, err
+		}
+		if !c.loads.current(storeKey, generation) {
+			return value, nil
+		}
+		data, err := json.Marshal(value)
+		if err == nil {
+			err = store.set(loadCtx, storeKey, data, c.expiration())
+		}
+		// The key may have been deleted while the value was being stored
+		if err == nil && !c.loads.current(storeKey, generation) {
+			err = store.delete(loadCtx, storeKey)
+		}
+		if err != nil {
+			c.stats.errors.Add(1)
+			zap.L().Warn(fmt.Sprintf("Cache %s not writable", c.name), zap.String("service", "cache"), zap.Error(err))
+		}
+		return value, nil
+	})
+	var zero V
+	select {
+	case result := <-results:
+		if result.Err != nil {
+			return zero, result.Err
+		}
+		return result.Val.(V), nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+/*
+Delete the given keys, so their next reads are loaded from the source. Loads of the keys in progress
+in this instance return their value without caching it.
+*/
+func (c Cache[V]) Delete(ctx context.Context, keys ...string) error {
+	if store == nil || len(keys) == 0 {
+		return nil
+	}
+	storeKeys := make([]string, len(keys))
+	for i, key := range keys {
+		storeKeys[i] = c.key(key)
+		c.loads.invalidate(storeKeys[i])
+		c.group.Forget(storeKeys[i])
+	}
+	if err := store.delete(ctx, storeKeys...); err != nil {
+		c.stats.errors.Add(1)
+		return err
+	}
+	c.stats.invalidations.Add(int64(len(keys)))
+	return nil
+}
+
+/*
+InvalidateOn deletes the keys of the values changed by the events published on the topic. The keyOf function
+returns the key of the value changed by an event, or false if the event does not change any value. E.g.
+
+	cache.InvalidateOn(pubSubAgent, bppubsub.TopicUserV1, func(event bppubsub.PubSubEvent) (string, bool) {
+		... // return the key of the changed user
+	})
+
+Events are published only inside the application instance: with the in-memory store, values changed
+by other instances are refreshed once they expire.
+*/
+func (c Cache[V]) InvalidateOn(pubSubAgent *bppubsub.PubSubAgent, topic bppubsub.PubSubTopic, keyOf func(event bppubsub.PubSubEvent) (string, bool)) {
+	messageChannel := pubSubAgent.Subscribe(topic)
+	if messageChannel == nil {
+		return
+	}
+	go func() {
+		for msg := range messageChannel {
+			key, ok := keyOf(msg.Message)
+			if !ok {
+				continue
+			}
+			if err := c.Delete(context.Background(), key); err != nil {
+				zap.L().Error(fmt.Sprintf("Cache %s not invalidated", c.name), zap.String("service", "cache"), zap.String("event-id", msg.Message.EventID.String()), zap.Error(err))
+			}
+		}
+	}()
+}
+
+/*
+Build the key of a value in the store, e.g. `cache:user:<uuid>`.
+*/
+func (c Cache[V]) key(key string) string {
+	return fmt.Sprintf("cache:%s:%s", c.name, key)
+}
+
+func (c Cache[V]) expiration() time.Duration {
+	if c.ttl > 0 {
+		return c.ttl
+	}
+	return defaultTTL
+}
+
+/*
+InFlightLoads tracks the keys being loaded from the source, with a generation incremented on their deletion,
+so loads started before a deletion can tell their value is stale. Keys are tracked only while loaded.
+*/
+type inFlightLoads struct {
+	mu   sync.Mutex
+	keys map[string]*inFlightLoad
+}
+
+type inFlightLoad struct {
+	generation uint64
+	count      int
+}
+
+func newInFlightLoads() *inFlightLoads {
+	return &inFlightLoads{keys: map[string]*inFlightLoad{}}
+}
+
+/*
+Track a load of the key, returning the current generation of the key.
+*/
+func (l *inFlightLoads) start(key string) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	load, exists := l.keys[key]
+	if !exists {
+		load = &inFlightLoad{}
+		l.keys[key] = load
+	}
+	load.count++
+	return load.generation
+}
+
+func (l *inFlightLoads) finish(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	load := l.keys[key]
+	load.count--
+	if load.count == 0 {
+		delete(l.keys, key)
+	}
+}
+
+/*
+Return true if the key has not been deleted since the load of the given generation started.
+*/
+func (l *inFlightLoads) current(key string, generation uint64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.keys[key].generation == generation
+}
+
+func (l *inFlightLoads) invalidate(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if load, exists := l.keys[key]; exists {
+		load.generation++
+	}
+}
@@ -0,0 +1,221 @@
+package bpcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/besasch88/blueprint/internal/pkg/bppubsub"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+type testItem struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+/*
+The bptest package cannot be used here since it depends on this package.
+*/
+func useTestStores(t *testing.T) map[Store]cacheStore {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	defaultTTL = time.Minute
+	stats = map[string]*cacheStats{}
+	return map[Store]cacheStore{
+		MemoryStore: newMemoryCache(100),
+		RedisStore:  redisCache{client: client},
+	}
+}
+
+func TestCacheReadThrough(t *testing.T) {
+	for name, testStore := range useTestStores(t) {
+		t.Run(string(name), func(t *testing.T) {
+			store = testStore
+			cache := New[testItem]("test-read-through-"+string(name), 0)
+			loads := 0
+			load := func(ctx context.Context) (testItem, error) {
+				loads++
+				return testItem{ID: "1", Title: "Title"}, nil
+			}
+			for i := 0; i < 3; i++ {
+				item, err := cache.Get(context.Background(), "1", load)
+				if err != nil || item.Title != "Title" {
+					t.Fatalf("expected the loaded item, got %+v %v", item, err)
+				}
+			}
+			if err := cache.Delete(context.Background(), "1"); err != nil {
+				t.Fatal(err)
+			}
+			cache.Get(context.Background(), "1", load)
+			if loads != 2 {
+				t.Errorf("expected the item to be loaded again only after its deletion, got %d loads", loads)
+			}
+			output := newCacheStatsOutputDto(cache.name, cache.stats)
+			if output.Hits != 2 || output.Misses != 2 || output.Invalidations != 1 || output.HitRatio != 0.5 {
+				t.Errorf("unexpected stats %+v", output)
+			}
+		})
+	}
+}
+
+func TestCacheDoesNotCacheErrors(t *testing.T) {
+	store = useTestStores(t)[MemoryStore]
+	cache := New[testItem]("test-errors", 0)
+	errNotFound := errors.New("not found")
+	calls := 0
+	for i := 0; i < 2; i++ {
+		_, err := cache.Get(context.Background(), "1", func(ctx context.Context) (testItem, error) {
+			calls++
+			return testItem{}, errNotFound
+		})
+		if err != errNotFound {
+			t.Fatalf("expected the error of the source, got %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected errors not to be cached, got %d calls", calls)
+	}
+}
+
+func TestCacheSharesConcurrentLoads(t *testing.T) {
+	store = useTestStores(t)[MemoryStore]
+	cache := New[testItem]("test-singleflight", 0)
+	var loads atomic.Int64
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Get(context.Background(), "1", func(ctx context.Context) (testItem, error) {
+				loads.Add(1)
+				<-release
+				return testItem{ID: "1"}, nil
+			})
+		}()
+	}
+	// Let all the readers miss before completing the load
+	for cache.stats.misses.Load() < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if loads.Load() != 1 {
+		t.Errorf("expected a single load, got %d", loads.Load())
+	}
+}
+
+func TestCacheLoadSurvivesCancelledCaller(t *testing.T) {
+	store = useTestStores(t)[MemoryStore]
+	cache := New[testItem]("test-cancelled-caller", 0)
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := cache.Get(leaderCtx, "1", func(ctx context.Context) (testItem, error) {
+			close(loading)
+			<-release
+			return testItem{ID: "1"}, ctx.Err()
+		})
+		leaderErr <- err
+	}()
+	<-loading
+	waiter := make(chan testItem)
+	go func() {
+		item, err := cache.Get(context.Background(), "1", func(ctx context.Context) (testItem, error) {
+			t.Error("expected the waiter to share the load in progress")
+			return testItem{}, nil
+		})
+		if err != nil {
+			t.Errorf("unexpected error for the waiter: %v", err)
+		}
+		waiter <- item
+	}()
+	for cache.stats.misses.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancelLeader()
+	select {
+	case err := <-leaderErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the leader to return on its cancellation, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the leader to return on its cancellation")
+	}
+	close(release)
+	if item := <-waiter; item.ID != "1" {
+		t.Errorf("expected the waiter to get the loaded value, got %+v", item)
+	}
+	item, err := cache.Get(context.Background(), "1", func(ctx context.Context) (testItem, error) {
+		return testItem{}, errors.New("expected a hit")
+	})
+	if err != nil || item.ID != "1" {
+		t.Errorf("expected the loaded value to be cached, got %+v %v", item, err)
+	}
+}
+
+func TestCacheDoesNotStoreLoadsPrecedingDeletions(t *testing.T) {
+	store = useTestStores(t)[MemoryStore]
+	cache := New[testItem]("test-stale-loads", 0)
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan testItem)
+	go func() {
+		item, _ := cache.Get(context.Background(), "1", func(ctx context.Context) (testItem, error) {
+			close(loading)
+			<-release
+			return testItem{ID: "1", Title: "Stale"}, nil
+		})
+		done <- item
+	}()
+	<-loading
+	if err := cache.Delete(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if item := <-done; item.Title != "Stale" {
+		t.Errorf("expected the loaded item to be returned, got %+v", item)
+	}
+	if _, found, _ := store.get(context.Background(), cache.key("1")); found {
+		t.Error("expected the item loaded before the deletion not to be stored")
+	}
+	if len(cache.loads.keys) != 0 {
+		t.Errorf("expected no tracked loads, got %v", cache.loads.keys)
+	}
+}
+
+func TestCacheInvalidateOn(t *testing.T) {
+	store = useTestStores(t)[MemoryStore]
+	cache := New[testItem]("test-events", 0)
+	agent := bppubsub.NewPubSubAgent()
+	defer agent.Close()
+	cache.InvalidateOn(agent, bppubsub.TopicUserV1, func(event bppubsub.PubSubEvent) (string, bool) {
+		return event.EventEntity.(bppubsub.UserEventEntity).ID.String(), event.EventType == bppubsub.UserUpdatedEvent
+	})
+	id := uuid.New()
+	cache.Get(context.Background(), id.String(), func(ctx context.Context) (testItem, error) {
+		return testItem{ID: id.String()}, nil
+	})
+	agent.Publish(bppubsub.TopicUserV1, bppubsub.PubSubMessage{Message: bppubsub.PubSubEvent{
+		EventType:   bppubsub.UserUpdatedEvent,
+		EventEntity: bppubsub.UserEventEntity{ID: id},
+	}})
+	deadline := time.Now().Add(time.Second)
+	for cache.stats.invalidations.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, found, _ := store.get(context.Background(), cache.key(id.String())); found {
+		t.Error("expected the changed item to be removed")
+	}
+}
@@ -0,0 +1,40 @@
+package bpcache
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpredis"
+	"go.uber.org/zap"
+)
+
+var store cacheStore
+var defaultTTL time.Duration
+
+/*
+Init initializes the Cache on the selected store. Redis shares the values among all the application instances,
+while the in-memory store does not require any external service. By default it uses the same Redis of the rate limit, sharing its client.
+If Redis is not ready, the connection is retried with an exponential backoff until the retry timeout is reached.
+Values expire after the given number of seconds, unless a cache sets its own TTL.
+*/
+func Init(cStore string, cConnectionURI string, cConnectRetryTimeoutSeconds int, cMemoryMaxKeys int, cTTLSeconds int) {
+	zap.L().Info(fmt.Sprintf("Initializing Cache on %s store...", cStore), zap.String("service", "cache"))
+	if !slices.Contains(AvailableStores, interface{}(Store(cStore))) {
+		zap.L().Error(fmt.Sprintf("Invalid Cache store %s", cStore), zap.String("service", "cache"))
+		panic(fmt.Sprintf("Invalid Cache store %s", cStore))
+	}
+	switch Store(cStore) {
+	case RedisStore:
+		client, err := bpredis.Connect(cConnectionURI, cConnectRetryTimeoutSeconds)
+		if err != nil {
+			zap.L().Error("Error during Cache initalization", zap.String("service", "cache"), zap.Error(err))
+			panic(err)
+		}
+		store = redisCache{client: client}
+	case MemoryStore:
+		store = newMemoryCache(cMemoryMaxKeys)
+	}
+	defaultTTL = time.Duration(cTTLSeconds) * time.Second
+	zap.L().Info("Cache initialized!", zap.String("service", "cache"), zap.String("store", cStore))
+}
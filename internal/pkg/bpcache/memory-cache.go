@@ -0,0 +1,85 @@
+package bpcache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+MemoryCache represents a store keeping the values in the process memory, bounded by a max number of keys.
+When full, the least recently used key is evicted. It is meant for single-instance deployments,
+local development and tests, since values are not shared among application instances.
+*/
+type memoryCache struct {
+	mu      sync.Mutex
+	maxKeys int
+	entries map[string]*list.Element
+	// Keys from the most to the least recently used
+	recency *list.List
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func newMemoryCache(maxKeys int) *memoryCache {
+	return &memoryCache{
+		maxKeys: max(maxKeys, 1),
+		entries: map[string]*list.Element{},
+		recency: list.New(),
+		now:     time.Now,
+	}
+}
+
+func (c *memoryCache) get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, exists := c.entries[key]
+	if !exists {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(element)
+		return nil, false, nil
+	}
+	c.recency.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (c *memoryCache) set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &memoryEntry{key: key, value: value, expiresAt: c.now().Add(ttl)}
+	if element, exists := c.entries[key]; exists {
+		element.Value = entry
+		c.recency.MoveToFront(element)
+		return nil
+	}
+	for len(c.entries) >= c.maxKeys {
+		c.remove(c.recency.Back())
+	}
+	c.entries[key] = c.recency.PushFront(entry)
+	return nil
+}
+
+func (c *memoryCache) delete(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if element, exists := c.entries[key]; exists {
+			c.remove(element)
+		}
+	}
+	return nil
+}
+
+func (c *memoryCache) remove(element *list.Element) {
+	c.recency.Remove(element)
+	delete(c.entries, element.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,40 @@
+package bpcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheEviction(t *testing.T) {
+	ctx := context.Background()
+	c := newMemoryCache(2)
+	c.set(ctx, "a", []byte("1"), time.Minute)
+	c.set(ctx, "b", []byte("2"), time.Minute)
+	// Reading a makes b the least recently used key
+	c.get(ctx, "a")
+	c.set(ctx, "c", []byte("3"), time.Minute)
+	if _, found, _ := c.get(ctx, "b"); found {
+		t.Error("expected the least recently used key to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, found, _ := c.get(ctx, key); !found {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+}
+
+func TestMemoryCacheExpiration(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	c := newMemoryCache(10)
+	c.now = func() time.Time { return now }
+	c.set(ctx, "a", []byte("1"), time.Minute)
+	if value, found, _ := c.get(ctx, "a"); !found || string(value) != "1" {
+		t.Fatalf("expected the value, got %s %v", value, found)
+	}
+	now = now.Add(time.Minute)
+	if _, found, _ := c.get(ctx, "a"); found || len(c.entries) != 0 {
+		t.Error("expected the expired key to be removed")
+	}
+}
@@ -0,0 +1,36 @@
+package bpcache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+RedisCache represents a store keeping the values in Redis, shared among all the application instances.
+Keys are evicted by Redis on expiration or, when the memory is full, following its eviction policy.
+*/
+type redisCache struct {
+	client *redis.Client
+}
+
+func (c redisCache) get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c redisCache) set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (c redisCache) delete(ctx context.Context, keys ...string) error {
+	return c.client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,102 @@
+package bpcache
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpopenapi"
+	"github.com/besasch88/blueprint/internal/pkg/bprouter"
+	"github.com/gin-gonic/gin"
+)
+
+/*
+CacheStats counts the outcome of the reads of a cache since the application started.
+Misses sharing the same load are counted once in loads.
+*/
+type cacheStats struct {
+	hits          atomic.Int64
+	misses        atomic.Int64
+	loads         atomic.Int64
+	invalidations atomic.Int64
+	errors        atomic.Int64
+}
+
+/*
+Stats of the caches created so far, by name.
+*/
+var stats = map[string]*cacheStats{}
+var statsMu sync.Mutex
+
+func registerStats(name string) *cacheStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	if _, exists := stats[name]; !exists {
+		stats[name] = &cacheStats{}
+	}
+	return stats[name]
+}
+
+/*
+CacheStatsOutputDto represents the stats of a cache returned to the clients.
+The hit ratio is zero until the cache is read.
+*/
+type cacheStatsOutputDto struct {
+	Name          string  `json:"name"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	Loads         int64   `json:"loads"`
+	Invalidations int64   `json:"invalidations"`
+	Errors        int64   `json:"errors"`
+	HitRatio      float64 `json:"hitRatio"`
+}
+
+func newCacheStatsOutputDto(name string, s *cacheStats) cacheStatsOutputDto {
+	output := cacheStatsOutputDto{
+		Name:          name,
+		Hits:          s.hits.Load(),
+		Misses:        s.misses.Load(),
+		Loads:         s.loads.Load(),
+		Invalidations: s.invalidations.Load(),
+		Errors:        s.errors.Load(),
+	}
+	if reads := output.Hits + output.Misses; reads > 0 {
+		output.HitRatio = float64(output.Hits) / float64(reads)
+	}
+	return output
+}
+
+/*
+StatsRoute describes the route exposing the StatsHandler, protected by the given claims. E.g.
+
+	bpopenapi.Handle(router, http.MethodGet, "/cache/stats", bpcache.StatsRoute([]string{bpauth.CacheStatsGet}), bpcache.StatsHandler())
+*/
+func StatsRoute(claims []string) bpopenapi.Route {
+	return bpopenapi.Route{
+		OperationID: "getCacheStats",
+		Summary:     "Get the cache stats",
+		Description: "The hits, misses and loads of each cache since the application started.",
+		Tags:        []string{"cache"},
+		Claims:      claims,
+		Response:    []cacheStatsOutputDto{},
+	}
+}
+
+/*
+StatsHandler returns the stats of all the caches of the application instance, sorted by name.
+*/
+func StatsHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		statsMu.Lock()
+		items := []cacheStatsOutputDto{}
+		for name, s := range stats {
+			items = append(items, newCacheStatsOutputDto(name, s))
+		}
+		statsMu.Unlock()
+		slices.SortFunc(items, func(a, b cacheStatsOutputDto) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		bprouter.ReturnOk(ctx, &gin.H{"items": items})
+	}
+}
@@ -18,7 +18,8 @@ func CorsMiddleware(allowOrigins []string) gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowOrigins:     allowOrigins,
 		AllowMethods:     []string{"GET", "POST", "DELETE", "PUT", "PATCH", "OPTIONS"},
-		AllowHeaders:     append([]string{"content-type"}, cors.DefaultConfig().AllowHeaders...),
+		AllowHeaders:     append([]string{"content-type", "if-match"}, cors.DefaultConfig().AllowHeaders...),
+		ExposeHeaders:    []string{"etag", "retry-after", "ratelimit-limit", "ratelimit-remaining", "ratelimit-reset", "ratelimit-policy", "x-ratelimit-limit", "x-ratelimit-remaining", "x-ratelimit-reset"},
 		AllowCredentials: true,
 	})
 }
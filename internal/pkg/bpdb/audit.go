@@ -0,0 +1,81 @@
+package bpdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/*
+contextActorKey represents the key where the actor performing the changes is stored inside the context.
+*/
+type contextActorKey struct{}
+
+var contextActor = contextActorKey{}
+
+/*
+WithActor returns a copy of the context carrying the ID of who is performing the changes.
+Repositories use it to stamp the audit fields of the models they store.
+*/
+func WithActor(ctx context.Context, actorID uuid.UUID) context.Context {
+	return context.WithValue(ctx, contextActor, actorID)
+}
+
+/*
+ActorFromContext returns the ID of who is performing the changes, if available in the context.
+*/
+func ActorFromContext(ctx context.Context) (uuid.UUID, bool) {
+	actorID, ok := ctx.Value(contextActor).(uuid.UUID)
+	return actorID, ok
+}
+
+/*
+AuditModel contains the audit fields shared by all the tables. Embed it inside a model
+to let the generic Repository stamp creation, update and deletion information automatically.
+*/
+type AuditModel struct {
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamp;autoCreateTime:false"`
+	UpdatedAt time.Time  `gorm:"column:updated_at;type:timestamp;autoUpdateTime:false"`
+	DeletedAt *time.Time `gorm:"column:deleted_at;type:timestamp;autoDeleteTime:false"`
+	CreatedBy uuid.UUID  `gorm:"column:created_by;type:varchar(36)"`
+	UpdatedBy uuid.UUID  `gorm:"column:updated_by;type:varchar(36)"`
+	DeletedBy *uuid.UUID `gorm:"column:deleted_by;type:varchar(36)"`
+}
+
+/*
+Audit gives access to the audit fields of the model embedding them.
+*/
+func (m *AuditModel) Audit() *AuditModel {
+	return m
+}
+
+/*
+auditable represents a model embedding the AuditModel.
+*/
+type auditable interface {
+	Audit() *AuditModel
+}
+
+/*
+Stamp the audit fields of a model before storing it. Creation fields are set only for new records,
+i.e. when the creation time is not set yet. Without an actor in the context, only times are stamped.
+*/
+func stampAudit(ctx context.Context, model any, now time.Time) {
+	audited, ok := model.(auditable)
+	if !ok {
+		return
+	}
+	audit := audited.Audit()
+	actorID, hasActor := ActorFromContext(ctx)
+	if audit.CreatedAt.IsZero() {
+		audit.CreatedAt = now
+		if hasActor {
+			audit.CreatedBy = actorID
+		}
+	}
+	audit.UpdatedAt = now
+	if hasActor {
+		audit.UpdatedBy = actorID
+	}
+}
@@ -1,9 +1,11 @@
 package bpdb
 
 import (
+	"database/sql"
 	"fmt"
 	"time"
 
+	"github.com/besasch88/blueprint/internal/pkg/bputils"
 	"go.uber.org/zap"
 	"moul.io/zapgorm2"
 
@@ -12,21 +14,30 @@ import (
 	"gorm.io/gorm/logger"
 )
 
+/*
+ConnectionConfig represents the configuration of the connection pool and of the sessions
+opened on the database, together with the time the application waits for the database to be ready.
+*/
+type ConnectionConfig struct {
+	MaxOpenConns     int
+	MaxIdleConns     int
+	ConnMaxLifetime  time.Duration
+	ConnMaxIdleTime  time.Duration
+	StatementTimeout time.Duration
+	ApplicationName  string
+	RetryTimeout     time.Duration
+}
+
 /*
 NewDatabaseConnection creates a new connection to PostgreSQL database based on
 the authentication configuration provided as input.
+If the database is not ready, the connection is retried with an exponential backoff
+until the retry timeout is reached.
 */
-func NewDatabaseConnection(dbHost string, dbUsername string, dbPassword string, dbName string, dbPort int, dbSslMode string, DbLogSlowQueryThresholdSeconds int, appMode string) *gorm.DB {
+func NewDatabaseConnection(dbHost string, dbUsername string, dbPassword string, dbName string, dbPort int, dbSslMode string, DbLogSlowQueryThresholdSeconds int, appMode string, config ConnectionConfig) *gorm.DB {
 	zap.L().Info("Start connecting to DB...", zap.String("service", "db-connection"))
 	// Set the string connection for the database.
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
-		dbHost,
-		dbUsername,
-		dbPassword,
-		dbName,
-		dbPort,
-		dbSslMode,
-	)
+	dsn := buildDsn(dbHost, fmt.Sprintf("%d", dbPort), dbUsername, dbPassword, dbName, dbSslMode, config)
 	// Set logger for the database ORM.
 	// It is possible to track slow queries and all the queries performed.
 	dbLogger := zapgorm2.New(zap.L())
@@ -37,16 +48,36 @@ func NewDatabaseConnection(dbHost string, dbUsername string, dbPassword string,
 	} else {
 		dbLogger.LogLevel = logger.Info
 	}
-	// Connect to the database.
-	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		SkipDefaultTransaction: true,
-		Logger:                 dbLogger,
-	})
+	// Connect to the database, waiting for it to be ready.
+	var database *gorm.DB
+	err := bputils.RetryWithBackoff(func() error {
+		var errOpen error
+		database, errOpen = gorm.Open(postgres.Open(dsn), &gorm.Config{
+			SkipDefaultTransaction: true,
+			Logger:                 dbLogger,
+		})
+		if errOpen != nil {
+			zap.L().Warn("DB not ready yet. Retry...", zap.String("service", "db-connection"), zap.Error(errOpen))
+			// Close the pool opened by the failed attempt, so retries do not leak connections
+			if database != nil {
+				if sqlDB, errDB := database.DB(); errDB == nil {
+					sqlDB.Close()
+				}
+			}
+		}
+		return errOpen
+	}, 500*time.Millisecond, 10*time.Second, config.RetryTimeout)
 
 	if err != nil {
 		zap.L().Error("Connection to DB failed!", zap.String("service", "db-connection"), zap.Error(err))
 		panic(err)
 	}
+	sqlDB, err := database.DB()
+	if err != nil {
+		zap.L().Error("Connection to DB failed!", zap.String("service", "db-connection"), zap.Error(err))
+		panic(err)
+	}
+	configurePool(sqlDB, config)
 	zap.L().Info("Connection to DB done!", zap.String("service", "db-connection"))
 	return database
 }
@@ -57,6 +88,9 @@ logs the error and silences it.
 */
 func CloseDatabaseConnection(database *gorm.DB) {
 	zap.L().Info("Closing DB connection...", zap.String("service", "db-connection"))
+	if plugin, ok := database.Config.Plugins[replicasPluginName]; ok {
+		plugin.(*replicaSet).close()
+	}
 	sqlDB, _ := database.DB()
 	err := sqlDB.Close()
 	if err != nil {
@@ -64,3 +98,36 @@ func CloseDatabaseConnection(database *gorm.DB) {
 	}
 	zap.L().Info("DB connection closed!", zap.String("service", "db-connection"))
 }
+
+/*
+Build the string connection for the database, including the session parameters.
+*/
+func buildDsn(dbHost string, dbPort string, dbUsername string, dbPassword string, dbName string, dbSslMode string, config ConnectionConfig) string {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+		dbHost,
+		dbUsername,
+		dbPassword,
+		dbName,
+		dbPort,
+		dbSslMode,
+	)
+	if config.ApplicationName != "" {
+		dsn = fmt.Sprintf("%s application_name=%s", dsn, config.ApplicationName)
+	}
+	if config.StatementTimeout > 0 {
+		dsn = fmt.Sprintf("%s statement_timeout=%d", dsn, config.StatementTimeout.Milliseconds())
+	}
+	return dsn
+}
+
+/*
+Apply the pool configuration to the connection. Zero values keep the default of the driver.
+*/
+func configurePool(sqlDB *sql.DB, config ConnectionConfig) {
+	sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	if config.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+}
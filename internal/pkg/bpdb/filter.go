@@ -0,0 +1,47 @@
+package bpdb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpfilter"
+	"gorm.io/gorm"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+/*
+ApplyFilters translates the filters into parameterized conditions of the query, all in AND.
+Columns come from the whitelist of filterable fields of each module, while values are always
+passed as parameters, so filters cannot inject SQL. Text matches are case insensitive.
+*/
+func ApplyFilters(query *gorm.DB, filters []bpfilter.Filter) {
+	for _, filter := range filters {
+		switch filter.Operator {
+		case bpfilter.Eq:
+			query.Where(fmt.Sprintf("%s = ?", filter.Column), filter.Values[0])
+		case bpfilter.Neq:
+			query.Where(fmt.Sprintf("%s <> ?", filter.Column), filter.Values[0])
+		case bpfilter.Contains:
+			query.Where(fmt.Sprintf("%s ILIKE ?", filter.Column), "%"+likeEscaper.Replace(fmt.Sprint(filter.Values[0]))+"%")
+		case bpfilter.StartsWith:
+			query.Where(fmt.Sprintf("%s ILIKE ?", filter.Column), likeEscaper.Replace(fmt.Sprint(filter.Values[0]))+"%")
+		case bpfilter.Gt:
+			query.Where(fmt.Sprintf("%s > ?", filter.Column), filter.Values[0])
+		case bpfilter.Gte:
+			query.Where(fmt.Sprintf("%s >= ?", filter.Column), filter.Values[0])
+		case bpfilter.Lt:
+			query.Where(fmt.Sprintf("%s < ?", filter.Column), filter.Values[0])
+		case bpfilter.Lte:
+			query.Where(fmt.Sprintf("%s <= ?", filter.Column), filter.Values[0])
+		case bpfilter.In:
+			query.Where(fmt.Sprintf("%s IN ?", filter.Column), filter.Values)
+		case bpfilter.IsNull:
+			if isNull, _ := filter.Values[0].(bool); isNull {
+				query.Where(fmt.Sprintf("%s IS NULL", filter.Column))
+			} else {
+				query.Where(fmt.Sprintf("%s IS NOT NULL", filter.Column))
+			}
+		}
+	}
+}
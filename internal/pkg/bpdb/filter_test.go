@@ -0,0 +1,29 @@
+package bpdb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpfilter"
+)
+
+func TestApplyFilters(t *testing.T) {
+	storage := newDryRunConnection(t)
+	query := storage.Model(fuzzySearchModel{})
+	ApplyFilters(query, []bpfilter.Filter{
+		{Column: "email", Operator: bpfilter.Contains, Values: []interface{}{"50%_off"}},
+		{Column: "email", Operator: bpfilter.StartsWith, Values: []interface{}{"alice"}},
+		{Column: "id", Operator: bpfilter.In, Values: []interface{}{"1", "2"}},
+		{Column: "id", Operator: bpfilter.Neq, Values: []interface{}{"3"}},
+		{Column: "deleted_at", Operator: bpfilter.IsNull, Values: []interface{}{false}},
+	})
+	statement := query.Find(&[]fuzzySearchModel{}).Statement
+	sql := statement.SQL.String()
+	expected := "WHERE email ILIKE $1 AND email ILIKE $2 AND id IN ($3,$4) AND id <> $5 AND deleted_at IS NOT NULL"
+	if !strings.Contains(sql, expected) {
+		t.Errorf("expected query to contain %q, got %s", expected, sql)
+	}
+	if statement.Vars[0] != `%50\%\_off%` || statement.Vars[1] != "alice%" {
+		t.Errorf("expected escaped patterns, got %v", statement.Vars)
+	}
+}
@@ -0,0 +1,194 @@
+package bpdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpfilter"
+	"gorm.io/gorm"
+)
+
+/*
+ErrInvalidKeyset is returned when the keyset refers to a different order than the requested one.
+*/
+var ErrInvalidKeyset = errors.New("invalid-keyset")
+
+/*
+Keyset represents the position a keyset page starts from: the values of the order field and of the ID
+of the last record of the previous page or, going backward, of the first record of the next page.
+The relevance order of a fuzzy search is based on computed ranks, so its pages are identified by an offset.
+Fields are kept short since keysets are meant to be encoded in cursors returned to clients.
+*/
+type Keyset struct {
+	OrderBy  string      `json:"o"`
+	OrderDir OrderDir    `json:"d"`
+	Value    interface{} `json:"v,omitempty"`
+	ID       interface{} `json:"i,omitempty"`
+	Offset   int         `json:"n,omitempty"`
+	Backward bool        `json:"b,omitempty"`
+}
+
+/*
+KeysetPage contains the keysets of the pages before and after the returned one, nil if there is none.
+*/
+type KeysetPage struct {
+	Next *Keyset
+	Prev *Keyset
+}
+
+/*
+ListKeyset returns a page of entities starting from the given keyset, or the first page if nil.
+Records are ordered by the order field and by ID as tiebreaker, so pages never contain duplicates
+even if records are changed between requests, and the performance does not degrade on large tables.
+The order field must not be nullable. Counting the records matching the filters is expensive
+on large tables, so it is performed only if requested, otherwise the returned total count is 0.
+*/
+func (r Repository[M, E]) ListKeyset(ctx context.Context, limit int, orderBy string, orderDir OrderDir, keyset *Keyset, searchKey *string, filters []bpfilter.Filter, includeDeleted bool, withCount bool) ([]E, KeysetPage, int64, error) {
+	if keyset != nil && (keyset.OrderBy != orderBy || keyset.OrderDir != orderDir) {
+		return []E{}, KeysetPage{}, 0, ErrInvalidKeyset
+	}
+	var models []*M
+	var page KeysetPage
+	tx := FromContext(ctx, r.storage)
+	query := tx.Model(new(M))
+	r.applyFilters(query, searchKey, filters, includeDeleted)
+	if orderBy == RelevanceField && searchKey != nil {
+		offset := 0
+		if keyset != nil {
+			offset = keyset.Offset
+		}
+		result := query.Limit(limit + 1).Offset(offset).Order(GenerateFuzzySearchOrderQuery(r.searchFields, orderDir)).Find(&models)
+		if result.Error != nil {
+			return []E{}, KeysetPage{}, 0, result.Error
+		}
+		if len(models) > limit {
+			models = models[:limit]
+			page.Next = &Keyset{OrderBy: orderBy, OrderDir: orderDir, Offset: offset + limit}
+		}
+		if offset > 0 {
+			page.Prev = &Keyset{OrderBy: orderBy, OrderDir: orderDir, Offset: max(offset-limit, 0)}
+		}
+	} else {
+		column := orderBy
+		if orderBy == RelevanceField {
+			column = "id"
+		}
+		backward := keyset != nil && keyset.Backward
+		dir := orderDir
+		if backward {
+			dir = reverseOrderDir(orderDir)
+		}
+		applyKeyset(query, column, dir, keyset)
+		result := query.Limit(limit + 1).Find(&models)
+		if result.Error != nil {
+			return []E{}, KeysetPage{}, 0, result.Error
+		}
+		more := len(models) > limit
+		if more {
+			models = models[:limit]
+		}
+		if backward {
+			slices.Reverse(models)
+		}
+		if len(models) > 0 {
+			first, last := models[0], models[len(models)-1]
+			// Going backward, the page the keyset was taken from is always after this one
+			if more || backward {
+				next, err := r.keysetOf(last, orderBy, orderDir, column, false)
+				if err != nil {
+					return []E{}, KeysetPage{}, 0, err
+				}
+				page.Next = &next
+			}
+			if (backward && more) || (!backward && keyset != nil) {
+				prev, err := r.keysetOf(first, orderBy, orderDir, column, true)
+				if err != nil {
+					return []E{}, KeysetPage{}, 0, err
+				}
+				page.Prev = &prev
+			}
+		}
+	}
+	var totalCount int64
+	if withCount {
+		queryCount := tx.Model(new(M))
+		r.applyFilters(queryCount, searchKey, filters, includeDeleted)
+		if err := queryCount.Count(&totalCount).Error; err != nil {
+			return []E{}, KeysetPage{}, 0, err
+		}
+	}
+	var entities []E = []E{}
+	for _, model := range models {
+		entities = append(entities, r.toEntity(*model))
+	}
+	return entities, page, totalCount, nil
+}
+
+/*
+Filter the records by the fuzzy search and the filters, if any, and by deletion.
+*/
+func (r Repository[M, E]) applyFilters(query *gorm.DB, searchKey *string, filters []bpfilter.Filter, includeDeleted bool) {
+	if searchKey != nil {
+		GenerateFuzzySearch(query, *searchKey, r.searchFields, r.relevanceThreshold)
+	}
+	ApplyFilters(query, filters)
+	if !includeDeleted {
+		query.Where("deleted_at IS NULL")
+	}
+}
+
+/*
+Filter the records after the keyset, following the given direction, and order them by the column and ID.
+*/
+func applyKeyset(query *gorm.DB, column string, dir OrderDir, keyset *Keyset) {
+	operator := ">"
+	if dir == Desc {
+		operator = "<"
+	}
+	if column == "id" {
+		if keyset != nil {
+			query.Where(fmt.Sprintf("id %s ?", operator), keyset.ID)
+		}
+		query.Order(fmt.Sprintf("id %s", dir))
+		return
+	}
+	if keyset != nil {
+		query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, operator), keyset.Value, keyset.ID)
+	}
+	query.Order(fmt.Sprintf("%s %s, id %s", column, dir, dir))
+}
+
+/*
+Build the keyset pointing to the given model, reading the values of the order column and of the ID.
+*/
+func (r Repository[M, E]) keysetOf(model *M, orderBy string, orderDir OrderDir, column string, backward bool) (Keyset, error) {
+	statement := &gorm.Statement{DB: r.storage}
+	if err := statement.Parse(model); err != nil {
+		return Keyset{}, err
+	}
+	keyset := Keyset{OrderBy: orderBy, OrderDir: orderDir, Backward: backward}
+	value := reflect.ValueOf(model).Elem()
+	idField := statement.Schema.LookUpField("id")
+	if idField == nil {
+		return Keyset{}, fmt.Errorf("model %s has no id field", statement.Schema.Name)
+	}
+	keyset.ID, _ = idField.ValueOf(context.Background(), value)
+	if column != "id" {
+		orderField := statement.Schema.LookUpField(column)
+		if orderField == nil {
+			return Keyset{}, fmt.Errorf("model %s has no %s field", statement.Schema.Name, column)
+		}
+		keyset.Value, _ = orderField.ValueOf(context.Background(), value)
+	}
+	return keyset, nil
+}
+
+func reverseOrderDir(orderDir OrderDir) OrderDir {
+	if orderDir == Desc {
+		return Asc
+	}
+	return Desc
+}
@@ -0,0 +1,41 @@
+package bpdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestApplyKeyset(t *testing.T) {
+	storage := newDryRunConnection(t)
+	tests := []struct {
+		column   string
+		dir      OrderDir
+		keyset   *Keyset
+		expected []string
+	}{
+		{column: "email", dir: Asc, expected: []string{"ORDER BY email asc, id asc"}},
+		{column: "email", dir: Asc, keyset: &Keyset{Value: "a@example.com", ID: "1"}, expected: []string{"WHERE (email, id) > ($1, $2)", "ORDER BY email asc, id asc"}},
+		{column: "email", dir: Desc, keyset: &Keyset{Value: "a@example.com", ID: "1"}, expected: []string{"WHERE (email, id) < ($1, $2)", "ORDER BY email desc, id desc"}},
+		{column: "id", dir: Desc, keyset: &Keyset{ID: "1"}, expected: []string{"WHERE id < $1", "ORDER BY id desc"}},
+	}
+	for _, test := range tests {
+		query := storage.Model(fuzzySearchModel{})
+		applyKeyset(query, test.column, test.dir, test.keyset)
+		sql := query.Find(&[]fuzzySearchModel{}).Statement.SQL.String()
+		for _, fragment := range test.expected {
+			if !strings.Contains(sql, fragment) {
+				t.Errorf("expected query to contain %q, got %s", fragment, sql)
+			}
+		}
+	}
+}
+
+func TestListKeysetInvalid(t *testing.T) {
+	identity := func(m fuzzySearchModel) fuzzySearchModel { return m }
+	repository := NewRepository(newDryRunConnection(t), identity, identity, []string{"email"}, 0.05)
+	keyset := Keyset{OrderBy: "email", OrderDir: Desc, Value: "a@example.com", ID: "1"}
+	if _, _, _, err := repository.ListKeyset(context.Background(), 10, "email", Asc, &keyset, nil, nil, false, false); err != ErrInvalidKeyset {
+		t.Errorf("expected %v, got %v", ErrInvalidKeyset, err)
+	}
+}
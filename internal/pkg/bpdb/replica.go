@@ -0,0 +1,180 @@
+package bpdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const replicasPluginName = "bpdb:replicas"
+
+/*
+AddReadReplicas routes the read queries performed outside of transactions to the given replicas,
+while writes, transactions and queries with locking clauses (e.g. FOR UPDATE) go to the primary.
+Each replica is identified by `host:port` and shares the credentials and the pool configuration of the primary.
+Replicas are not pinged while connecting, so the application starts even if they are unreachable:
+they are considered unhealthy until the first successful check and then periodically checked,
+being excluded from routing until they recover. If no replica is healthy, reads fall back to the primary.
+*/
+func AddReadReplicas(database *gorm.DB, replicaHosts []string, dbUsername string, dbPassword string, dbName string, dbSslMode string, healthCheckIntervalSeconds int, config ConnectionConfig) {
+	if len(replicaHosts) == 0 {
+		return
+	}
+	zap.L().Info(fmt.Sprintf("Start connecting to %d DB replicas...", len(replicaHosts)), zap.String("service", "db-connection"))
+	primary, err := database.DB()
+	if err != nil {
+		zap.L().Error("Primary DB connection not available!", zap.String("service", "db-connection"), zap.Error(err))
+		panic(err)
+	}
+	replicas := &replicaSet{
+		primary: primary,
+		health:  make(map[gorm.ConnPool]*atomic.Bool),
+		quit:    make(chan struct{}),
+	}
+	var dialectors []gorm.Dialector
+	for _, replicaHost := range replicaHosts {
+		host, port, err := net.SplitHostPort(replicaHost)
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("Invalid DB replica host %s", replicaHost), zap.String("service", "db-connection"), zap.Error(err))
+			panic(err)
+		}
+		dsn := buildDsn(host, port, dbUsername, dbPassword, dbName, dbSslMode, config)
+		// The connection pool is opened here to keep a reference used by health checks.
+		replica, err := sql.Open("pgx", dsn)
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("Connection to DB replica %s failed!", replicaHost), zap.String("service", "db-connection"), zap.Error(err))
+			panic(err)
+		}
+		configurePool(replica, config)
+		replicas.hosts = append(replicas.hosts, replicaHost)
+		replicas.pools = append(replicas.pools, replica)
+		replicas.health[replica] = &atomic.Bool{}
+		dialectors = append(dialectors, postgres.New(postgres.Config{Conn: replica}))
+	}
+	// The primary is registered as a replica too, so the policy is used even with a single replica
+	// (dbresolver skips it otherwise) and can fall back on the primary.
+	dialectors = append(dialectors, postgres.New(postgres.Config{Conn: primary}))
+
+	// dbresolver opens the replicas with the configuration of the primary, pinging them by default.
+	disableAutomaticPing := database.Config.DisableAutomaticPing
+	database.Config.DisableAutomaticPing = true
+	err = database.Use(dbresolver.Register(dbresolver.Config{
+		Replicas:          dialectors,
+		Policy:            replicas,
+		TraceResolverMode: true,
+	}))
+	database.Config.DisableAutomaticPing = disableAutomaticPing
+	if err == nil {
+		err = database.Use(replicas)
+	}
+	if err != nil {
+		zap.L().Error("DB replicas registration failed!", zap.String("service", "db-connection"), zap.Error(err))
+		panic(err)
+	}
+	go replicas.monitor(time.Duration(healthCheckIntervalSeconds) * time.Second)
+	zap.L().Info("Connection to DB replicas done!", zap.String("service", "db-connection"))
+}
+
+/*
+UsePrimary forces the next queries on the given connection to be performed on the primary.
+It is useful to read your own writes right after they happen, without waiting for replication. E.g.
+
+	item, err := s.repository.getUserByID(bpdb.UsePrimary(s.storage), userID, false)
+*/
+func UsePrimary(database *gorm.DB) *gorm.DB {
+	return database.Clauses(dbresolver.Write)
+}
+
+/*
+replicaSet keeps track of the replica connections and their health.
+It is registered as a GORM plugin so the replicas can be closed together with the primary,
+and acts as the policy used to pick a replica for each read query.
+*/
+type replicaSet struct {
+	primary   gorm.ConnPool
+	hosts     []string
+	pools     []*sql.DB
+	health    map[gorm.ConnPool]*atomic.Bool
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+func (r *replicaSet) Name() string {
+	return replicasPluginName
+}
+
+func (r *replicaSet) Initialize(_ *gorm.DB) error {
+	return nil
+}
+
+/*
+Resolve picks a random healthy connection among the given ones, using the primary only as a fallback.
+Connections that are not replicas are always considered healthy.
+*/
+func (r *replicaSet) Resolve(connPools []gorm.ConnPool) gorm.ConnPool {
+	var healthy []gorm.ConnPool
+	for _, connPool := range connPools {
+		if connPool == r.primary {
+			continue
+		}
+		if isHealthy, isReplica := r.health[connPool]; !isReplica || isHealthy.Load() {
+			healthy = append(healthy, connPool)
+		}
+	}
+	if len(healthy) == 0 {
+		return r.primary
+	}
+	return healthy[rand.Intn(len(healthy))]
+}
+
+/*
+Ping all the replicas and update their health, logging any change.
+*/
+func (r *replicaSet) checkHealth() {
+	for i, pool := range r.pools {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := pool.PingContext(ctx)
+		cancel()
+		wasHealthy := r.health[pool].Swap(err == nil)
+		if err != nil && wasHealthy {
+			zap.L().Warn(fmt.Sprintf("DB replica %s is unhealthy", r.hosts[i]), zap.String("service", "db-connection"), zap.Error(err))
+		} else if err == nil && !wasHealthy {
+			zap.L().Info(fmt.Sprintf("DB replica %s is healthy", r.hosts[i]), zap.String("service", "db-connection"))
+		}
+	}
+}
+
+func (r *replicaSet) monitor(interval time.Duration) {
+	r.checkHealth()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.checkHealth()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+func (r *replicaSet) close() {
+	r.closeOnce.Do(func() {
+		close(r.quit)
+		for i, pool := range r.pools {
+			if err := pool.Close(); err != nil {
+				zap.L().Error(fmt.Sprintf("Closing DB replica %s connection failed!", r.hosts[i]), zap.String("service", "db-connection"), zap.Error(err))
+			}
+		}
+	})
+}
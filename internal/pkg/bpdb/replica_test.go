@@ -0,0 +1,73 @@
+package bpdb
+
+import (
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newTestPool(t *testing.T) *sql.DB {
+	t.Helper()
+	pool, err := sql.Open("pgx", "host=127.0.0.1")
+	if err != nil {
+		t.Fatalf("unable to open pool: %v", err)
+	}
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func TestReplicaSetResolve(t *testing.T) {
+	primary := newTestPool(t)
+	healthyReplica := newTestPool(t)
+	unhealthyReplica := newTestPool(t)
+	replicas := &replicaSet{
+		primary: primary,
+		health: map[gorm.ConnPool]*atomic.Bool{
+			healthyReplica:   {},
+			unhealthyReplica: {},
+		},
+	}
+	replicas.health[healthyReplica].Store(true)
+
+	for i := 0; i < 10; i++ {
+		if pool := replicas.Resolve([]gorm.ConnPool{healthyReplica, unhealthyReplica}); pool != healthyReplica {
+			t.Fatalf("expected the healthy replica to be picked")
+		}
+	}
+	if pool := replicas.Resolve([]gorm.ConnPool{unhealthyReplica}); pool != primary {
+		t.Errorf("expected fallback on primary when no replica is healthy")
+	}
+	if pool := replicas.Resolve([]gorm.ConnPool{primary}); pool != primary {
+		t.Errorf("expected primary to be always available for writes")
+	}
+}
+
+func TestAddReadReplicasSingleUnhealthyReplica(t *testing.T) {
+	primary := newTestPool(t)
+	database, err := gorm.Open(postgres.New(postgres.Config{Conn: primary}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	database.Config.DisableAutomaticPing = false
+	// Nothing listens on the replica, which must neither fail the registration nor receive reads.
+	AddReadReplicas(database, []string{"127.0.0.1:1"}, "user", "password", "blueprint", "disable", 60, ConnectionConfig{})
+	t.Cleanup(func() { database.Config.Plugins[replicasPluginName].(*replicaSet).close() })
+
+	var resolved gorm.ConnPool
+	errResolved := errors.New("resolved")
+	database.Callback().Query().After("gorm:db_resolver").Before("gorm:query").Register("test:resolved", func(db *gorm.DB) {
+		resolved = db.Statement.ConnPool
+		db.AddError(errResolved)
+	})
+	var count int64
+	if err := database.Table("users").Count(&count).Error; !errors.Is(err, errResolved) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved != primary {
+		t.Errorf("expected reads on the primary when the only replica is unhealthy")
+	}
+}
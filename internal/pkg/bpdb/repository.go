@@ -0,0 +1,192 @@
+package bpdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpfilter"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/*
+Repository implements the common operations on a table mapped by the model M, converting
+records into the entity E used by the business logic. Tables are expected to have an `id` primary key
+and the soft-delete `deleted_at` column. If M embeds the AuditModel, audit fields are stamped
+automatically by leveraging the actor found in the context (see WithActor).
+
+Each module wraps the Repository in its own repository, exposing only the operations it needs. E.g.
+
+	type userRepository struct {
+		repository bpdb.Repository[userModel, userEntity]
+	}
+*/
+type Repository[M any, E any] struct {
+	storage            *gorm.DB
+	toEntity           func(model M) E
+	toModel            func(entity E) M
+	searchFields       []string
+	relevanceThreshold float64
+}
+
+/*
+NewRepository creates a new generic repository. The mapping functions convert models into entities
+and vice versa, while search fields are the ones used by the fuzzy search, ordered by their weight.
+*/
+func NewRepository[M any, E any](storage *gorm.DB, toEntity func(model M) E, toModel func(entity E) M, searchFields []string, relevanceThreshold float64) Repository[M, E] {
+	return Repository[M, E]{
+		storage:            storage,
+		toEntity:           toEntity,
+		toModel:            toModel,
+		searchFields:       searchFields,
+		relevanceThreshold: relevanceThreshold,
+	}
+}
+
+/*
+List returns a page of entities and the total number of records matching the search key and the filters.
+The RelevanceField order is available only in combination with a search key.
+*/
+func (r Repository[M, E]) List(ctx context.Context, limit int, offset int, orderBy string, orderDir OrderDir, searchKey *string, filters []bpfilter.Filter, includeDeleted bool, forUpdate bool) ([]E, int64, error) {
+	var totalCount int64
+	var order string
+	var models []*M
+	tx := FromContext(ctx, r.storage)
+	query := tx.Model(new(M))
+	queryCount := tx.Model(new(M))
+
+	// Add fuzzy search query based on the provided search key and table fields
+	r.applyFilters(query, searchKey, filters, includeDeleted)
+	r.applyFilters(queryCount, searchKey, filters, includeDeleted)
+	// Based on the order field, we apply it on different tables
+	if orderBy == RelevanceField && searchKey != nil {
+		order = GenerateFuzzySearchOrderQuery(r.searchFields, orderDir)
+	} else if orderBy == RelevanceField {
+		order = fmt.Sprintf("id %s", orderDir)
+	} else {
+		order = fmt.Sprintf("%s %s", orderBy, orderDir)
+	}
+
+	if forUpdate {
+		query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	result := query.Limit(limit).Offset(offset).Order(order).Find(&models)
+	if result.Error != nil {
+		return []E{}, 0, result.Error
+	}
+	if err := queryCount.Count(&totalCount).Error; err != nil {
+		return []E{}, 0, err
+	}
+	var entities []E = []E{}
+	for _, model := range models {
+		entities = append(entities, r.toEntity(*model))
+	}
+	return entities, totalCount, nil
+}
+
+/*
+GetByID returns the entity with the given ID, or an empty entity if not found.
+*/
+func (r Repository[M, E]) GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool, forUpdate bool) (E, error) {
+	var model *M
+	query := FromContext(ctx, r.storage).Where("id = ?", id)
+	if !includeDeleted {
+		query.Where("deleted_at IS NULL")
+	}
+	if forUpdate {
+		query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	result := query.Limit(1).Find(&model)
+	if result.Error != nil {
+		return *new(E), result.Error
+	}
+	if result.RowsAffected == 0 {
+		return *new(E), nil
+	}
+	return r.toEntity(*model), nil
+}
+
+/*
+GetByIDs returns the entities with the given IDs in a single query, e.g. to resolve the resources
+related to a page of entities. IDs not found are skipped, so the result can be shorter than the IDs.
+*/
+func (r Repository[M, E]) GetByIDs(ctx context.Context, ids []uuid.UUID, includeDeleted bool) ([]E, error) {
+	var models []*M
+	query := FromContext(ctx, r.storage).Where("id IN ?", ids)
+	if !includeDeleted {
+		query.Where("deleted_at IS NULL")
+	}
+	if err := query.Find(&models).Error; err != nil {
+		return []E{}, err
+	}
+	var entities []E = []E{}
+	for _, model := range models {
+		entities = append(entities, r.toEntity(*model))
+	}
+	return entities, nil
+}
+
+/*
+Save inserts or updates the entity based on its ID, stamping the audit fields.
+If M embeds the VersionModel, an entity without version is created, otherwise it is updated
+only if its version is still the stored one, returning ErrVersionConflict if not.
+It returns the entity as stored.
+*/
+func (r Repository[M, E]) Save(ctx context.Context, entity E) (E, error) {
+	model := r.toModel(entity)
+	stampAudit(ctx, &model, time.Now())
+	tx := FromContext(ctx, r.storage)
+	if versionedModel, ok := any(&model).(versioned); ok {
+		version := versionedModel.Versioning()
+		currentVersion := version.Version
+		version.Version++
+		if currentVersion == 0 {
+			if err := tx.Create(&model).Error; err != nil {
+				return *new(E), err
+			}
+			return r.toEntity(model), nil
+		}
+		result := tx.Model(&model).Select("*").Where("version = ?", currentVersion).Updates(&model)
+		if result.Error != nil {
+			return *new(E), result.Error
+		}
+		if result.RowsAffected == 0 {
+			return *new(E), ErrVersionConflict
+		}
+		return r.toEntity(model), nil
+	}
+	if err := tx.Save(&model).Error; err != nil {
+		return *new(E), err
+	}
+	return r.toEntity(model), nil
+}
+
+/*
+SoftDelete marks the record with the given ID as deleted, storing who deleted it.
+It returns false if the record does not exist or is already deleted.
+*/
+func (r Repository[M, E]) SoftDelete(ctx context.Context, id uuid.UUID) (bool, error) {
+	now := time.Now()
+	values := map[string]interface{}{"deleted_at": now, "updated_at": now}
+	if actorID, ok := ActorFromContext(ctx); ok {
+		values["deleted_by"] = actorID
+		values["updated_by"] = actorID
+	}
+	result := FromContext(ctx, r.storage).Model(new(M)).Where("id = ? AND deleted_at IS NULL", id).Updates(values)
+	return result.RowsAffected > 0, result.Error
+}
+
+/*
+Restore brings back a soft-deleted record with the given ID.
+It returns false if the record does not exist or is not deleted.
+*/
+func (r Repository[M, E]) Restore(ctx context.Context, id uuid.UUID) (bool, error) {
+	values := map[string]interface{}{"deleted_at": nil, "deleted_by": nil, "updated_at": time.Now()}
+	if actorID, ok := ActorFromContext(ctx); ok {
+		values["updated_by"] = actorID
+	}
+	result := FromContext(ctx, r.storage).Model(new(M)).Where("id = ? AND deleted_at IS NOT NULL", id).Updates(values)
+	return result.RowsAffected > 0, result.Error
+}
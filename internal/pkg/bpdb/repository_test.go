@@ -0,0 +1,75 @@
+package bpdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bptest"
+	"github.com/google/uuid"
+)
+
+type repositoryTestModel struct {
+	ID   uuid.UUID `gorm:"primaryKey;column:id;type:varchar(36)"`
+	Name string    `gorm:"column:name;type:varchar(255)"`
+	AuditModel
+}
+
+func (m repositoryTestModel) TableName() string {
+	return "bp_repository_test"
+}
+
+func TestStampAudit(t *testing.T) {
+	creatorID := uuid.New()
+	updaterID := uuid.New()
+	now := time.Now()
+	model := repositoryTestModel{}
+
+	stampAudit(WithActor(context.Background(), creatorID), &model, now)
+	if model.CreatedAt != now || model.CreatedBy != creatorID || model.UpdatedBy != creatorID {
+		t.Errorf("expected creation fields to be stamped, got %+v", model.AuditModel)
+	}
+	later := now.Add(time.Minute)
+	stampAudit(WithActor(context.Background(), updaterID), &model, later)
+	if model.CreatedAt != now || model.CreatedBy != creatorID || model.UpdatedAt != later || model.UpdatedBy != updaterID {
+		t.Errorf("expected only update fields to be stamped, got %+v", model.AuditModel)
+	}
+}
+
+func TestRepositorySoftDeleteAndRestore(t *testing.T) {
+	tx := bptest.RequireDatabase(t, testDatabase)
+	err := tx.Exec(`CREATE TEMPORARY TABLE bp_repository_test (
+		id varchar(36) PRIMARY KEY, name varchar(255),
+		created_at timestamp, updated_at timestamp, deleted_at timestamp,
+		created_by varchar(36), updated_by varchar(36), deleted_by varchar(36))`).Error
+	if err != nil {
+		t.Fatalf("unable to create table: %v", err)
+	}
+	identity := func(m repositoryTestModel) repositoryTestModel { return m }
+	repository := NewRepository(tx, identity, identity, []string{"name"}, 0.05)
+	actorID := uuid.New()
+	ctx := WithActor(context.Background(), actorID)
+
+	saved, err := repository.Save(ctx, repositoryTestModel{ID: uuid.New(), Name: "test"})
+	if err != nil || saved.CreatedBy != actorID {
+		t.Fatalf("unexpected save result %+v, %v", saved, err)
+	}
+	if deleted, err := repository.SoftDelete(ctx, saved.ID); err != nil || !deleted {
+		t.Fatalf("expected record to be deleted, got %v", err)
+	}
+	item, _ := repository.GetByID(ctx, saved.ID, false, false)
+	if item.ID != uuid.Nil {
+		t.Errorf("expected deleted record to be hidden")
+	}
+	item, _ = repository.GetByID(ctx, saved.ID, true, false)
+	if item.DeletedBy == nil || *item.DeletedBy != actorID {
+		t.Errorf("expected deleted_by to be stamped, got %+v", item.AuditModel)
+	}
+	if restored, err := repository.Restore(ctx, saved.ID); err != nil || !restored {
+		t.Fatalf("expected record to be restored, got %v", err)
+	}
+	items, count, err := repository.List(ctx, 10, 0, "name", Asc, nil, nil, false, false)
+	if err != nil || count != 1 || len(items) != 1 {
+		t.Errorf("expected restored record to be listed, got %d items and %v", count, err)
+	}
+}
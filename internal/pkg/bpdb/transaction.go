@@ -0,0 +1,146 @@
+package bpdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+/*
+List of Postgres error codes for which a transaction can be safely retried from the beginning.
+*/
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+/*
+contextUnitOfWork represents the key where the active unit of work is stored inside the context.
+*/
+type contextUnitOfWorkKey struct{}
+
+var contextUnitOfWork = contextUnitOfWorkKey{}
+
+/*
+unitOfWork represents an active transaction and the hooks to be executed once it is committed.
+Nested units of work share the hooks of the outermost one, that is the only one actually committing.
+*/
+type unitOfWork struct {
+	tx    *gorm.DB
+	mu    *sync.Mutex
+	hooks *[]func()
+}
+
+/*
+TxManager runs business logic inside database transactions, storing the active transaction
+in the context so repositories can retrieve it via FromContext without receiving it as a parameter.
+*/
+type TxManager struct {
+	storage    *gorm.DB
+	maxRetries int
+}
+
+/*
+NewTxManager creates a new transaction manager on the given connection. Transactions failing due to
+serialization failures or deadlocks are retried up to maxRetries times.
+*/
+func NewTxManager(storage *gorm.DB, maxRetries int) TxManager {
+	return TxManager{
+		storage:    storage,
+		maxRetries: maxRetries,
+	}
+}
+
+/*
+Run executes the function inside a transaction, committed if the function returns no error
+and rolled back otherwise. If the context already contains a transaction, a savepoint is created,
+so only the changes of the nested function are rolled back in case of error.
+After-commit hooks are executed only once the outermost transaction is committed.
+*/
+func (m TxManager) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if current, ok := ctx.Value(contextUnitOfWork).(unitOfWork); ok {
+		return m.runNested(ctx, current, fn)
+	}
+	var err error
+	for attempt := 0; attempt <= m.maxRetries; attempt++ {
+		var hooks []func()
+		err = m.storage.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, contextUnitOfWork, unitOfWork{tx: tx, mu: &sync.Mutex{}, hooks: &hooks}))
+		})
+		if err == nil {
+			for _, hook := range hooks {
+				hook()
+			}
+			return nil
+		}
+		if !isRetryable(err) {
+			return err
+		}
+		zap.L().Warn(fmt.Sprintf("Transaction conflict, attempt %d of %d. Retry...", attempt+1, m.maxRetries+1), zap.String("service", "db-transaction"), zap.Error(err))
+		time.Sleep(time.Duration(attempt+1)*10*time.Millisecond + time.Duration(rand.Intn(10))*time.Millisecond)
+	}
+	return err
+}
+
+/*
+Execute the function in a savepoint of the current transaction. Hooks registered by the function
+are discarded if the savepoint is rolled back.
+*/
+func (m TxManager) runNested(ctx context.Context, current unitOfWork, fn func(ctx context.Context) error) error {
+	var hooks []func()
+	err := current.tx.Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, contextUnitOfWork, unitOfWork{tx: tx, mu: &sync.Mutex{}, hooks: &hooks}))
+	})
+	if err != nil {
+		return err
+	}
+	current.mu.Lock()
+	defer current.mu.Unlock()
+	*current.hooks = append(*current.hooks, hooks...)
+	return nil
+}
+
+/*
+FromContext returns the connection of the transaction active in the context,
+or the given storage if no transaction is active.
+*/
+func FromContext(ctx context.Context, storage *gorm.DB) *gorm.DB {
+	if current, ok := ctx.Value(contextUnitOfWork).(unitOfWork); ok {
+		return current.tx
+	}
+	return storage.WithContext(ctx)
+}
+
+/*
+AfterCommit registers a function to be executed once the transaction active in the context is committed,
+e.g. to publish events only when the changes are persisted. If no transaction is active,
+the function is executed immediately.
+*/
+func AfterCommit(ctx context.Context, hook func()) {
+	current, ok := ctx.Value(contextUnitOfWork).(unitOfWork)
+	if !ok {
+		hook()
+		return
+	}
+	current.mu.Lock()
+	defer current.mu.Unlock()
+	*current.hooks = append(*current.hooks, hook)
+}
+
+/*
+Check if the error is due to a serialization failure or a deadlock.
+*/
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package bpdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/besasch88/blueprint/internal/pkg/bptest"
+)
+
+func TestAfterCommitWithoutTransaction(t *testing.T) {
+	executed := false
+	AfterCommit(context.Background(), func() { executed = true })
+	if !executed {
+		t.Error("expected hook to be executed immediately")
+	}
+}
+
+func TestTxManagerRun(t *testing.T) {
+	tx := bptest.RequireDatabase(t, testDatabase)
+	if err := tx.Exec("CREATE TEMPORARY TABLE bp_tx_test (id int)").Error; err != nil {
+		t.Fatalf("unable to create table: %v", err)
+	}
+	manager := NewTxManager(tx, 0)
+	var executed []string
+	errRollback := errors.New("rollback")
+
+	err := manager.Run(context.Background(), func(ctx context.Context) error {
+		FromContext(ctx, tx).Exec("INSERT INTO bp_tx_test VALUES (1)")
+		AfterCommit(ctx, func() { executed = append(executed, "outer") })
+		// The nested function fails, so only its changes and hooks are discarded.
+		manager.Run(ctx, func(ctx context.Context) error {
+			FromContext(ctx, tx).Exec("INSERT INTO bp_tx_test VALUES (2)")
+			AfterCommit(ctx, func() { executed = append(executed, "nested") })
+			return errRollback
+		})
+		if len(executed) != 0 {
+			t.Error("expected hooks not to be executed before commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(executed) != 1 || executed[0] != "outer" {
+		t.Errorf("unexpected executed hooks: %v", executed)
+	}
+	var count int64
+	tx.Raw("SELECT count(*) FROM bp_tx_test").Scan(&count)
+	if count != 1 {
+		t.Errorf("expected 1 row, got %d", count)
+	}
+
+	err = manager.Run(context.Background(), func(ctx context.Context) error {
+		AfterCommit(ctx, func() { executed = append(executed, "failed") })
+		return errRollback
+	})
+	if err != errRollback || len(executed) != 1 {
+		t.Errorf("expected rolled back transaction without hooks, got %v and %v", err, executed)
+	}
+}
@@ -0,0 +1,61 @@
+package bpdb
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type fuzzySearchModel struct {
+	ID    string
+	Email string
+}
+
+func (m fuzzySearchModel) TableName() string {
+	return "bp_test"
+}
+
+func newDryRunConnection(t *testing.T) *gorm.DB {
+	t.Helper()
+	storage, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("unable to open dry run connection: %v", err)
+	}
+	return storage
+}
+
+func TestGenerateFuzzySearch(t *testing.T) {
+	storage := newDryRunConnection(t)
+	query := storage.Model(fuzzySearchModel{})
+	GenerateFuzzySearch(query, " marco  o'rossi ", []string{"email", "lastname"}, 0.05)
+	sql := query.Find(&[]fuzzySearchModel{}).Statement.SQL.String()
+
+	expected := []string{
+		"to_tsvector('simple', email || ' ' || lastname || ' ' || regexp_replace(email, '[^\\w]+',' ', 'g') || ' ' || regexp_replace(lastname, '[^\\w]+',' ', 'g')) full_text",
+		"to_tsquery('simple', 'marco & o & rossi') query_key",
+		"rank_0",
+		"rank_1",
+		"SIMILARITY('marco & o & rossi',",
+		"query_key @@ full_text OR relevance >= 0.05",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(sql, fragment) {
+			t.Errorf("expected query to contain %q, got %s", fragment, sql)
+		}
+	}
+	if strings.Contains(sql, "o'rossi") {
+		t.Errorf("expected search key to be sanitized, got %s", sql)
+	}
+}
+
+func TestGenerateFuzzySearchOrderQuery(t *testing.T) {
+	order := GenerateFuzzySearchOrderQuery([]string{"email", "lastname", "firstname"}, Desc)
+	if order != "rank_0, rank_1, rank_2, relevance desc" {
+		t.Errorf("unexpected order query: %s", order)
+	}
+}
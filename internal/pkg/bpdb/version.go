@@ -0,0 +1,31 @@
+package bpdb
+
+import "errors"
+
+/*
+ErrVersionConflict is returned when a record cannot be saved because it has been changed
+by someone else since it was read.
+*/
+var ErrVersionConflict = errors.New("version-conflict")
+
+/*
+VersionModel contains the version field used for optimistic concurrency control.
+Embed it inside a model to let the generic Repository check and increment the version on each save.
+*/
+type VersionModel struct {
+	Version int64 `gorm:"column:version;type:integer"`
+}
+
+/*
+Versioning gives access to the version field of the model embedding it.
+*/
+func (m *VersionModel) Versioning() *VersionModel {
+	return m
+}
+
+/*
+versioned represents a model embedding the VersionModel.
+*/
+type versioned interface {
+	Versioning() *VersionModel
+}
@@ -0,0 +1,93 @@
+package bpenv
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/*
+EncryptedFileSecretProvider represents a secret provider reading the secrets from a local file,
+a JSON object of secrets by reference encrypted via AES-256-GCM (see EncryptSecrets).
+*/
+type encryptedFileSecretProvider struct {
+	secrets map[string]string
+}
+
+/*
+NewEncryptedFileSecretProvider reads and decrypts the given file with the given 32 bytes key.
+*/
+func NewEncryptedFileSecretProvider(file string, key []byte) (SecretProvider, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, errors.New("the secrets file is not base64 encoded")
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("the secrets file is corrupted")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("the secrets file cannot be decrypted with the given key")
+	}
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, errors.New("the secrets file does not contain a JSON object of strings")
+	}
+	return encryptedFileSecretProvider{secrets: secrets}, nil
+}
+
+/*
+EncryptSecrets encrypts the given secrets by reference with the given 32 bytes key,
+returning the content of a file readable by the encrypted file provider.
+*/
+func EncryptSecrets(secrets map[string]string, key []byte) ([]byte, error) {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ciphertext := aead.Seal(nonce, nonce, plaintext, nil)
+	return []byte(base64.StdEncoding.EncodeToString(ciphertext) + "\n"), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("the key of the secrets file must be 32 bytes long")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (p encryptedFileSecretProvider) Secret(ctx context.Context, reference string) (string, error) {
+	secret, ok := p.secrets[reference]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", reference)
+	}
+	return secret, nil
+}
@@ -1,125 +1,152 @@
 package bpenv
 
 import (
-	"fmt"
-	"os"
-	"strconv"
-
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 )
 
 /*
-Envs is a struct containing all the available environment variables available inside the application.
-These variables are set by the .env file and overwritten by input (e.g. via Docker compose)
-It is possible to set each variable as mandatory as input or optional, in which case it is needed
-to define a default value.
+AppMode represents the mode the application runs in, the same of the GIN modes.
 */
-type Envs struct {
-	DbHost                               string
-	DbPort                               int
-	DbUsername                           string
-	DbPassword                           string
-	DbName                               string
-	DbSslMode                            string
-	DbLogSlowQueryThreshold              int
-	AppPort                              int
-	AppMode                              string
-	AppCorsOrigin                        string
-	SearchRelevanceThreshold             float64
-	RateLimitRedisConnectionURI          string
-	RateLimitAnonymousTimeRangeSeconds   int
-	RateLimitAnonymousMaxRequestsInRange int
-	RateLimitAuthUserTimeRangeSeconds    int
-	RateLimitAuthUserMaxRequestsInRange  int
-}
+type AppMode string
+
+const (
+	DebugMode   AppMode = "debug"
+	ReleaseMode AppMode = "release"
+	TestMode    AppMode = "test"
+)
 
 /*
-ReadEnvs function reads all the Env Variables.
+AvailableAppModes represents a list of available application modes. It is generally used
+for validation purposes.
 */
-func ReadEnvs() *Envs {
-	godotenv.Load()
-	envs := Envs{
-		DbHost:                               getMandatoryStringValue("DB_HOST"),
-		DbPort:                               getMandatoryIntValue("DB_PORT"),
-		DbUsername:                           getMandatoryStringValue("DB_USERNAME"),
-		DbPassword:                           getMandatoryStringValue("DB_PASSWORD"),
-		DbName:                               getMandatoryStringValue("DB_NAME"),
-		DbSslMode:                            getMandatoryStringValue("DB_SSL_MODE"),
-		DbLogSlowQueryThreshold:              getMandatoryIntValue("DB_LOG_SLOW_QUERY_THRESHOLD"),
-		AppPort:                              getMandatoryIntValue("APP_PORT"),
-		AppMode:                              getMandatoryStringValue("APP_MODE"),
-		AppCorsOrigin:                        getMandatoryStringValue("APP_CORS_ORIGIN"),
-		SearchRelevanceThreshold:             getMandatoryFloatValue("SEARCH_RELEVANCE_THRESHOLD"),
-		RateLimitRedisConnectionURI:          getMandatoryStringValue("RATE_LIMIT_REDIS_CONNECTION_URI"),
-		RateLimitAnonymousTimeRangeSeconds:   getMandatoryIntValue("RATE_LIMIT_ANONYMOUS_TIME_RANGE_SECONDS"),
-		RateLimitAnonymousMaxRequestsInRange: getMandatoryIntValue("RATE_LIMIT_ANONYMOUS_MAX_REQUESTS_IN_RANGE"),
-		RateLimitAuthUserTimeRangeSeconds:    getMandatoryIntValue("RATE_LIMIT_AUTH_USER_TIME_RANGE_SECONDS"),
-		RateLimitAuthUserMaxRequestsInRange:  getMandatoryIntValue("RATE_LIMIT_AUTH_USER_MAX_REQUESTS_IN_RANGE"),
-	}
-
-	return &envs
-}
+var AvailableAppModes = []interface{}{DebugMode, ReleaseMode, TestMode}
 
 /*
-Read a mandatory integer field, otherwise raise a panic error.
+Envs is a struct containing all the available environment variables available inside the application.
+These variables are set by the .env file and overwritten by input (e.g. via Docker compose).
+Each field is read from the variable of its `env` tag and checked against the rules of its `validate` tag
+(see Load). Variables without a `default` tag are mandatory, while the `section` and `usage` tags
+describe the variables in the generated `.env.example` file (see Example). Variables with the `secret:"true"` tag
+are redacted when the configuration is printed or logged (see Dump).
 */
-func getMandatoryIntValue(field string) int {
-	val := os.Getenv(field)
-	if val == "" {
-		zap.L().Error(fmt.Sprintf("Missing Mandatory %s field value", field), zap.String("service", "envs-service"))
-		panic(fmt.Sprintf("Missing Mandatory %s field value", field))
-	}
-	intValue, err := strconv.Atoi(val)
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("Invalid %s field. Value is not an integer", field), zap.String("service", "envs-service"), zap.Error(err))
-		panic(fmt.Sprintf("Invalid %s field.  Value is not an integer", field))
-	}
-	return intValue
+type Envs struct {
+	DbHost                                   string   `env:"DB_HOST" section:"DATABASE"`
+	DbPort                                   int      `env:"DB_PORT" validate:"min=1,max=65535"`
+	DbUsername                               string   `env:"DB_USERNAME"`
+	DbPassword                               string   `env:"DB_PASSWORD" secret:"true"`
+	DbName                                   string   `env:"DB_NAME"`
+	DbSslMode                                string   `env:"DB_SSL_MODE" validate:"oneof=disable|allow|prefer|require|verify-ca|verify-full"`
+	DbLogSlowQueryThreshold                  int      `env:"DB_LOG_SLOW_QUERY_THRESHOLD" validate:"min=0"`
+	DbMaxOpenConns                           int      `env:"DB_MAX_OPEN_CONNS" default:"25" validate:"min=1"`
+	DbMaxIdleConns                           int      `env:"DB_MAX_IDLE_CONNS" default:"10" validate:"min=0"`
+	DbConnMaxLifetimeSeconds                 int      `env:"DB_CONN_MAX_LIFETIME_SECONDS" default:"300" validate:"min=0"`
+	DbConnMaxIdleTimeSeconds                 int      `env:"DB_CONN_MAX_IDLE_TIME_SECONDS" default:"60" validate:"min=0"`
+	DbStatementTimeoutSeconds                int      `env:"DB_STATEMENT_TIMEOUT_SECONDS" default:"30" validate:"min=0"`
+	DbApplicationName                        string   `env:"DB_APPLICATION_NAME" default:"blueprint"`
+	DbConnectRetryTimeoutSeconds             int      `env:"DB_CONNECT_RETRY_TIMEOUT_SECONDS" default:"30" validate:"min=0"`
+	DbTransactionMaxRetries                  int      `env:"DB_TRANSACTION_MAX_RETRIES" default:"3" validate:"min=0"`
+	DbReplicaHosts                           []string `env:"DB_REPLICA_HOSTS" default:"" usage:"Comma separated list of host:port, e.g. 10.0.0.2:5432,10.0.0.3:5432"`
+	DbReplicaHealthCheckIntervalSeconds      int      `env:"DB_REPLICA_HEALTH_CHECK_INTERVAL_SECONDS" default:"5" validate:"min=1"`
+	AppPort                                  int      `env:"APP_PORT" section:"APPLICATION" validate:"min=1,max=65535"`
+	AppMode                                  AppMode  `env:"APP_MODE" validate:"oneof=debug|release|test" usage:"For production: release"`
+	AppCorsOrigin                            string   `env:"APP_CORS_ORIGIN" validate:"url"`
+	AppMaxBodyBytes                          int      `env:"APP_MAX_BODY_BYTES" default:"1048576" validate:"min=1"`
+	AppStrictBinding                         bool     `env:"APP_STRICT_BINDING" default:"false" usage:"When true, unknown JSON fields are rejected"`
+	AppCursorSecret                          string   `env:"APP_CURSOR_SECRET" default:"" secret:"true" usage:"Secret signing the pagination cursors, shared by all the instances. Mandatory in release mode"`
+	AppOpenAPIValidation                     bool     `env:"APP_OPENAPI_VALIDATION" default:"false" usage:"When true, requests (and responses in debug mode) are validated against the OpenAPI document"`
+	AppOpenAPIFile                           string   `env:"APP_OPENAPI_FILE" default:"./api/openapi.json"`
+	AppCompressionMinBytes                   int      `env:"APP_COMPRESSION_MIN_BYTES" default:"1024" validate:"min=0" usage:"Smaller responses are not compressed"`
+	SearchRelevanceThreshold                 float64  `env:"SEARCH_RELEVANCE_THRESHOLD" section:"SEARCH" validate:"min=0,max=1"`
+	RateLimitStore                           string   `env:"RATE_LIMIT_STORE" section:"RATE LIMIT" default:"redis" validate:"oneof=redis|memory" usage:"Memory for single instance only"`
+	RateLimitRedisConnectionURI              string   `env:"RATE_LIMIT_REDIS_CONNECTION_URI" default:"" secret:"true" validate:"url"`
+	RateLimitRedisConnectRetryTimeoutSeconds int      `env:"RATE_LIMIT_REDIS_CONNECT_RETRY_TIMEOUT_SECONDS" default:"30" validate:"min=0"`
+	RateLimitFailureMode                     string   `env:"RATE_LIMIT_FAILURE_MODE" default:"open" validate:"oneof=open|closed" usage:"Applied when Redis is not reachable"`
+	RateLimitMemoryMaxKeys                   int      `env:"RATE_LIMIT_MEMORY_MAX_KEYS" default:"100000" validate:"min=1"`
+	RateLimitAlgorithm                       string   `env:"RATE_LIMIT_ALGORITHM" default:"fixed-window" validate:"oneof=fixed-window|sliding-window-log|sliding-window-counter|token-bucket"`
+	RateLimitHeadersStyle                    string   `env:"RATE_LIMIT_HEADERS_STYLE" default:"ietf" validate:"oneof=ietf|x-ratelimit|none"`
+	RateLimitPoliciesFile                    string   `env:"RATE_LIMIT_POLICIES_FILE" default:""`
+	RateLimitAnonymousTimeRangeSeconds       int      `env:"RATE_LIMIT_ANONYMOUS_TIME_RANGE_SECONDS" validate:"min=1"`
+	RateLimitAnonymousMaxRequestsInRange     int      `env:"RATE_LIMIT_ANONYMOUS_MAX_REQUESTS_IN_RANGE" validate:"min=1"`
+	RateLimitAuthUserTimeRangeSeconds        int      `env:"RATE_LIMIT_AUTH_USER_TIME_RANGE_SECONDS" validate:"min=1"`
+	RateLimitAuthUserMaxRequestsInRange      int      `env:"RATE_LIMIT_AUTH_USER_MAX_REQUESTS_IN_RANGE" validate:"min=1"`
+	QuotaRedisConnectionURI                  string   `env:"QUOTA_REDIS_CONNECTION_URI" section:"QUOTA" default:"${RATE_LIMIT_REDIS_CONNECTION_URI}" secret:"true" validate:"url"`
+	QuotaRedisConnectRetryTimeoutSeconds     int      `env:"QUOTA_REDIS_CONNECT_RETRY_TIMEOUT_SECONDS" default:"30" validate:"min=0"`
+	QuotaFile                                string   `env:"QUOTA_FILE" default:""`
+	QuotaFlushIntervalSeconds                int      `env:"QUOTA_FLUSH_INTERVAL_SECONDS" default:"60" validate:"min=1"`
+	IdempotencyRedisConnectionURI            string   `env:"IDEMPOTENCY_REDIS_CONNECTION_URI" section:"IDEMPOTENCY" default:"${RATE_LIMIT_REDIS_CONNECTION_URI}" secret:"true" validate:"url"`
+	IdempotencyConnectRetryTimeoutSeconds    int      `env:"IDEMPOTENCY_REDIS_CONNECT_RETRY_TIMEOUT_SECONDS" default:"30" validate:"min=0"`
+	IdempotencyTTLSeconds                    int      `env:"IDEMPOTENCY_TTL_SECONDS" default:"86400" validate:"min=1"`
+	CacheStore                               string   `env:"CACHE_STORE" section:"CACHE" default:"redis" validate:"oneof=redis|memory"`
+	CacheRedisConnectionURI                  string   `env:"CACHE_REDIS_CONNECTION_URI" default:"${RATE_LIMIT_REDIS_CONNECTION_URI}" secret:"true" validate:"url"`
+	CacheRedisConnectRetryTimeoutSeconds     int      `env:"CACHE_REDIS_CONNECT_RETRY_TIMEOUT_SECONDS" default:"30" validate:"min=0"`
+	CacheMemoryMaxKeys                       int      `env:"CACHE_MEMORY_MAX_KEYS" default:"10000" validate:"min=1"`
+	CacheTTLSeconds                          int      `env:"CACHE_TTL_SECONDS" default:"300" validate:"min=1"`
+	SecretEnvs
 }
 
 /*
-Read a mandatory string field, otherwise raise a panic error.
+SecretEnvs is a struct containing the environment variables configuring the secret provider,
+read before the other variables, so they can refer to its secrets.
 */
-func getMandatoryStringValue(field string) string {
-	val := os.Getenv(field)
-	if val == "" {
-		zap.L().Error(fmt.Sprintf("Missing Mandatory %s field value", field), zap.String("service", "envs-service"))
-		panic(fmt.Sprintf("Missing Mandatory %s field value", field))
-	}
-	return val
+type SecretEnvs struct {
+	SecretsProvider       string `env:"SECRETS_PROVIDER" section:"SECRETS" default:"none" validate:"oneof=none|encrypted-file|vault"`
+	SecretsFile           string `env:"SECRETS_FILE" default:"" usage:"File encrypted via the encrypt-secrets command, for the encrypted-file provider"`
+	SecretsFileKey        string `env:"SECRETS_FILE_KEY" default:"" secret:"true" usage:"Base64 encoded 32 bytes key of the secrets file"`
+	SecretsVaultAddress   string `env:"SECRETS_VAULT_ADDRESS" default:"" validate:"url"`
+	SecretsVaultToken     string `env:"SECRETS_VAULT_TOKEN" default:"" secret:"true"`
+	SecretsVaultMount     string `env:"SECRETS_VAULT_MOUNT" default:"secret" usage:"Mount of the KV v2 secrets engine"`
+	SecretsTimeoutSeconds int    `env:"SECRETS_TIMEOUT_SECONDS" default:"10" validate:"min=1"`
 }
 
 /*
-Read a mandatory integer field, otherwise raise a panic error.
+ReadEnvs function reads all the Env Variables, also from files and from the configured secret provider
+(see LoadWithSecrets). All the invalid variables are reported at once, then a panic error is raised.
 */
-func getMandatoryFloatValue(field string) float64 {
-	val := os.Getenv(field)
-	if val == "" {
-		zap.L().Error(fmt.Sprintf("Missing Mandatory %s field value", field), zap.String("service", "envs-service"))
-		panic(fmt.Sprintf("Missing Mandatory %s field value", field))
+func ReadEnvs() *Envs {
+	godotenv.Load()
+	envs := Envs{}
+	var provider SecretProvider
+	if err := Load(&envs.SecretEnvs); err == nil {
+		if provider, err = newSecretProvider(envs.SecretEnvs); err != nil {
+			if configErr, ok := err.(ConfigError); ok {
+				for _, varErr := range configErr.Errors {
+					zap.L().Error(varErr.Error(), zap.String("service", "envs-service"), zap.String("env", varErr.Name))
+				}
+			} else {
+				zap.L().Error("Error during secret provider initialization", zap.String("service", "envs-service"), zap.Error(err))
+			}
+			panic(err.Error())
+		}
 	}
-	floatValue, err := strconv.ParseFloat(val, 64)
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("Invalid %s field. Value is not a float", field), zap.String("service", "envs-service"), zap.Error(err))
-		panic(fmt.Sprintf("Invalid %s field.  Value is not a float", field))
+	configErr := ConfigError{}
+	if err := LoadWithSecrets(&envs, provider); err != nil {
+		var ok bool
+		if configErr, ok = err.(ConfigError); !ok {
+			panic(err.Error())
+		}
 	}
-	return floatValue
+	configErr.Errors = append(configErr.Errors, envs.releaseErrors()...)
+	if len(configErr.Errors) > 0 {
+		for _, varErr := range configErr.Errors {
+			zap.L().Error(varErr.Error(), zap.String("service", "envs-service"), zap.String("env", varErr.Name))
+		}
+		panic(configErr.Error())
+	}
+	return &envs
 }
 
 /*
-Read a mandatory integer field, otherwise raise a panic error.
+Check the variables that are optional during development but mandatory in release mode, e.g. the secrets
+shared by all the instances, whose random default would differ across instances and restarts.
 */
-func getMandatoryBooleanValue(field string) bool {
-	val := os.Getenv(field)
-	if val == "" {
-		zap.L().Error(fmt.Sprintf("Missing Mandatory %s field value", field), zap.String("service", "envs-service"))
-		panic(fmt.Sprintf("Missing Mandatory %s field value", field))
+func (e Envs) releaseErrors() []VarError {
+	if e.AppMode != ReleaseMode {
+		return nil
 	}
-	boolValue, err := strconv.ParseBool(val)
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("Invalid %s field. Value is not a boolean", field), zap.String("service", "envs-service"), zap.Error(err))
-		panic(fmt.Sprintf("Invalid %s field.  Value is not a boolean", field))
+	errors := []VarError{}
+	if e.AppCursorSecret == "" {
+		errors = append(errors, VarError{Name: "APP_CURSOR_SECRET", Reason: "is mandatory in release mode"})
 	}
-	return boolValue
+	return errors
 }
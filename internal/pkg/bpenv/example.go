@@ -0,0 +1,53 @@
+package bpenv
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+/*
+Example generates the content of a `.env.example` file from the `env` tags of the given struct,
+e.g. the Envs one, so the documented variables never drift from the read ones.
+Variables are grouped by their `section` tag and set to their default value, while the comment above them
+reports whether they are mandatory or secret, their allowed values and their `usage` tag. Comments are not
+written after the values, since the ones following an empty value would be read as the value itself. E.g.
+
+	# RATE LIMIT
+	# One of: redis, memory. Memory for single instance only
+	RATE_LIMIT_STORE=redis
+*/
+func Example(source interface{}) []byte {
+	sourceType := reflect.TypeOf(source)
+	if sourceType.Kind() == reflect.Pointer {
+		sourceType = sourceType.Elem()
+	}
+	content := bytes.NewBufferString("# Generated via `go run ./cmd/cli/cli.go env-example`, do not edit.\n")
+	for _, field := range envFields(reflect.New(sourceType).Elem()) {
+		if section, ok := field.tag.Lookup("section"); ok {
+			fmt.Fprintf(content, "\n# %s\n", section)
+		}
+		defaultValue, optional := field.tag.Lookup("default")
+		notes := []string{}
+		if !optional {
+			notes = append(notes, "Mandatory")
+		}
+		if field.tag.Get("secret") == "true" {
+			notes = append(notes, "Secret")
+		}
+		for _, rule := range strings.Split(field.tag.Get("validate"), ",") {
+			if values, ok := strings.CutPrefix(rule, "oneof="); ok {
+				notes = append(notes, fmt.Sprintf("One of: %s", strings.ReplaceAll(values, "|", ", ")))
+			}
+		}
+		if usage, ok := field.tag.Lookup("usage"); ok {
+			notes = append(notes, usage)
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(content, "# %s\n", strings.Join(notes, ". "))
+		}
+		fmt.Fprintf(content, "%s=%s\n", field.name, defaultValue)
+	}
+	return content.Bytes()
+}
@@ -0,0 +1,324 @@
+package bpenv
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+var urlType = reflect.TypeOf(url.URL{})
+
+/*
+VarError represents an invalid environment variable and the reason why it is invalid.
+*/
+type VarError struct {
+	Name   string
+	Reason string
+}
+
+func (e VarError) Error() string {
+	return fmt.Sprintf("%s %s", e.Name, e.Reason)
+}
+
+/*
+ConfigError lists all the invalid environment variables found while loading the configuration,
+so they can be fixed at once.
+*/
+type ConfigError struct {
+	Errors []VarError
+}
+
+func (e ConfigError) Error() string {
+	reasons := make([]string, len(e.Errors))
+	for i, varErr := range e.Errors {
+		reasons[i] = varErr.Error()
+	}
+	return fmt.Sprintf("invalid environment variables: %s", strings.Join(reasons, "; "))
+}
+
+/*
+Load populates the exported fields of the given struct pointer with a `env` tag from the environment variables,
+also the ones of its embedded structs. When a variable is not set or empty, the value of the `default` tag
+is used instead, expanding references to other variables, e.g. `default:"${RATE_LIMIT_REDIS_CONNECTION_URI}"`.
+Variables without a `default` tag are mandatory. Supported types are strings (also enums like AppMode), integers,
+floats, booleans, durations (e.g. `30s`), URLs and lists of comma separated strings.
+
+Not empty values are checked against the comma separated rules of the `validate` tag:
+  - `min=<n>` and `max=<n>`: bounds of numbers and durations, or of the length of strings and lists
+  - `oneof=<a>|<b>`: allowed values, e.g. for enums
+  - `url`: the string must be an absolute URL
+
+All the invalid variables are returned at once as a ConfigError.
+*/
+func Load(target interface{}) error {
+	return LoadWithSecrets(target, nil)
+}
+
+/*
+LoadWithSecrets works as Load, also reading the variables from files and secret providers:
+  - a variable can be set via the file at the path of the same variable with the `_FILE` suffix,
+    e.g. `DB_PASSWORD_FILE=/run/secrets/db-password` (Docker and Kubernetes secrets)
+  - a value in the form `secret:<reference>` is replaced with the secret read from the provider,
+    e.g. `DB_PASSWORD=secret:blueprint/database#password`
+*/
+func LoadWithSecrets(target interface{}, provider SecretProvider) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("the target must be a pointer to a struct, got %T", target)
+	}
+	configErr := ConfigError{}
+	// Defaults referring to other variables get their resolved value, e.g. the one read from a file
+	resolved := map[string]string{}
+	expand := func(name string) string {
+		if value, ok := resolved[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	}
+	for _, field := range envFields(value.Elem()) {
+		raw, err := lookupValue(field.name)
+		if err != nil {
+			configErr.Errors = append(configErr.Errors, VarError{Name: field.name, Reason: err.Error()})
+			continue
+		}
+		if raw == "" {
+			defaultValue, optional := field.tag.Lookup("default")
+			if !optional {
+				configErr.Errors = append(configErr.Errors, VarError{Name: field.name, Reason: "is mandatory"})
+				continue
+			}
+			raw = os.Expand(defaultValue, expand)
+		}
+		if raw, err = resolveSecret(raw, provider); err != nil {
+			configErr.Errors = append(configErr.Errors, VarError{Name: field.name, Reason: err.Error()})
+			continue
+		}
+		resolved[field.name] = raw
+		if raw == "" {
+			field.value.SetZero()
+			continue
+		}
+		if err := setValue(field.value, raw); err != nil {
+			configErr.Errors = append(configErr.Errors, VarError{Name: field.name, Reason: err.Error()})
+			continue
+		}
+		if err := validateValue(field.value, field.tag.Get("validate")); err != nil {
+			configErr.Errors = append(configErr.Errors, VarError{Name: field.name, Reason: err.Error()})
+		}
+	}
+	if len(configErr.Errors) > 0 {
+		return configErr
+	}
+	return nil
+}
+
+/*
+EnvField represents a field of a struct read from an environment variable.
+*/
+type envField struct {
+	name  string
+	tag   reflect.StructTag
+	value reflect.Value
+}
+
+/*
+Return the fields of the struct with a `env` tag, in order, including the ones of its embedded structs.
+*/
+func envFields(value reflect.Value) []envField {
+	fields := []envField{}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, envFields(value.Field(i))...)
+			continue
+		}
+		name, ok := field.Tag.Lookup("env")
+		if !ok || !field.IsExported() {
+			continue
+		}
+		fields = append(fields, envField{name: name, tag: field.Tag, value: value.Field(i)})
+	}
+	return fields
+}
+
+/*
+Read the value of a variable, or the content of the file set via the variable with the `_FILE` suffix.
+*/
+func lookupValue(name string) (string, error) {
+	value := os.Getenv(name)
+	file := os.Getenv(name + fileSuffix)
+	if file == "" {
+		return value, nil
+	}
+	if value != "" {
+		return "", fmt.Errorf("cannot be set together with %s%s", name, fileSuffix)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("cannot be read from %s%s: %v", name, fileSuffix, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+/*
+Parse the raw value of a variable into the field, based on its type.
+*/
+func setValue(field reflect.Value, raw string) error {
+	switch field.Type() {
+	case durationType:
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return errors.New("is not a duration, e.g. 30s")
+		}
+		field.SetInt(int64(duration))
+		return nil
+	case urlType, reflect.PointerTo(urlType):
+		parsedURL, err := parseURL(raw)
+		if err != nil {
+			return err
+		}
+		if field.Kind() == reflect.Pointer {
+			field.Set(reflect.ValueOf(parsedURL))
+		} else {
+			field.Set(reflect.ValueOf(*parsedURL))
+		}
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intValue, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("is not an integer")
+		}
+		field.SetInt(intValue)
+	case reflect.Float32, reflect.Float64:
+		floatValue, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return errors.New("is not a number")
+		}
+		field.SetFloat(floatValue)
+	case reflect.Bool:
+		boolValue, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("is not a boolean")
+		}
+		field.SetBool(boolValue)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("has an unsupported type %s", field.Type())
+		}
+		items := reflect.MakeSlice(field.Type(), 0, 0)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = reflect.Append(items, reflect.ValueOf(item).Convert(field.Type().Elem()))
+			}
+		}
+		field.Set(items)
+	default:
+		return fmt.Errorf("has an unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+/*
+Parse an absolute URL, e.g. `redis://localhost:6379/0` or `unix:///tmp/redis.sock`.
+*/
+func parseURL(raw string) (*url.URL, error) {
+	parsedURL, err := url.Parse(raw)
+	if err != nil || parsedURL.Scheme == "" || (parsedURL.Host == "" && parsedURL.Path == "") {
+		return nil, errors.New("is not an absolute URL")
+	}
+	return parsedURL, nil
+}
+
+/*
+Check the value of the field against the comma separated rules of its `validate` tag.
+*/
+func validateValue(field reflect.Value, rules string) error {
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == "" {
+			continue
+		}
+		ruleName, argument, _ := strings.Cut(rule, "=")
+		switch ruleName {
+		case "min", "max":
+			size, isLength, err := measure(field)
+			if err != nil {
+				return err
+			}
+			limit, err := parseLimit(field, argument)
+			if err != nil {
+				return fmt.Errorf("has an invalid rule %s", rule)
+			}
+			subject := "be"
+			if isLength {
+				subject = "have a length of"
+			}
+			if ruleName == "min" && size < limit {
+				return fmt.Errorf("must %s at least %s", subject, argument)
+			}
+			if ruleName == "max" && size > limit {
+				return fmt.Errorf("must %s at most %s", subject, argument)
+			}
+		case "oneof":
+			allowed := strings.Split(argument, "|")
+			values := []reflect.Value{field}
+			if field.Kind() == reflect.Slice {
+				values = make([]reflect.Value, field.Len())
+				for i := range values {
+					values[i] = field.Index(i)
+				}
+			}
+			for _, value := range values {
+				if !slices.Contains(allowed, fmt.Sprint(value.Interface())) {
+					return fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+				}
+			}
+		case "url":
+			if field.Kind() != reflect.String {
+				return fmt.Errorf("has the rule %s not supported by its type", rule)
+			}
+			if _, err := parseURL(field.String()); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("has an unknown rule %s", rule)
+		}
+	}
+	return nil
+}
+
+/*
+Return the number or the length of the value to compare with the min and max rules.
+*/
+func measure(field reflect.Value) (float64, bool, error) {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), false, nil
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), false, nil
+	case reflect.String, reflect.Slice:
+		return float64(field.Len()), true, nil
+	}
+	return 0, false, fmt.Errorf("has a type %s not supported by the min and max rules", field.Type())
+}
+
+/*
+Parse the limit of a min or max rule, a duration for durations, otherwise a number.
+*/
+func parseLimit(field reflect.Value, argument string) (float64, error) {
+	if field.Type() == durationType {
+		duration, err := time.ParseDuration(argument)
+		return float64(duration), err
+	}
+	return strconv.ParseFloat(argument, 64)
+}
@@ -0,0 +1,113 @@
+package bpenv
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+type testConfig struct {
+	Host     string        `env:"TEST_HOST"`
+	Port     int           `env:"TEST_PORT" default:"8080" validate:"min=1,max=65535"`
+	Ratio    float64       `env:"TEST_RATIO" default:"0.5" validate:"max=1"`
+	Enabled  bool          `env:"TEST_ENABLED" default:"false"`
+	Timeout  time.Duration `env:"TEST_TIMEOUT" default:"30s" validate:"min=1s"`
+	Mode     AppMode       `env:"TEST_MODE" default:"debug" validate:"oneof=debug|release|test"`
+	Hosts    []string      `env:"TEST_HOSTS" default:"" validate:"max=2"`
+	Endpoint string        `env:"TEST_ENDPOINT" default:"${TEST_HOST}/api" validate:"url"`
+	internal string
+}
+
+func TestLoadParsesValuesAndDefaults(t *testing.T) {
+	t.Setenv("TEST_HOST", "https://example.com")
+	t.Setenv("TEST_ENABLED", "true")
+	t.Setenv("TEST_MODE", "release")
+	t.Setenv("TEST_HOSTS", "10.0.0.2:5432, ,10.0.0.3:5432")
+	config := testConfig{}
+	if err := Load(&config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := testConfig{
+		Host:     "https://example.com",
+		Port:     8080,
+		Ratio:    0.5,
+		Enabled:  true,
+		Timeout:  30 * time.Second,
+		Mode:     ReleaseMode,
+		Hosts:    []string{"10.0.0.2:5432", "10.0.0.3:5432"},
+		Endpoint: "https://example.com/api",
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config)
+	}
+}
+
+func TestLoadReportsAllTheInvalidVariables(t *testing.T) {
+	t.Setenv("TEST_PORT", "http")
+	t.Setenv("TEST_RATIO", "2")
+	t.Setenv("TEST_ENABLED", "maybe")
+	t.Setenv("TEST_TIMEOUT", "0s")
+	t.Setenv("TEST_MODE", "production")
+	t.Setenv("TEST_HOSTS", "a,b,c")
+	t.Setenv("TEST_ENDPOINT", "example.com")
+	os.Unsetenv("TEST_HOST")
+	err := Load(&testConfig{})
+	var configErr ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("expected a ConfigError, got %v", err)
+	}
+	expected := []VarError{
+		{Name: "TEST_HOST", Reason: "is mandatory"},
+		{Name: "TEST_PORT", Reason: "is not an integer"},
+		{Name: "TEST_RATIO", Reason: "must be at most 1"},
+		{Name: "TEST_ENABLED", Reason: "is not a boolean"},
+		{Name: "TEST_TIMEOUT", Reason: "must be at least 1s"},
+		{Name: "TEST_MODE", Reason: "must be one of debug, release, test"},
+		{Name: "TEST_HOSTS", Reason: "must have a length of at most 2"},
+		{Name: "TEST_ENDPOINT", Reason: "is not an absolute URL"},
+	}
+	if !reflect.DeepEqual(configErr.Errors, expected) {
+		t.Errorf("expected %v, got %v", expected, configErr.Errors)
+	}
+}
+
+func TestReleaseModeRequiresTheCursorSecret(t *testing.T) {
+	if errs := (Envs{AppMode: DebugMode}).releaseErrors(); len(errs) != 0 {
+		t.Errorf("expected no errors in debug mode, got %v", errs)
+	}
+	if errs := (Envs{AppMode: ReleaseMode, AppCursorSecret: "secret"}).releaseErrors(); len(errs) != 0 {
+		t.Errorf("expected no errors with a cursor secret, got %v", errs)
+	}
+	expected := []VarError{{Name: "APP_CURSOR_SECRET", Reason: "is mandatory in release mode"}}
+	if errs := (Envs{AppMode: ReleaseMode}).releaseErrors(); !reflect.DeepEqual(errs, expected) {
+		t.Errorf("expected %v, got %v", expected, errs)
+	}
+}
+
+func TestExampleIsUpToDate(t *testing.T) {
+	content, err := os.ReadFile("../../../.env.example")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(content) != string(Example(Envs{})) {
+		t.Errorf("the .env.example file is outdated, run `go run ./cmd/cli/cli.go env-example`")
+	}
+	// The file must be read back with the default values, and mandatory variables left empty
+	values, err := godotenv.Parse(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, field := range envFields(reflect.ValueOf(Envs{})) {
+		expected := os.Expand(field.tag.Get("default"), func(name string) string {
+			return values[name]
+		})
+		if value, exists := values[field.name]; !exists || value != expected {
+			t.Errorf("expected %s to be read as %q, got %q", field.name, expected, value)
+		}
+	}
+}
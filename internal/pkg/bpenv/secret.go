@@ -0,0 +1,187 @@
+package bpenv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Suffix of the variables set via the path of a file containing their value.
+*/
+const fileSuffix = "_FILE"
+
+/*
+Prefix of the values read from the secret provider, e.g. `secret:blueprint/database#password`.
+*/
+const secretPrefix = "secret:"
+
+/*
+Replacement of the redacted secrets.
+*/
+const redactedValue = "******"
+
+/*
+SecretProvider represents a source of secrets, e.g. a secret manager, where each secret is identified
+by a reference whose format depends on the provider.
+*/
+type SecretProvider interface {
+	Secret(ctx context.Context, reference string) (string, error)
+}
+
+/*
+Provider represents a type of secret provider.
+*/
+type Provider string
+
+const (
+	NoProvider            Provider = "none"
+	EncryptedFileProvider Provider = "encrypted-file"
+	VaultProvider         Provider = "vault"
+)
+
+/*
+AvailableProviders represents a list of available secret providers. It is generally used
+for validation purposes.
+*/
+var AvailableProviders = []interface{}{NoProvider, EncryptedFileProvider, VaultProvider}
+
+/*
+Build the secret provider configured via the SECRETS_* env variables, if any,
+after checking that all the settings it requires are set.
+*/
+func newSecretProvider(envs SecretEnvs) (SecretProvider, error) {
+	if errs := envs.providerErrors(); len(errs) > 0 {
+		return nil, ConfigError{Errors: errs}
+	}
+	switch Provider(envs.SecretsProvider) {
+	case EncryptedFileProvider:
+		key, _ := base64.StdEncoding.DecodeString(envs.SecretsFileKey)
+		return NewEncryptedFileSecretProvider(envs.SecretsFile, key)
+	case VaultProvider:
+		return NewVaultSecretProvider(
+			envs.SecretsVaultAddress,
+			envs.SecretsVaultToken,
+			envs.SecretsVaultMount,
+			time.Duration(envs.SecretsTimeoutSeconds)*time.Second,
+		), nil
+	}
+	return nil, nil
+}
+
+/*
+Check the settings required by the configured provider, which are optional otherwise,
+so all the missing or invalid ones are reported at once.
+*/
+func (e SecretEnvs) providerErrors() []VarError {
+	varErrors := []VarError{}
+	mandatory := func(name string, value string) {
+		if value == "" {
+			varErrors = append(varErrors, VarError{Name: name, Reason: fmt.Sprintf("is mandatory for the %s provider", e.SecretsProvider)})
+		}
+	}
+	switch Provider(e.SecretsProvider) {
+	case EncryptedFileProvider:
+		mandatory("SECRETS_FILE", e.SecretsFile)
+		mandatory("SECRETS_FILE_KEY", e.SecretsFileKey)
+		if key, err := base64.StdEncoding.DecodeString(e.SecretsFileKey); e.SecretsFileKey != "" && (err != nil || len(key) != 32) {
+			varErrors = append(varErrors, VarError{Name: "SECRETS_FILE_KEY", Reason: "is not a base64 encoded 32 bytes key"})
+		}
+	case VaultProvider:
+		mandatory("SECRETS_VAULT_ADDRESS", e.SecretsVaultAddress)
+		mandatory("SECRETS_VAULT_TOKEN", e.SecretsVaultToken)
+		mandatory("SECRETS_VAULT_MOUNT", e.SecretsVaultMount)
+	}
+	return varErrors
+}
+
+/*
+Replace a value in the form `secret:<reference>` with the secret read from the provider.
+Other values are returned as they are.
+*/
+func resolveSecret(raw string, provider SecretProvider) (string, error) {
+	reference, ok := strings.CutPrefix(raw, secretPrefix)
+	if !ok {
+		return raw, nil
+	}
+	if provider == nil {
+		return "", errors.New("refers to a secret, but no secret provider is configured")
+	}
+	secret, err := provider.Secret(context.Background(), reference)
+	if err != nil {
+		return "", fmt.Errorf("refers to a secret that cannot be read: %v", err)
+	}
+	return secret, nil
+}
+
+/*
+Redact the value of a secret. Only the password of URLs is redacted, so they can still be
+recognized, e.g. `redis://:xxxxx@localhost:6379/0`.
+*/
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	if parsedURL, err := url.Parse(value); err == nil && parsedURL.Scheme != "" && parsedURL.Host != "" {
+		return parsedURL.Redacted()
+	}
+	return redactedValue
+}
+
+/*
+Dump returns the variables of the given struct and their values, by name, redacting the ones
+with the `secret:"true"` tag, so the configuration can be safely logged.
+*/
+func Dump(source interface{}) map[string]string {
+	value := reflect.ValueOf(source)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	dump := map[string]string{}
+	for _, field := range envFields(value) {
+		fieldValue := fmt.Sprint(field.value.Interface())
+		if field.value.Kind() == reflect.Slice {
+			items := make([]string, field.value.Len())
+			for i := range items {
+				items[i] = fmt.Sprint(field.value.Index(i).Interface())
+			}
+			fieldValue = strings.Join(items, ",")
+		}
+		if field.tag.Get("secret") == "true" {
+			fieldValue = redact(fieldValue)
+		}
+		dump[field.name] = fieldValue
+	}
+	return dump
+}
+
+/*
+String returns the env variables, with the secrets redacted.
+*/
+func (e Envs) String() string {
+	dump := Dump(e)
+	names := make([]string, 0, len(dump))
+	for name := range dump {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = fmt.Sprintf("%s=%s", name, dump[name])
+	}
+	return strings.Join(lines, "\n")
+}
+
+/*
+MarshalJSON returns the env variables, with the secrets redacted, e.g. when logged via zap.Any.
+*/
+func (e Envs) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Dump(e))
+}
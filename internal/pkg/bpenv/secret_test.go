@@ -0,0 +1,180 @@
+package bpenv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testSecretConfig struct {
+	Password string `env:"TEST_PASSWORD" secret:"true"`
+	RedisURI string `env:"TEST_REDIS_URI" default:"" secret:"true"`
+	CacheURI string `env:"TEST_CACHE_URI" default:"${TEST_REDIS_URI}" secret:"true"`
+	Name     string `env:"TEST_NAME" default:"blueprint"`
+}
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func writeTestFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, content, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return file
+}
+
+func TestLoadReadsFileVariables(t *testing.T) {
+	t.Setenv("TEST_PASSWORD_FILE", writeTestFile(t, "password", []byte("s3cret\n")))
+	t.Setenv("TEST_REDIS_URI_FILE", writeTestFile(t, "redis-uri", []byte("redis://:s3cret@localhost:6379/0")))
+	config := testSecretConfig{}
+	if err := Load(&config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Password != "s3cret" || config.CacheURI != "redis://:s3cret@localhost:6379/0" {
+		t.Errorf("expected the values of the files, got %+v", config)
+	}
+	t.Setenv("TEST_PASSWORD", "plain")
+	t.Setenv("TEST_REDIS_URI_FILE", "/not/existing")
+	var configErr ConfigError
+	if err := Load(&testSecretConfig{}); !errors.As(err, &configErr) || len(configErr.Errors) != 2 {
+		t.Errorf("expected the conflicting and the unreadable variables, got %v", err)
+	}
+}
+
+func TestLoadReadsEncryptedFileSecrets(t *testing.T) {
+	content, err := EncryptSecrets(map[string]string{"database/password": "s3cret"}, testKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file := writeTestFile(t, "secrets.enc", content)
+	if _, err := NewEncryptedFileSecretProvider(file, []byte("fedcba9876543210fedcba9876543210")); err == nil {
+		t.Errorf("expected an error decrypting with a different key")
+	}
+	provider, err := NewEncryptedFileSecretProvider(file, testKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv("TEST_PASSWORD", "secret:database/password")
+	config := testSecretConfig{}
+	if err := LoadWithSecrets(&config, provider); err != nil || config.Password != "s3cret" {
+		t.Errorf("expected the secret of the file, got %q %v", config.Password, err)
+	}
+	t.Setenv("TEST_PASSWORD", "secret:database/username")
+	if err := LoadWithSecrets(&config, provider); err == nil || !strings.Contains(err.Error(), "database/username not found") {
+		t.Errorf("expected the missing secret error, got %v", err)
+	}
+	if err := Load(&config); err == nil || !strings.Contains(err.Error(), "no secret provider is configured") {
+		t.Errorf("expected the missing provider error, got %v", err)
+	}
+}
+
+func TestLoadReadsVaultSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/kv/data/blueprint/database" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"data": map[string]interface{}{"password": "s3cret"}},
+		})
+	}))
+	defer server.Close()
+	provider := NewVaultSecretProvider(server.URL, "token", "kv", time.Second)
+	t.Setenv("TEST_PASSWORD", "secret:blueprint/database#password")
+	config := testSecretConfig{}
+	if err := LoadWithSecrets(&config, provider); err != nil || config.Password != "s3cret" {
+		t.Errorf("expected the secret of Vault, got %q %v", config.Password, err)
+	}
+	tests := map[string]SecretProvider{
+		"blueprint/database#username": provider,
+		"blueprint/cache#password":    provider,
+		"blueprint/database":          provider,
+		"blueprint/database#password": NewVaultSecretProvider(server.URL, "wrong", "kv", time.Second),
+	}
+	for reference, provider := range tests {
+		if _, err := provider.Secret(context.Background(), reference); err == nil {
+			t.Errorf("expected an error reading %s", reference)
+		}
+	}
+}
+
+func TestDumpRedactsSecrets(t *testing.T) {
+	config := testSecretConfig{Password: "s3cret", RedisURI: "redis://:s3cret@localhost:6379/0", CacheURI: "redis://localhost:6379/1", Name: "blueprint"}
+	expected := map[string]string{
+		"TEST_PASSWORD":  "******",
+		"TEST_REDIS_URI": "redis://:xxxxx@localhost:6379/0",
+		"TEST_CACHE_URI": "redis://localhost:6379/1",
+		"TEST_NAME":      "blueprint",
+	}
+	if dump := Dump(config); !reflect.DeepEqual(dump, expected) {
+		t.Errorf("expected %v, got %v", expected, dump)
+	}
+	envs := &Envs{DbPassword: "s3cret", SecretEnvs: SecretEnvs{SecretsVaultToken: "s3cret"}}
+	content, err := json.Marshal(envs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, dump := range []string{string(content), fmt.Sprint(envs), fmt.Sprintf("%+v", *envs)} {
+		if strings.Contains(dump, "s3cret") || !strings.Contains(dump, "DB_PASSWORD") {
+			t.Errorf("expected the secrets to be redacted, got %s", dump)
+		}
+	}
+}
+
+func TestSecretProviderRequiresItsSettings(t *testing.T) {
+	tests := map[string]struct {
+		envs     SecretEnvs
+		expected []VarError
+	}{
+		"none": {
+			envs: SecretEnvs{SecretsProvider: string(NoProvider)},
+		},
+		"encrypted-file": {
+			envs: SecretEnvs{SecretsProvider: string(EncryptedFileProvider)},
+			expected: []VarError{
+				{Name: "SECRETS_FILE", Reason: "is mandatory for the encrypted-file provider"},
+				{Name: "SECRETS_FILE_KEY", Reason: "is mandatory for the encrypted-file provider"},
+			},
+		},
+		"encrypted-file with a short key": {
+			envs: SecretEnvs{SecretsProvider: string(EncryptedFileProvider), SecretsFile: "secrets.enc", SecretsFileKey: "c2hvcnQ="},
+			expected: []VarError{
+				{Name: "SECRETS_FILE_KEY", Reason: "is not a base64 encoded 32 bytes key"},
+			},
+		},
+		"vault": {
+			envs: SecretEnvs{SecretsProvider: string(VaultProvider), SecretsVaultMount: "secret"},
+			expected: []VarError{
+				{Name: "SECRETS_VAULT_ADDRESS", Reason: "is mandatory for the vault provider"},
+				{Name: "SECRETS_VAULT_TOKEN", Reason: "is mandatory for the vault provider"},
+			},
+		},
+	}
+	for name, test := range tests {
+		provider, err := newSecretProvider(test.envs)
+		if test.expected == nil {
+			if err != nil || provider != nil {
+				t.Errorf("%s: expected no provider and no error, got %v %v", name, provider, err)
+			}
+			continue
+		}
+		var configErr ConfigError
+		if !errors.As(err, &configErr) || !reflect.DeepEqual(configErr.Errors, test.expected) {
+			t.Errorf("%s: expected %v, got %v", name, test.expected, err)
+		}
+	}
+}
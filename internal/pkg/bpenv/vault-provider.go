@@ -0,0 +1,74 @@
+package bpenv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/*
+VaultSecretProvider represents a secret provider reading the secrets from the KV v2 secrets engine
+of Vault, or of any service exposing the same HTTP API.
+*/
+type vaultSecretProvider struct {
+	address string
+	token   string
+	mount   string
+	client  http.Client
+}
+
+/*
+NewVaultSecretProvider returns a provider reading the secrets from the Vault at the given address,
+authenticated via the given token. Secrets are referred as `<path>#<key>`, e.g. `blueprint/database#password`
+is the `password` key of the `blueprint/database` secret of the given KV v2 mount.
+*/
+func NewVaultSecretProvider(address string, token string, mount string, timeout time.Duration) SecretProvider {
+	return vaultSecretProvider{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		mount:   strings.Trim(mount, "/"),
+		client:  http.Client{Timeout: timeout},
+	}
+}
+
+func (p vaultSecretProvider) Secret(ctx context.Context, reference string) (string, error) {
+	path, key, ok := strings.Cut(reference, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("secret %s is not in the form <path>#<key>", reference)
+	}
+	secretURL, err := url.JoinPath(p.address, "v1", p.mount, "data", path)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, secretURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secret %s not readable, status %d", path, res.StatusCode)
+	}
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", errors.New("invalid response of Vault")
+	}
+	secret, ok := body.Data.Data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", reference)
+	}
+	return secret, nil
+}
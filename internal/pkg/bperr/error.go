@@ -1,8 +1,117 @@
 package bperr
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
 
 /*
-ErrGeneric represents a generic error across the entire application.
+Error represents an application error with a stable code that clients can rely on,
+the HTTP status to return, a human readable message and optional field-level details.
+The cause, if any, is never returned to clients, but it is available for logging
+and it can be inspected via errors.Is and errors.As.
+
+Errors are compared by code, so an error enriched with a cause or details
+still matches the error it was derived from. E.g.
+
+	var errUserNotFound = bperr.New(http.StatusNotFound, "user-not-found", "The user does not exist")
+	...
+	return errUserNotFound.WithCause(err)
+	...
+	errors.Is(err, errUserNotFound) // true
+*/
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	Details map[string][]string
+	Cause   error
+}
+
+/*
+New creates a new application error.
+*/
+func New(status int, code string, message string) *Error {
+	return &Error{
+		Code:    code,
+		Status:  status,
+		Message: message,
+	}
+}
+
+func (e Error) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %s", e.Code, e.Cause.Error())
+	}
+	return e.Code
+}
+
+func (e Error) Unwrap() error {
+	return e.Cause
+}
+
+func (e Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+/*
+WithCause returns a copy of the error wrapping the given cause.
+*/
+func (e Error) WithCause(cause error) *Error {
+	e.Cause = cause
+	return &e
+}
+
+/*
+WithMessage returns a copy of the error with a different human readable message.
+*/
+func (e Error) WithMessage(message string) *Error {
+	e.Message = message
+	return &e
+}
+
+/*
+WithDetails returns a copy of the error with the given field-level details,
+mapping each field to the codes of its errors.
+*/
+func (e Error) WithDetails(details map[string][]string) *Error {
+	e.Details = details
+	return &e
+}
+
+/*
+From returns the application error contained in the chain of the given error.
+Errors not generated by this package are converted into a generic error wrapping them.
+*/
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return ErrGeneric.WithCause(err)
+}
+
+/*
+List of errors shared across the entire application. Modules define their own errors
+via New, e.g. to return a more specific code.
 */
-var ErrGeneric = errors.New("generic-error")
+var (
+	ErrGeneric              = New(http.StatusInternalServerError, "internal-server-error", "Something went wrong, please try again later")
+	ErrBadRequest           = New(http.StatusBadRequest, "bad-request", "The request is malformed")
+	ErrInvalidCursor        = New(http.StatusBadRequest, "invalid-cursor", "The cursor is not valid for this request")
+	ErrUnauthorized         = New(http.StatusUnauthorized, "unauthorized", "Authentication is required")
+	ErrForbidden            = New(http.StatusForbidden, "forbidden", "You are not allowed to perform this operation")
+	ErrNotFound             = New(http.StatusNotFound, "not-found", "The resource does not exist")
+	ErrEndpointNotFound     = New(http.StatusNotFound, "endpoint-not-found", "The endpoint does not exist")
+	ErrRequestTimeout       = New(http.StatusRequestTimeout, "request-timeout", "The request took too long to be processed")
+	ErrPreconditionFailed   = New(http.StatusPreconditionFailed, "precondition-failed", "The resource has been changed since it was read")
+	ErrRequestTooLarge      = New(http.StatusRequestEntityTooLarge, "request-too-large", "The request body is too large")
+	ErrValidation           = New(http.StatusUnprocessableEntity, "validation-error", "The request contains invalid parameters")
+	ErrPreconditionRequired = New(http.StatusPreconditionRequired, "precondition-required", "The If-Match header is required")
+	ErrTooManyRequests      = New(http.StatusTooManyRequests, "too-many-requests", "Too many requests, please retry later")
+	ErrQuotaExceeded        = New(http.StatusTooManyRequests, "quota-exceeded", "The quota of the current period has been exhausted")
+	ErrRequestInProgress    = New(http.StatusConflict, "idempotency-key-in-progress", "A request with the same Idempotency-Key is still in progress")
+	ErrIdempotencyMismatch  = New(http.StatusConflict, "idempotency-key-mismatch", "The Idempotency-Key has already been used for a different request")
+)
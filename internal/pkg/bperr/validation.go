@@ -0,0 +1,62 @@
+package bperr
+
+import (
+	"errors"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+/*
+NewValidationError converts the errors returned by ozzo-validation into a validation error,
+mapping each field to the codes of its errors, e.g. `{"email": ["is-email"]}`.
+Nested structs are flattened with dots, e.g. `address.city`.
+Errors not generated by the validation rules are returned as generic errors.
+*/
+func NewValidationError(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	details := map[string][]string{}
+	if !collectValidationDetails(details, "", err) {
+		return ErrGeneric.WithCause(err)
+	}
+	return ErrValidation.WithCause(err).WithDetails(details)
+}
+
+func collectValidationDetails(details map[string][]string, field string, err error) bool {
+	var fieldErrors validation.Errors
+	if errors.As(err, &fieldErrors) {
+		for name, fieldErr := range fieldErrors {
+			if field != "" {
+				name = field + "." + name
+			}
+			if !collectValidationDetails(details, name, fieldErr) {
+				return false
+			}
+		}
+		return true
+	}
+	var internalErr validation.InternalError
+	if errors.As(err, &internalErr) {
+		return false
+	}
+	if field == "" {
+		field = "request"
+	}
+	code := "invalid"
+	var ruleErr validation.Error
+	if errors.As(err, &ruleErr) {
+		code = validationCode(ruleErr.Code())
+	}
+	details[field] = append(details[field], code)
+	return true
+}
+
+/*
+Convert ozzo-validation codes to the style of the application codes, e.g. `validation_is_email` to `is-email`.
+*/
+func validationCode(code string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(code, "validation_"), "_", "-")
+}
@@ -0,0 +1,62 @@
+package bperr
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+type testAddress struct {
+	City string
+}
+
+type testInput struct {
+	Email   string
+	Age     int
+	Address testAddress
+}
+
+func TestNewValidationError(t *testing.T) {
+	input := testInput{Email: "not-an-email", Age: 200}
+	err := validation.ValidateStruct(&input,
+		validation.Field(&input.Email, is.Email),
+		validation.Field(&input.Age, validation.Max(150)),
+		validation.Field(&input.Address, validation.By(func(interface{}) error {
+			return validation.ValidateStruct(&input.Address, validation.Field(&input.Address.City, validation.Required))
+		})),
+	)
+	appErr := NewValidationError(err)
+	if appErr.Status != http.StatusUnprocessableEntity || !errors.Is(appErr, ErrValidation) {
+		t.Fatalf("expected a validation error, got %v", appErr)
+	}
+	expected := map[string][]string{
+		"Email":        {"is-email"},
+		"Age":          {"max-less-equal-than-required"},
+		"Address.City": {"required"},
+	}
+	if !reflect.DeepEqual(appErr.Details, expected) {
+		t.Errorf("expected details %v, got %v", expected, appErr.Details)
+	}
+}
+
+func TestNewValidationErrorInternal(t *testing.T) {
+	err := validation.Errors{"Email": validation.NewInternalError(errors.New("boom"))}
+	if appErr := NewValidationError(err); !errors.Is(appErr, ErrGeneric) {
+		t.Errorf("expected a generic error, got %v", appErr)
+	}
+}
+
+func TestErrorIsByCode(t *testing.T) {
+	errCustom := New(http.StatusNotFound, "custom-not-found", "Not found")
+	err := errCustom.WithCause(errors.New("record not found"))
+	if !errors.Is(err, errCustom) || errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the error to match by code only, got %v", err)
+	}
+	if From(errors.New("boom")).Code != ErrGeneric.Code {
+		t.Error("expected unknown errors to be converted into generic errors")
+	}
+}
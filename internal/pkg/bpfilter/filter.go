@@ -0,0 +1,72 @@
+package bpfilter
+
+/*
+Operator represents a comparison that can be applied to a filterable field.
+*/
+type Operator string
+
+/*
+List of operators available for filters. Values of the `in` operator are comma separated,
+while the `isNull` operator accepts a boolean selecting null or not null values.
+*/
+const (
+	Eq         Operator = "eq"
+	Neq        Operator = "neq"
+	Contains   Operator = "contains"
+	StartsWith Operator = "startsWith"
+	Gt         Operator = "gt"
+	Gte        Operator = "gte"
+	Lt         Operator = "lt"
+	Lte        Operator = "lte"
+	In         Operator = "in"
+	IsNull     Operator = "isNull"
+)
+
+/*
+AvailableOperators represents the list of available operators.
+*/
+var AvailableOperators = []interface{}{Eq, Neq, Contains, StartsWith, Gt, Gte, Lt, Lte, In, IsNull}
+
+/*
+Type represents the type of the values of a filterable field. Values are converted
+before reaching the database, so invalid values are rejected as validation errors.
+*/
+type Type string
+
+const (
+	String Type = "string"
+	Number Type = "number"
+	Bool   Type = "bool"
+	Time   Type = "time"
+	UUID   Type = "uuid"
+)
+
+/*
+Field represents a filterable field, with the column it refers to,
+the type of its values and the operators that can be applied.
+*/
+type Field struct {
+	Column    string
+	Type      Type
+	Operators []Operator
+}
+
+/*
+Fields represents the whitelist of filterable fields of a module, by name exposed to clients. E.g.
+
+	var userFilterableFields = bpfilter.Fields{
+		"email":     {Column: "email", Type: bpfilter.String, Operators: []bpfilter.Operator{bpfilter.Eq, bpfilter.Contains}},
+		"createdAt": {Column: "created_at", Type: bpfilter.Time, Operators: []bpfilter.Operator{bpfilter.Gte, bpfilter.Lt}},
+	}
+*/
+type Fields map[string]Field
+
+/*
+Filter represents a validated condition on a column, with values already converted in the type of the field.
+*/
+type Filter struct {
+	Field    string
+	Column   string
+	Operator Operator
+	Values   []interface{}
+}
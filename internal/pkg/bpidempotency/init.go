@@ -0,0 +1,26 @@
+package bpidempotency
+
+import (
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpredis"
+	"go.uber.org/zap"
+)
+
+var store idempotencyStore
+
+/*
+Init initializes the Idempotency service, storing the responses in Redis for the given number of seconds.
+By default it uses the same Redis of the rate limit, sharing its client. If Redis is not ready, the connection is retried
+with an exponential backoff until the retry timeout is reached.
+*/
+func Init(iConnectionURI string, iConnectRetryTimeoutSeconds int, iTTLSeconds int) {
+	zap.L().Info("Initializing Idempotency Service on Redis. Connecting...", zap.String("service", "idempotency"))
+	client, err := bpredis.Connect(iConnectionURI, iConnectRetryTimeoutSeconds)
+	if err != nil {
+		zap.L().Error("Error during Idempotency Service initalization", zap.String("service", "idempotency"), zap.Error(err))
+		panic(err)
+	}
+	store = idempotencyStore{client: client, ttl: time.Duration(iTTLSeconds) * time.Second}
+	zap.L().Info("Idempotency Service initialized on Redis. Connected!", zap.String("service", "idempotency"))
+}
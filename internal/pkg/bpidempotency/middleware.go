@@ -0,0 +1,182 @@
+package bpidempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"github.com/besasch88/blueprint/internal/pkg/bprouter"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+/*
+IdempotencyKeyHeader is the header where clients send the key identifying a request and its retries.
+*/
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+/*
+ReplayedHeader is returned together with the responses replayed from a previous request.
+*/
+const ReplayedHeader = "Idempotent-Replayed"
+
+/*
+Max length of the keys, e.g. enough for UUIDs and hashes.
+*/
+const maxKeyLength = 255
+
+/*
+Client errors depending on the moment of the request, e.g. a timeout, a concurrent update or
+an exceeded quota, so their retries may succeed. They are not stored, as the server errors.
+*/
+var transientStatuses = []int{http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests}
+
+/*
+IdempotencyMiddleware is a middleware for mutating APIs, so clients can safely retry a request,
+e.g. after a network timeout, sending the same `Idempotency-Key` header. The first response for a key
+of the authenticated user is stored and replayed for the retries with the same method, URL and payload,
+marked by the `Idempotent-Replayed` header. While the first request is in progress, or when the key is
+reused for a different request, a `409` error is returned. Server errors and transient client errors,
+i.e. `408`, `409` and `429`, are not stored, so the request can be retried. Keys expire after the TTL set on Init.
+
+It must be placed after the AuthMiddleware and the rate limit, and before the quotas, so replays
+do not consume quotas. Requests without the header, anonymous requests or requests received
+while the store cannot be reached are processed as they are.
+
+Example of usage of this middleware:
+router.PUT(
+
+	"/users/:userID",
+	bpauth.AuthMiddleware([]string{bpauth.UserUpdate}),
+	bpratelimit.RateLimitMiddleware("user-write"),
+	bpidempotency.IdempotencyMiddleware(),
+	... //other middlewares
+	func(ctx *gin.Context) {
+		... // your logic
+*/
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		idempotencyKey := ctx.GetHeader(IdempotencyKeyHeader)
+		authUser := bpauth.GetAuthUserFromSession(ctx)
+		if idempotencyKey == "" || authUser == nil {
+			ctx.Next()
+			return
+		}
+		if len(idempotencyKey) > maxKeyLength {
+			bprouter.ReturnError(ctx, bperr.ErrValidation.WithDetails(map[string][]string{IdempotencyKeyHeader: {"length-out-of-range"}}))
+			return
+		}
+		fingerprint, err := requestFingerprint(ctx)
+		if err != nil {
+			bprouter.ReturnError(ctx, err)
+			return
+		}
+		key := requestKey(fmt.Sprintf("user:%s", authUser.ID.String()), idempotencyKey)
+		stored, lock, err := store.reserve(ctx, key, fingerprint)
+		if err != nil {
+			zap.L().Error("Idempotency check failed", zap.String("service", "idempotency"), zap.Error(err))
+			ctx.Next()
+			return
+		}
+		if stored != nil {
+			switch {
+			case stored.Fingerprint != fingerprint:
+				bprouter.ReturnError(ctx, bperr.ErrIdempotencyMismatch)
+			case stored.inProgress():
+				bprouter.ReturnError(ctx, bperr.ErrRequestInProgress)
+			default:
+				replay(ctx, *stored)
+			}
+			return
+		}
+
+		completed := false
+		defer func() {
+			// Release the key when the request fails or panics, so it can be retried
+			if completed {
+				return
+			}
+			if err := store.release(ctx, key, lock); err != nil {
+				zap.L().Error("Idempotency key release failed", zap.String("service", "idempotency"), zap.Error(err))
+			}
+		}()
+		previousHeader := ctx.Writer.Header().Clone()
+		writer := &recordingWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		ctx.Next()
+		ctx.Writer = writer.ResponseWriter
+		if writer.Status() >= http.StatusInternalServerError || slices.Contains(transientStatuses, writer.Status()) {
+			return
+		}
+		response := storedRequest{
+			Fingerprint: fingerprint,
+			Status:      writer.Status(),
+			Header:      http.Header{},
+			Body:        writer.body.Bytes(),
+		}
+		// Headers set before this middleware, e.g. the rate limit ones, are not replayed
+		for name, values := range ctx.Writer.Header() {
+			if _, exists := previousHeader[name]; !exists {
+				response.Header[name] = values
+			}
+		}
+		if err := store.complete(ctx, key, lock, response); err != nil {
+			zap.L().Error("Idempotency response not stored", zap.String("service", "idempotency"), zap.Error(err))
+			return
+		}
+		completed = true
+	}
+}
+
+/*
+Hash the method, the URL and the payload of the request, so retries can be told apart from
+different requests reusing the same key.
+*/
+func requestFingerprint(ctx *gin.Context) (string, error) {
+	body, err := bprouter.ReadBody(ctx)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", ctx.Request.Method, ctx.Request.URL.RequestURI())
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+/*
+Return the stored response, keeping the headers already set by the previous middlewares.
+*/
+func replay(ctx *gin.Context, stored storedRequest) {
+	for name, values := range stored.Header {
+		if _, exists := ctx.Writer.Header()[name]; !exists {
+			ctx.Writer.Header()[name] = values
+		}
+	}
+	ctx.Header(ReplayedHeader, "true")
+	ctx.Writer.WriteHeader(stored.Status)
+	ctx.Writer.Write(stored.Body)
+	ctx.Abort()
+}
+
+/*
+RecordingWriter keeps a copy of the response written to the client, so it can be stored.
+*/
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
@@ -0,0 +1,129 @@
+package bpidempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var testUser = bpauth.AuthUser{ID: uuid.New(), Claims: []string{"item-c"}}
+
+/*
+Configure the package as Init does, on a Redis stand-in, serving a route that counts its calls
+and returns the given status. The bptest package cannot be used here since it depends on this package.
+*/
+func setupMiddlewareTest(t *testing.T, status *int) (*gin.Engine, *miniredis.Miniredis, *int) {
+	gin.SetMode(gin.TestMode)
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	store = idempotencyStore{client: client, ttl: time.Hour}
+	bpauth.SetAuthUserProvider(func(ctx *gin.Context) (bpauth.AuthUser, error) {
+		return testUser, nil
+	})
+	calls := 0
+	engine := gin.New()
+	engine.POST("/items", bpauth.AuthMiddleware([]string{"item-c"}), IdempotencyMiddleware(), func(ctx *gin.Context) {
+		calls++
+		ctx.Header("Location", "/items/1")
+		ctx.JSON(*status, gin.H{"calls": calls})
+	})
+	return engine, server, &calls
+}
+
+func idempotentRequest(engine *gin.Engine, key string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+	request.Header.Set(IdempotencyKeyHeader, key)
+	response := httptest.NewRecorder()
+	engine.ServeHTTP(response, request)
+	return response
+}
+
+func TestIdempotencyMiddlewareReplays(t *testing.T) {
+	status := http.StatusCreated
+	engine, _, calls := setupMiddlewareTest(t, &status)
+	first := idempotentRequest(engine, "key-1", `{"name": "item"}`)
+	second := idempotentRequest(engine, "key-1", `{"name": "item"}`)
+	if *calls != 1 {
+		t.Fatalf("expected the handler to be called once, got %d", *calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() || second.Header().Get("Location") != "/items/1" {
+		t.Errorf("expected the first response, got %d %s %v", second.Code, second.Body.String(), second.Header())
+	}
+	if first.Header().Get(ReplayedHeader) != "" || second.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("expected only the second response to be replayed")
+	}
+	if idempotentRequest(engine, "key-2", `{"name": "item"}`); *calls != 2 {
+		t.Errorf("expected a different key to be processed, got %d calls", *calls)
+	}
+}
+
+func TestIdempotencyMiddlewareConflicts(t *testing.T) {
+	status := http.StatusCreated
+	engine, _, calls := setupMiddlewareTest(t, &status)
+	idempotentRequest(engine, "key-1", `{"name": "item"}`)
+	response := idempotentRequest(engine, "key-1", `{"name": "other"}`)
+	if response.Code != http.StatusConflict || !strings.Contains(response.Body.String(), "idempotency-key-mismatch") {
+		t.Errorf("expected the mismatch error, got %d %s", response.Code, response.Body.String())
+	}
+	key := requestKey("user:"+testUser.ID.String(), "key-2")
+	if _, _, err := store.reserve(context.Background(), key, "fingerprint"); err != nil {
+		t.Fatal(err)
+	}
+	response = idempotentRequest(engine, "key-2", `{"name": "item"}`)
+	if response.Code != http.StatusConflict || !strings.Contains(response.Body.String(), "idempotency-key-mismatch") {
+		t.Errorf("expected the mismatch error for a different fingerprint, got %d %s", response.Code, response.Body.String())
+	}
+	request := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{"name": "item"}`))
+	fingerprint, _ := requestFingerprint(&gin.Context{Request: request})
+	key = requestKey("user:"+testUser.ID.String(), "key-3")
+	store.reserve(context.Background(), key, fingerprint)
+	response = idempotentRequest(engine, "key-3", `{"name": "item"}`)
+	if response.Code != http.StatusConflict || !strings.Contains(response.Body.String(), "idempotency-key-in-progress") {
+		t.Errorf("expected the in progress error, got %d %s", response.Code, response.Body.String())
+	}
+	if *calls != 1 {
+		t.Errorf("expected conflicting requests not to be processed, got %d calls", *calls)
+	}
+}
+
+func TestIdempotencyMiddlewareRetries(t *testing.T) {
+	status := http.StatusInternalServerError
+	engine, server, calls := setupMiddlewareTest(t, &status)
+	idempotentRequest(engine, "key-1", `{}`)
+	status = http.StatusCreated
+	if response := idempotentRequest(engine, "key-1", `{}`); response.Code != http.StatusCreated || *calls != 2 {
+		t.Errorf("expected server errors to be retried, got %d after %d calls", response.Code, *calls)
+	}
+	server.FastForward(time.Hour)
+	if idempotentRequest(engine, "key-1", `{}`); *calls != 3 {
+		t.Errorf("expected expired keys to be processed again, got %d calls", *calls)
+	}
+	if idempotentRequest(engine, "", `{}`); *calls != 4 {
+		t.Errorf("expected requests without key to be processed, got %d calls", *calls)
+	}
+}
+
+func TestIdempotencyMiddlewareRetriesTransientErrors(t *testing.T) {
+	for _, transient := range []int{http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests} {
+		status := transient
+		engine, _, calls := setupMiddlewareTest(t, &status)
+		idempotentRequest(engine, "key-1", `{}`)
+		status = http.StatusCreated
+		response := idempotentRequest(engine, "key-1", `{}`)
+		if response.Code != http.StatusCreated || response.Header().Get(ReplayedHeader) != "" || *calls != 2 {
+			t.Errorf("expected the %d response to be retried, got %d after %d calls", transient, response.Code, *calls)
+		}
+	}
+}
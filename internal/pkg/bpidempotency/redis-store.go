@@ -0,0 +1,127 @@
+package bpidempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+Requests in progress keep their key locked for at most this time, so a crashed instance
+does not block the retries of the client until the key expires.
+*/
+const lockTimeout = time.Minute
+
+/*
+Lock the key, unless it already exists. KEYS is the key of the request.
+ARGV are the lock and its expiration in milliseconds.
+It returns the current value of the key, or false if the key has been locked.
+*/
+var reserveScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	return current
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return false
+`)
+
+/*
+Replace the lock with the response, if the key is still locked by the same request.
+KEYS is the key of the request. ARGV are the lock, the response and its expiration in milliseconds.
+*/
+var completeScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+/*
+Delete the key, if it is still locked by the same request. KEYS is the key of the request. ARGV is the lock.
+*/
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+return redis.call('DEL', KEYS[1])
+`)
+
+/*
+StoredRequest represents the value of a key: the lock of a request in progress, identified by a random token,
+or the response of a completed request.
+*/
+type storedRequest struct {
+	Fingerprint string      `json:"fingerprint"`
+	Token       string      `json:"token,omitempty"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+func (r storedRequest) inProgress() bool {
+	return r.Token != ""
+}
+
+/*
+IdempotencyStore represents the store of the requests, based on Redis.
+*/
+type idempotencyStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+/*
+Build the key of a request of a subject, e.g. `idempotency:user:<uuid>:<idempotency key>`.
+*/
+func requestKey(subject string, idempotencyKey string) string {
+	return fmt.Sprintf("idempotency:%s:%s", subject, idempotencyKey)
+}
+
+/*
+Lock the key for the request with the given fingerprint. If the key is already used,
+the stored request is returned instead, otherwise the lock to complete or release the key.
+*/
+func (s idempotencyStore) reserve(ctx context.Context, key string, fingerprint string) (*storedRequest, string, error) {
+	lock, err := json.Marshal(storedRequest{Fingerprint: fingerprint, Token: uuid.NewString()})
+	if err != nil {
+		return nil, "", err
+	}
+	current, err := reserveScript.Run(ctx, s.client, []string{key}, lock, min(lockTimeout, s.ttl).Milliseconds()).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, string(lock), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	stored := &storedRequest{}
+	if err := json.Unmarshal([]byte(current), stored); err != nil {
+		return nil, "", err
+	}
+	return stored, "", nil
+}
+
+/*
+Store the response of the request, replacing its lock. The key expires after the TTL.
+*/
+func (s idempotencyStore) complete(ctx context.Context, key string, lock string, response storedRequest) error {
+	value, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return completeScript.Run(ctx, s.client, []string{key}, lock, value, s.ttl.Milliseconds()).Err()
+}
+
+/*
+Release the lock of the request, so it can be retried.
+*/
+func (s idempotencyStore) release(ctx context.Context, key string, lock string) error {
+	return releaseScript.Run(ctx, s.client, []string{key}, lock).Err()
+}
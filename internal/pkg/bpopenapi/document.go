@@ -0,0 +1,154 @@
+package bpopenapi
+
+import (
+	"encoding/json"
+)
+
+/*
+Version of the OpenAPI specification the documents are generated for.
+*/
+const openAPIVersion = "3.1.0"
+
+/*
+Name of the security scheme authenticating the users via the JWT in the Authorization header.
+*/
+const bearerAuth = "bearerAuth"
+
+/*
+Document represents an OpenAPI document, limited to the objects generated from the described routes.
+Maps are encoded with sorted keys, so the same routes always generate the same document.
+*/
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+/*
+Info represents the metadata of the API.
+*/
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+/*
+PathItem represents the operations available on a path, by lowercase HTTP method.
+*/
+type PathItem map[string]*Operation
+
+/*
+Components represents the reusable objects of the document.
+*/
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+/*
+SecurityScheme represents a way to authenticate the requests.
+*/
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+/*
+Operation represents a single API on a path. Security lists the claims required
+by the AuthMiddleware as roles of the bearer scheme.
+*/
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+/*
+Parameter represents a path, query or header parameter of an operation.
+*/
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Style       string  `json:"style,omitempty"`
+	Explode     *bool   `json:"explode,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+/*
+RequestBody represents the payload of an operation, by content type.
+*/
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+/*
+Response represents a response of an operation, by content type.
+*/
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+/*
+MediaType represents the schema of a payload.
+*/
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+/*
+Schema represents the subset of JSON Schema used to describe the DTOs. Nullable values
+list the `null` type together with their own type, as defined by OpenAPI 3.1.
+*/
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 SchemaType         `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+/*
+SchemaType represents the types a value can have. A single type is encoded as a string,
+multiple types as a list of strings.
+*/
+type SchemaType []string
+
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *SchemaType) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = SchemaType{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*t = SchemaType(multiple)
+	return nil
+}
@@ -0,0 +1,49 @@
+package bpopenapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+	"go.uber.org/zap"
+)
+
+const documentTitle = "Blueprint API"
+const documentDescription = "Errors are returned as `application/problem+json` (RFC 7807) with a stable `code`."
+const documentVersion = "1.0.0"
+
+/*
+Page rendering the OpenAPI document via Swagger UI, whose assets are embedded in the binary
+and served at `/docs/assets`, so the docs work without reaching any CDN.
+*/
+//go:embed docs.html
+var docsPage []byte
+
+/*
+Init generates the OpenAPI document of the routes registered so far, so it must be called
+after all the modules have been initialized. The document is served at `/openapi.json`,
+while `/docs` renders it for humans.
+*/
+func Init(router gin.IRoutes) {
+	zap.L().Info("Generating OpenAPI document...", zap.String("service", "openapi"))
+	document, err := Generate()
+	if err != nil {
+		zap.L().Error("Error generating OpenAPI document", zap.String("service", "openapi"), zap.Error(err))
+		panic(err)
+	}
+	content, err := json.Marshal(document)
+	if err != nil {
+		zap.L().Error("Error encoding OpenAPI document", zap.String("service", "openapi"), zap.Error(err))
+		panic(err)
+	}
+	router.GET("/openapi.json", func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "application/json", content)
+	})
+	router.GET("/docs", func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+	})
+	router.StaticFS("/docs/assets", http.FS(swaggerFiles.FS))
+	zap.L().Info("OpenAPI document generated!", zap.String("service", "openapi"), zap.Int("paths", len(document.Paths)))
+}
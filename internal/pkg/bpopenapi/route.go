@@ -0,0 +1,380 @@
+package bpopenapi
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"github.com/besasch88/blueprint/internal/pkg/bpfilter"
+	"github.com/besasch88/blueprint/internal/pkg/bpidempotency"
+	"github.com/besasch88/blueprint/internal/pkg/bprouter"
+	"github.com/besasch88/blueprint/internal/pkg/bputils"
+	"github.com/gin-gonic/gin"
+)
+
+/*
+Route describes an API registered via Handle, so it can be documented in the OpenAPI document.
+Request is the DTO bound via bprouter.BindParameters: fields with the `uri`, `form` and `header` tags
+are documented as parameters, while the `json` ones as the payload of POST, PUT and PATCH requests.
+Enums lists the values of the Request parameters, by name, e.g. the lists validated via validation.In,
+so the documented values cannot diverge from the accepted ones.
+Response is the DTO returned as `item`, or a slice of DTOs returned as `items`, with the `meta` DTOs, if any.
+Lists accepting different kinds of pagination can return different metas.
+The errors of the authentication, the binding and the validation are documented automatically,
+so Errors lists only the ones specific to the route. Idempotent documents the `Idempotency-Key` header
+of the routes using the bpidempotency.IdempotencyMiddleware.
+*/
+type Route struct {
+	OperationID string
+	Summary     string
+	Description string
+	Tags        []string
+	Claims      []string
+	Request     any
+	Enums       map[string][]interface{}
+	Status      int
+	Response    any
+	Meta        []any
+	Filters     bpfilter.Fields
+	Expanders   bprouter.Expanders
+	Idempotent  bool
+	Errors      []*bperr.Error
+}
+
+type describedRoute struct {
+	method string
+	path   string
+	route  Route
+}
+
+/*
+Routes described so far, by method and path. Routes registered again,
+e.g. by engines created in tests, replace the previous description.
+*/
+var routes = map[string]describedRoute{}
+var routesMu sync.Mutex
+
+var pathParamRegex = regexp.MustCompile(`[:*](\w+)`)
+
+/*
+Handle registers the handlers of a route together with its description. If the route requires claims,
+the AuthMiddleware checking them is added as the first handler, so the documented claims
+are always the ones required by the API. E.g.
+
+	bpopenapi.Handle(router, http.MethodGet, "/users/:userID", bpopenapi.Route{
+		Summary:  "Get a user",
+		Claims:   []string{bpauth.UserGet},
+		Request:  getUserInputDto{},
+		Response: userOutputDto{},
+		Errors:   []*bperr.Error{errUserNotFound},
+	},
+		bptimeout.TimeoutMiddleware(time.Duration(1)*time.Second),
+		... //other middlewares
+		func(ctx *gin.Context) {
+			... // your logic
+*/
+func Handle(router *gin.RouterGroup, method string, relativePath string, route Route, handlers ...gin.HandlerFunc) {
+	if route.Claims != nil {
+		handlers = append([]gin.HandlerFunc{bpauth.AuthMiddleware(route.Claims)}, handlers...)
+	}
+	router.Handle(method, relativePath, handlers...)
+	fullPath := path.Join(router.BasePath(), relativePath)
+	routesMu.Lock()
+	defer routesMu.Unlock()
+	routes[method+" "+fullPath] = describedRoute{method: method, path: fullPath, route: route}
+}
+
+/*
+Generate the OpenAPI document of all the routes registered via Handle.
+*/
+func Generate() (*Document, error) {
+	routesMu.Lock()
+	defer routesMu.Unlock()
+	registry := newSchemaRegistry()
+	document := &Document{
+		OpenAPI: openAPIVersion,
+		Info: Info{
+			Title:       documentTitle,
+			Description: documentDescription,
+			Version:     documentVersion,
+		},
+		Paths: map[string]PathItem{},
+		Components: Components{
+			Schemas: registry.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerAuth: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "The claims listed by each operation are required to call it",
+				},
+			},
+		},
+	}
+	keys := []string{}
+	for key := range routes {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		described := routes[key]
+		operation, err := newOperation(registry, described)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		openAPIPath := pathParamRegex.ReplaceAllString(described.path, "{$1}")
+		if document.Paths[openAPIPath] == nil {
+			document.Paths[openAPIPath] = PathItem{}
+		}
+		document.Paths[openAPIPath][strings.ToLower(described.method)] = operation
+	}
+	return document, nil
+}
+
+func newOperation(registry schemaRegistry, described describedRoute) (*Operation, error) {
+	route := described.route
+	operation := &Operation{
+		OperationID: route.OperationID,
+		Summary:     route.Summary,
+		Description: route.Description,
+		Tags:        route.Tags,
+		Responses:   map[string]Response{},
+	}
+	if operation.OperationID == "" {
+		operation.OperationID = operationID(described.method, described.path)
+	}
+	errs := []*bperr.Error{}
+	if route.Claims != nil {
+		operation.Security = []map[string][]string{{bearerAuth: route.Claims}}
+		operation.Description = strings.TrimSpace(fmt.Sprintf("%s\n\nRequired claims: `%s`.", route.Description, strings.Join(route.Claims, "`, `")))
+		errs = append(errs, bperr.ErrUnauthorized, bperr.ErrForbidden)
+	}
+	if route.Request != nil {
+		requestType := indirect(reflect.TypeOf(route.Request))
+		parameters, err := registry.parametersOf(requestType)
+		if err != nil {
+			return nil, err
+		}
+		for _, parameter := range parameters {
+			if values, ok := route.Enums[parameter.Name]; ok {
+				parameter.Schema.Enum = bputils.TransformToStrings(values)
+			}
+		}
+		operation.Parameters = append(operation.Parameters, parameters...)
+		if slices.Contains([]string{http.MethodPost, http.MethodPut, http.MethodPatch}, described.method) && hasBody(requestType) {
+			schema, err := registry.refOf(requestType)
+			if err != nil {
+				return nil, err
+			}
+			operation.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{"application/json": {Schema: schema}},
+			}
+			errs = append(errs, bperr.ErrBadRequest, bperr.ErrRequestTooLarge)
+		}
+		errs = append(errs, bperr.ErrValidation)
+	}
+	if route.Filters != nil {
+		operation.Parameters = append(operation.Parameters, filterParameter(route.Filters))
+		errs = append(errs, bperr.ErrValidation)
+	}
+	if route.Expanders != nil {
+		operation.Parameters = append(operation.Parameters, shapeParameters(route.Expanders)...)
+		errs = append(errs, bperr.ErrValidation)
+	}
+	if route.Idempotent {
+		operation.Parameters = append(operation.Parameters, Parameter{
+			Name:        bpidempotency.IdempotencyKeyHeader,
+			In:          "header",
+			Description: "Key identifying the request and its retries. Retries get the response of the first request.",
+			Schema:      &Schema{Type: SchemaType{"string"}},
+		})
+		errs = append(errs, bperr.ErrValidation, bperr.ErrRequestInProgress, bperr.ErrIdempotencyMismatch)
+	}
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success, err := successResponse(registry, route.Response, route.Meta, status)
+	if err != nil {
+		return nil, err
+	}
+	operation.Responses[fmt.Sprint(status)] = success
+	errs = append(errs, route.Errors...)
+	errs = append(errs, bperr.ErrGeneric)
+	if err := addErrorResponses(registry, operation, errs); err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+/*
+Return the envelope shared by all the APIs: `item` for a single resource, `items` for a slice of resources,
+together with their `meta` if any.
+*/
+func successResponse(registry schemaRegistry, dto any, metaDtos []any, status int) (Response, error) {
+	response := Response{Description: http.StatusText(status)}
+	if dto == nil {
+		return response, nil
+	}
+	schema, err := registry.schemaOf(reflect.TypeOf(dto))
+	if err != nil {
+		return Response{}, err
+	}
+	envelope := &Schema{
+		Type:       SchemaType{"object"},
+		Properties: map[string]*Schema{"item": schema},
+		Required:   []string{"item"},
+	}
+	if reflect.TypeOf(dto).Kind() == reflect.Slice {
+		envelope = &Schema{
+			Type:       SchemaType{"object"},
+			Properties: map[string]*Schema{"items": schema},
+			Required:   []string{"items"},
+		}
+	}
+	if len(metaDtos) > 0 {
+		metas := []*Schema{}
+		for _, meta := range metaDtos {
+			metaSchema, err := registry.schemaOf(reflect.TypeOf(meta))
+			if err != nil {
+				return Response{}, err
+			}
+			metas = append(metas, metaSchema)
+		}
+		envelope.Properties["meta"] = metas[0]
+		if len(metas) > 1 {
+			envelope.Properties["meta"] = &Schema{OneOf: metas}
+		}
+		envelope.Required = append(envelope.Required, "meta")
+	}
+	response.Content = map[string]MediaType{"application/json": {Schema: envelope}}
+	return response, nil
+}
+
+/*
+Add a response for each status of the given errors, listing the codes that can be returned.
+*/
+func addErrorResponses(registry schemaRegistry, operation *Operation, errs []*bperr.Error) error {
+	problem, err := registry.refOf(reflect.TypeOf(bprouter.Problem{}))
+	if err != nil {
+		return err
+	}
+	codes := map[int][]interface{}{}
+	statuses := []int{}
+	for _, e := range errs {
+		if _, exists := codes[e.Status]; !exists {
+			statuses = append(statuses, e.Status)
+		}
+		if !slices.Contains(codes[e.Status], interface{}(e.Code)) {
+			codes[e.Status] = append(codes[e.Status], e.Code)
+		}
+	}
+	for _, status := range statuses {
+		operation.Responses[fmt.Sprint(status)] = Response{
+			Description: http.StatusText(status),
+			Content: map[string]MediaType{
+				bprouter.ProblemContentType: {Schema: &Schema{AllOf: []*Schema{
+					problem,
+					{Properties: map[string]*Schema{"code": {Enum: codes[status]}}},
+				}}},
+			},
+		}
+	}
+	return nil
+}
+
+/*
+Describe the `filter[field][operator]` Query params as a deep object, by field and operator.
+*/
+func filterParameter(fields bpfilter.Fields) Parameter {
+	explode := true
+	schema := &Schema{Type: SchemaType{"object"}, Properties: map[string]*Schema{}}
+	for name, field := range fields {
+		operators := &Schema{Type: SchemaType{"object"}, Properties: map[string]*Schema{}}
+		for _, operator := range field.Operators {
+			operators.Properties[string(operator)] = filterValueSchema(field.Type, operator)
+		}
+		schema.Properties[name] = operators
+	}
+	return Parameter{
+		Name:        "filter",
+		In:          "query",
+		Description: "Filters in the form `filter[field][operator]=value`, where the operator can be omitted for equality.",
+		Style:       "deepObject",
+		Explode:     &explode,
+		Schema:      schema,
+	}
+}
+
+func filterValueSchema(fieldType bpfilter.Type, operator bpfilter.Operator) *Schema {
+	switch {
+	case operator == bpfilter.IsNull:
+		return &Schema{Type: SchemaType{"boolean"}}
+	case operator == bpfilter.In:
+		return &Schema{Type: SchemaType{"string"}, Description: "Comma separated values"}
+	case fieldType == bpfilter.Number:
+		return &Schema{Type: SchemaType{"number"}}
+	case fieldType == bpfilter.Bool:
+		return &Schema{Type: SchemaType{"boolean"}}
+	case fieldType == bpfilter.Time:
+		return &Schema{Type: SchemaType{"string"}, Format: "date-time"}
+	case fieldType == bpfilter.UUID:
+		return &Schema{Type: SchemaType{"string"}, Format: "uuid"}
+	default:
+		return &Schema{Type: SchemaType{"string"}}
+	}
+}
+
+/*
+Describe the `fields` and `expand` Query params read by bprouter.ShapeMiddleware.
+*/
+func shapeParameters(expanders bprouter.Expanders) []Parameter {
+	explode := false
+	expansions := []interface{}{}
+	for name := range expanders {
+		expansions = append(expansions, name)
+	}
+	slices.SortFunc(expansions, func(a, b interface{}) int {
+		return strings.Compare(a.(string), b.(string))
+	})
+	return []Parameter{
+		{
+			Name:        "fields",
+			In:          "query",
+			Description: "Fields to return, with nested fields separated by dots",
+			Explode:     &explode,
+			Schema:      &Schema{Type: SchemaType{"array"}, Items: &Schema{Type: SchemaType{"string"}}},
+		},
+		{
+			Name:        "expand",
+			In:          "query",
+			Description: "Related resources to expand",
+			Explode:     &explode,
+			Schema:      &Schema{Type: SchemaType{"array"}, Items: &Schema{Type: SchemaType{"string"}, Enum: expansions}},
+		},
+	}
+}
+
+/*
+Derive the operation ID from the method and the path, e.g. `getApiV1UsersUserID`.
+*/
+func operationID(method string, fullPath string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(fullPath, "/") {
+		segment = strings.TrimLeft(segment, ":*")
+		for _, part := range strings.FieldsFunc(segment, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		}) {
+			id += capitalize(part)
+		}
+	}
+	return id
+}
@@ -0,0 +1,191 @@
+package bpopenapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"github.com/besasch88/blueprint/internal/pkg/bpfilter"
+	"github.com/besasch88/blueprint/internal/pkg/bprouter"
+	"github.com/gin-gonic/gin"
+)
+
+type testArticleInputDto struct {
+	ID      string   `uri:"articleID" json:"-" openapi:"format=uuid"`
+	Lang    string   `header:"Accept-Language" json:"-"`
+	Title   string   `json:"title"`
+	Summary *string  `json:"summary"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+type testListArticlesInputDto struct {
+	PageSize int    `form:"pageSize" json:"pageSize"`
+	OrderBy  string `form:"orderBy" json:"orderBy" openapi:"enum=title|createdAt"`
+	OrderDir string `form:"orderDir" json:"orderDir"`
+}
+
+type testArticleOutputDto struct {
+	ID        string                `json:"id"`
+	Title     string                `json:"title"`
+	Summary   *string               `json:"summary"`
+	CreatedAt time.Time             `json:"createdAt"`
+	Parent    *testArticleOutputDto `json:"parent"`
+}
+
+type testOrderDir string
+
+const (
+	testAsc  testOrderDir = "asc"
+	testDesc testOrderDir = "desc"
+)
+
+var errTestArticleNotFound = bperr.New(http.StatusNotFound, "article-not-found", "The article does not exist")
+
+func newTestDocument(t *testing.T) *Document {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	routes = map[string]describedRoute{}
+	engine := gin.New()
+	group := engine.Group("api/v1")
+	Handle(group, http.MethodGet, "/articles", Route{
+		Summary:  "List articles",
+		Claims:   []string{"article-l"},
+		Request:  testListArticlesInputDto{},
+		Enums:    map[string][]interface{}{"orderDir": {testAsc, testDesc}},
+		Response: []testArticleOutputDto{},
+		Meta:     []any{bprouter.CursorPageMeta{}, bprouter.OffsetPageMeta{}},
+		Filters: bpfilter.Fields{
+			"title": {Column: "title", Type: bpfilter.String, Operators: []bpfilter.Operator{bpfilter.Eq, bpfilter.In}},
+		},
+		Expanders: bprouter.Expanders{"parent": {KeyField: "parent"}},
+	}, func(ctx *gin.Context) {})
+	Handle(group, http.MethodPut, "/articles/:articleID", Route{
+		OperationID: "updateArticle",
+		Request:     testArticleInputDto{},
+		Response:    testArticleOutputDto{},
+		Errors:      []*bperr.Error{errTestArticleNotFound, bperr.ErrNotFound},
+	}, func(ctx *gin.Context) {})
+	document, err := Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return document
+}
+
+func TestGenerateParameters(t *testing.T) {
+	document := newTestDocument(t)
+	list := document.Paths["/api/v1/articles"]["get"]
+	if list == nil || list.OperationID != "getApiV1Articles" || list.RequestBody != nil {
+		t.Fatalf("expected the list operation without payload, got %+v", list)
+	}
+	names := []string{}
+	for _, parameter := range list.Parameters {
+		names = append(names, parameter.In+":"+parameter.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"query:pageSize", "query:orderBy", "query:orderDir", "query:filter", "query:fields", "query:expand"}) {
+		t.Errorf("unexpected parameters %v", names)
+	}
+	if !reflect.DeepEqual(list.Parameters[1].Schema.Enum, []interface{}{"title", "createdAt"}) {
+		t.Errorf("expected the enum from the openapi tag, got %v", list.Parameters[1].Schema.Enum)
+	}
+	if !reflect.DeepEqual(list.Parameters[2].Schema.Enum, []interface{}{"asc", "desc"}) {
+		t.Errorf("expected the enum from the route, got %v", list.Parameters[2].Schema.Enum)
+	}
+	update := document.Paths["/api/v1/articles/{articleID}"]["put"]
+	if update == nil || len(update.Parameters) != 2 {
+		t.Fatalf("expected the update operation with path and header parameters, got %+v", update)
+	}
+	if p := update.Parameters[0]; p.In != "path" || p.Name != "articleID" || !p.Required || p.Schema.Format != "uuid" {
+		t.Errorf("unexpected path parameter %+v", p)
+	}
+	if p := update.Parameters[1]; p.In != "header" || p.Name != "Accept-Language" || p.Required {
+		t.Errorf("unexpected header parameter %+v", p)
+	}
+}
+
+func TestGenerateSchemas(t *testing.T) {
+	document := newTestDocument(t)
+	update := document.Paths["/api/v1/articles/{articleID}"]["put"]
+	if update.RequestBody == nil || update.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/TestArticleInput" {
+		t.Fatalf("expected the payload referring to the input component, got %+v", update.RequestBody)
+	}
+	input := document.Components.Schemas["TestArticleInput"]
+	if len(input.Properties) != 3 || !reflect.DeepEqual(input.Required, []string{"title"}) {
+		t.Errorf("expected only the JSON fields, with optional pointers and omitted fields, got %+v", input)
+	}
+	if !reflect.DeepEqual(input.Properties["summary"].Type, SchemaType{"string", "null"}) {
+		t.Errorf("expected a nullable summary, got %v", input.Properties["summary"].Type)
+	}
+	output := document.Components.Schemas["TestArticleOutput"]
+	if output.Properties["createdAt"].Format != "date-time" || len(output.Properties["parent"].OneOf) != 2 {
+		t.Errorf("unexpected output component %+v", output)
+	}
+	envelope := document.Paths["/api/v1/articles"]["get"].Responses["200"].Content["application/json"].Schema
+	if envelope.Properties["items"].Items.Ref != "#/components/schemas/TestArticleOutput" || len(envelope.Properties["meta"].OneOf) != 2 {
+		t.Errorf("expected the list envelope, got %+v", envelope)
+	}
+}
+
+func TestGenerateSecurityAndErrors(t *testing.T) {
+	document := newTestDocument(t)
+	list := document.Paths["/api/v1/articles"]["get"]
+	if !reflect.DeepEqual(list.Security, []map[string][]string{{bearerAuth: {"article-l"}}}) {
+		t.Errorf("expected the required claims, got %v", list.Security)
+	}
+	for _, status := range []string{"401", "403", "422", "500"} {
+		if _, exists := list.Responses[status]; !exists {
+			t.Errorf("expected the %s response", status)
+		}
+	}
+	update := document.Paths["/api/v1/articles/{articleID}"]["put"]
+	if update.Security != nil {
+		t.Errorf("expected no security, got %v", update.Security)
+	}
+	notFound := update.Responses["404"].Content[bprouter.ProblemContentType].Schema.AllOf[1].Properties["code"].Enum
+	if !reflect.DeepEqual(notFound, []interface{}{"article-not-found", "not-found"}) {
+		t.Errorf("expected the codes of the status, got %v", notFound)
+	}
+}
+
+func TestGenerateIsStable(t *testing.T) {
+	first, err := json.Marshal(newTestDocument(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := json.Marshal(newTestDocument(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(first) != string(second) {
+		t.Error("expected the same document for the same routes")
+	}
+}
+
+func TestInitServesDocument(t *testing.T) {
+	newTestDocument(t)
+	engine := gin.New()
+	Init(engine)
+	response := httptest.NewRecorder()
+	engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var document Document
+	if err := json.Unmarshal(response.Body.Bytes(), &document); err != nil || response.Code != http.StatusOK {
+		t.Fatalf("expected the document, got %d %s", response.Code, response.Body.String())
+	}
+	if document.OpenAPI != "3.1.0" || len(document.Paths) != 2 {
+		t.Errorf("unexpected document %+v", document)
+	}
+	response = httptest.NewRecorder()
+	engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if response.Code != http.StatusOK || response.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("expected the docs page, got %d", response.Code)
+	}
+	response = httptest.NewRecorder()
+	engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/docs/assets/swagger-ui-bundle.js", nil))
+	if response.Code != http.StatusOK || response.Body.Len() == 0 {
+		t.Errorf("expected the embedded docs assets, got %d", response.Code)
+	}
+}
@@ -0,0 +1,305 @@
+package bpopenapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+var uuidType = reflect.TypeOf(uuid.UUID{})
+var numberType = reflect.TypeOf(json.Number(""))
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+/*
+SchemaRegistry converts Go types into schemas, collecting named structs as reusable components.
+*/
+type schemaRegistry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() schemaRegistry {
+	return schemaRegistry{
+		schemas: map[string]*Schema{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+/*
+Return the schema of the given type. Pointers are nullable, while named structs
+are referenced as components, so recursive types are supported.
+*/
+func (r schemaRegistry) schemaOf(t reflect.Type) (*Schema, error) {
+	if t.Kind() != reflect.Pointer {
+		return r.nonNullSchemaOf(t)
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	schema, err := r.nonNullSchemaOf(t)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case schema.Ref != "":
+		return &Schema{OneOf: []*Schema{schema, {Type: SchemaType{"null"}}}}, nil
+	case len(schema.Type) == 0:
+		// Any value, null included
+		return schema, nil
+	default:
+		schema.Type = append(schema.Type, "null")
+		return schema, nil
+	}
+}
+
+func (r schemaRegistry) nonNullSchemaOf(t reflect.Type) (*Schema, error) {
+	switch t {
+	case timeType:
+		return &Schema{Type: SchemaType{"string"}, Format: "date-time"}, nil
+	case uuidType:
+		return &Schema{Type: SchemaType{"string"}, Format: "uuid"}, nil
+	case numberType:
+		return &Schema{Type: SchemaType{"number"}}, nil
+	case rawMessageType:
+		return &Schema{}, nil
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return &Schema{Type: SchemaType{"string"}}, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: SchemaType{"boolean"}}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: SchemaType{"integer"}, Format: "int32"}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: SchemaType{"integer"}, Format: "int64"}, nil
+	case reflect.Float32:
+		return &Schema{Type: SchemaType{"number"}, Format: "float"}, nil
+	case reflect.Float64:
+		return &Schema{Type: SchemaType{"number"}, Format: "double"}, nil
+	case reflect.String:
+		return &Schema{Type: SchemaType{"string"}}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: SchemaType{"string"}, Format: "byte"}, nil
+		}
+		items, err := r.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: SchemaType{"array"}, Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String && !t.Key().Implements(textMarshalerType) {
+			return nil, fmt.Errorf("unsupported map key of type %s", t)
+		}
+		values, err := r.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: SchemaType{"object"}, AdditionalProperties: values}, nil
+	case reflect.Struct:
+		return r.refOf(t)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+/*
+Return the reference to the component of a named struct, registering it on first use.
+Anonymous structs are returned inline.
+*/
+func (r schemaRegistry) refOf(t reflect.Type) (*Schema, error) {
+	if t.Name() == "" {
+		return r.objectOf(t)
+	}
+	name, exists := r.names[t]
+	if !exists {
+		name = r.componentName(t)
+		r.names[t] = name
+		// Registered before its properties, so recursive references find it
+		r.schemas[name] = &Schema{}
+		object, err := r.objectOf(t)
+		if err != nil {
+			return nil, err
+		}
+		*r.schemas[name] = *object
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}, nil
+}
+
+/*
+Name the component after the type, dropping the `Dto` suffix, e.g. `userOutputDto` becomes `UserOutput`.
+Types with the same name in different packages are prefixed with their package name.
+*/
+func (r schemaRegistry) componentName(t reflect.Type) string {
+	name := capitalize(strings.TrimSuffix(t.Name(), "Dto"))
+	if _, taken := r.schemas[name]; taken {
+		pkg := t.PkgPath()
+		name = capitalize(pkg[strings.LastIndex(pkg, "/")+1:]) + name
+	}
+	return name
+}
+
+/*
+Return the object schema of a struct following its `json` tags, as encoded by encoding/json.
+Fields are required unless they are pointers or omitted when empty.
+*/
+func (r schemaRegistry) objectOf(t reflect.Type) (*Schema, error) {
+	object := &Schema{Type: SchemaType{"object"}, Properties: map[string]*Schema{}}
+	if err := r.addProperties(object, t); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func (r schemaRegistry) addProperties(object *Schema, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		// Fields of embedded structs are promoted
+		if field.Anonymous && name == "" && indirect(field.Type).Kind() == reflect.Struct {
+			if err := r.addProperties(object, indirect(field.Type)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema, err := r.fieldSchemaOf(field)
+		if err != nil {
+			return err
+		}
+		object.Properties[name] = schema
+		if field.Type.Kind() != reflect.Pointer && !slices.Contains(strings.Split(options, ","), "omitempty") {
+			object.Required = append(object.Required, name)
+		}
+	}
+	return nil
+}
+
+/*
+Return the schema of a struct field, enriched via its `openapi` tag. E.g.
+
+	ID      string `json:"id" openapi:"format=uuid"`
+	OrderBy string `form:"orderBy" openapi:"enum=firstname|lastname"`
+*/
+func (r schemaRegistry) fieldSchemaOf(field reflect.StructField) (*Schema, error) {
+	schema, err := r.schemaOf(field.Type)
+	if err != nil {
+		return nil, err
+	}
+	target := schema
+	if target.Items != nil {
+		target = target.Items
+	}
+	if target.Ref != "" {
+		return schema, nil
+	}
+	for _, option := range strings.Split(field.Tag.Get("openapi"), ",") {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "format":
+			target.Format = value
+		case "enum":
+			for _, item := range strings.Split(value, "|") {
+				target.Enum = append(target.Enum, item)
+			}
+		}
+	}
+	return schema, nil
+}
+
+/*
+Return the parameters bound via the `uri`, `form` and `header` tags of the exported fields.
+Path parameters are always required, while the others are optional.
+*/
+func (r schemaRegistry) parametersOf(t reflect.Type) ([]Parameter, error) {
+	parameters := []Parameter{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.IsExported() && field.Type.Kind() == reflect.Struct {
+			embedded, err := r.parametersOf(field.Type)
+			if err != nil {
+				return nil, err
+			}
+			parameters = append(parameters, embedded...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		for _, location := range []struct{ tag, in string }{{"uri", "path"}, {"form", "query"}, {"header", "header"}} {
+			name, _, _ := strings.Cut(field.Tag.Get(location.tag), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			// Missing parameters are not bound, so they are not nullable
+			field.Type = indirect(field.Type)
+			schema, err := r.fieldSchemaOf(field)
+			if err != nil {
+				return nil, err
+			}
+			parameters = append(parameters, Parameter{
+				Name:     name,
+				In:       location.in,
+				Required: location.in == "path",
+				Schema:   schema,
+			})
+		}
+	}
+	return parameters, nil
+}
+
+/*
+Return true if the struct has at least a field decoded from the JSON payload.
+*/
+func hasBody(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && indirect(field.Type).Kind() == reflect.Struct {
+			if hasBody(indirect(field.Type)) {
+				return true
+			}
+			continue
+		}
+		if field.IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func capitalize(value string) string {
+	if value == "" {
+		return value
+	}
+	return strings.ToUpper(value[:1]) + value[1:]
+}
@@ -0,0 +1,259 @@
+package bpopenapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"github.com/besasch88/blueprint/internal/pkg/bprouter"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var errInvalidResponse = bperr.New(http.StatusInternalServerError, "invalid-response", "The response does not match the API specification")
+
+/*
+ValidationMiddleware validates the requests against the OpenAPI document loaded from the given file,
+e.g. the one written by the `openapi` command and checked in, so handlers cannot drift from what
+clients have been promised. Path, Query and Header parameters and the JSON payload are validated,
+returning a validation error with the codes of the errors by parameter or payload field.
+
+When responses are validated too, they are buffered and replaced by an error if they do not match
+the document. It is meant for the debug mode, since buffering and validating responses has a cost.
+Routes not described by the document are not validated. It must be applied before registering
+the routes, e.g. to the API group:
+
+	v1Api := r.Group("api/v1")
+	v1Api.Use(bpopenapi.ValidationMiddleware("./api/openapi.json", envs.AppMode == bpenv.DebugMode))
+*/
+func ValidationMiddleware(openAPIFile string, validateResponses bool) gin.HandlerFunc {
+	document, err := loadDocument(openAPIFile)
+	if err != nil {
+		zap.L().Error("Error loading OpenAPI document", zap.String("service", "openapi"), zap.Error(err))
+		panic(err)
+	}
+	validator := newSchemaValidator(document)
+	return func(ctx *gin.Context) {
+		operation := findOperation(document, ctx.Request.Method, ctx.FullPath())
+		if operation == nil {
+			zap.L().Warn(fmt.Sprintf("Route %s %s not documented. Validation skipped", ctx.Request.Method, ctx.FullPath()), zap.String("service", "openapi"))
+			ctx.Next()
+			return
+		}
+		if err := validateRequest(ctx, validator, operation); err != nil {
+			bprouter.ReturnError(ctx, err)
+			return
+		}
+		if !validateResponses {
+			ctx.Next()
+			return
+		}
+		writer := bprouter.NewResponseBuffer(ctx.Writer)
+		ctx.Writer = writer
+		ctx.Next()
+		ctx.Writer = writer.ResponseWriter
+		// Shaped responses follow the fields and expansions requested by the client, instead of the DTOs
+		if bprouter.IsResponseShaped(ctx) {
+			writer.Send(nil)
+			return
+		}
+		if err := validateResponse(validator, operation, writer); err != nil {
+			zap.L().Error(fmt.Sprintf("Response of %s %s not matching the OpenAPI document", ctx.Request.Method, ctx.FullPath()), zap.String("service", "openapi"), zap.Error(err))
+			ctx.Writer.Header().Del("ETag")
+			bprouter.ReturnError(ctx, err)
+			return
+		}
+		writer.Send(nil)
+	}
+}
+
+/*
+Load the OpenAPI document from a JSON file.
+*/
+func loadDocument(openAPIFile string) (*Document, error) {
+	content, err := os.ReadFile(openAPIFile)
+	if err != nil {
+		return nil, err
+	}
+	document := &Document{}
+	if err := json.Unmarshal(content, document); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document %s: %w", openAPIFile, err)
+	}
+	return document, nil
+}
+
+/*
+Find the operation of the route, converting its GIN path into the OpenAPI one.
+*/
+func findOperation(document *Document, method string, fullPath string) *Operation {
+	if fullPath == "" {
+		return nil
+	}
+	return document.Paths[pathParamRegex.ReplaceAllString(fullPath, "{$1}")][strings.ToLower(method)]
+}
+
+func validateRequest(ctx *gin.Context, validator schemaValidator, operation *Operation) error {
+	details := map[string][]string{}
+	query := ctx.Request.URL.Query()
+	for _, parameter := range operation.Parameters {
+		var values []string
+		switch parameter.In {
+		case "path":
+			if value, exists := ctx.Params.Get(parameter.Name); exists {
+				values = []string{value}
+			}
+		case "query":
+			if parameter.Style == "deepObject" {
+				validateDeepObject(validator, parameter, query, details)
+				continue
+			}
+			values = query[parameter.Name]
+		case "header":
+			values = ctx.Request.Header.Values(parameter.Name)
+		}
+		validateParameter(validator, parameter, values, details)
+	}
+	if operation.RequestBody != nil {
+		body, err := bprouter.ReadBody(ctx)
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(body)) == 0 {
+			if operation.RequestBody.Required {
+				details["body"] = append(details["body"], requiredCode)
+			}
+		} else {
+			value, err := decodeJSON(body)
+			if err != nil {
+				return bperr.ErrBadRequest.WithMessage("The request body is not a valid JSON").WithCause(err)
+			}
+			mergeDetails(details, validator.validate(operation.RequestBody.Content["application/json"].Schema, value, ""), "body")
+		}
+	}
+	if len(details) > 0 {
+		return bperr.ErrValidation.WithDetails(details)
+	}
+	return nil
+}
+
+/*
+Validate the values of a parameter. Arrays receive all the values, split by comma
+when the parameter is not exploded, while other types receive only the first one.
+*/
+func validateParameter(validator schemaValidator, parameter Parameter, values []string, details map[string][]string) {
+	if len(values) == 0 {
+		if parameter.Required {
+			details[parameter.Name] = append(details[parameter.Name], requiredCode)
+		}
+		return
+	}
+	schema := parameter.Schema
+	if schema == nil || !slices.Contains(schema.Type, "array") {
+		mergeDetails(details, validator.validate(schema, parameterValue(schema, values[0]), parameter.Name), parameter.Name)
+		return
+	}
+	if parameter.Explode != nil && !*parameter.Explode {
+		values = strings.Split(strings.Join(values, ","), ",")
+	}
+	items := []interface{}{}
+	for _, value := range values {
+		items = append(items, parameterValue(schema.Items, value))
+	}
+	mergeDetails(details, validator.validate(schema, items, parameter.Name), parameter.Name)
+}
+
+/*
+Validate the params of a deep object in the form `name[property][operator]`, as the filters are described.
+The operator can be omitted for equality.
+*/
+func validateDeepObject(validator schemaValidator, parameter Parameter, query url.Values, details map[string][]string) {
+	prefix := parameter.Name + "["
+	for key, values := range query {
+		if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		segments := strings.Split(strings.TrimSuffix(strings.TrimPrefix(key, prefix), "]"), "][")
+		property, exists := parameter.Schema.Properties[segments[0]]
+		if !exists || len(segments) > 2 {
+			details[key] = append(details[key], unknownFieldCode)
+			continue
+		}
+		operator := "eq"
+		if len(segments) == 2 {
+			operator = segments[1]
+		}
+		schema, exists := property.Properties[operator]
+		if !exists {
+			details[key] = append(details[key], unsupportedOperatorCode)
+			continue
+		}
+		for _, value := range values {
+			mergeDetails(details, validator.validate(schema, parameterValue(schema, value), key), key)
+		}
+	}
+}
+
+/*
+Validate the buffered response against the documented response of its status.
+Not Modified responses of conditional requests have no body, so they are not validated.
+*/
+func validateResponse(validator schemaValidator, operation *Operation, writer *bprouter.ResponseBuffer) error {
+	if writer.Status() == http.StatusNotModified {
+		return nil
+	}
+	response, exists := operation.Responses[strconv.Itoa(writer.Status())]
+	if !exists {
+		response, exists = operation.Responses["default"]
+	}
+	if !exists {
+		return errInvalidResponse.WithDetails(map[string][]string{"status": {"undocumented-status"}})
+	}
+	if len(response.Content) == 0 || len(writer.Body()) == 0 {
+		return nil
+	}
+	contentType, _, _ := mime.ParseMediaType(writer.Header().Get("Content-Type"))
+	media, exists := response.Content[contentType]
+	if !exists {
+		return errInvalidResponse.WithDetails(map[string][]string{"Content-Type": {invalidValueCode}})
+	}
+	value, err := decodeJSON(writer.Body())
+	if err != nil {
+		return errInvalidResponse.WithCause(err)
+	}
+	details := map[string][]string{}
+	mergeDetails(details, validator.validate(media.Schema, value, ""), "body")
+	if len(details) > 0 {
+		return errInvalidResponse.WithDetails(details)
+	}
+	return nil
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+/*
+Merge the codes of the errors, moving the ones of the root value under the given key.
+*/
+func mergeDetails(details map[string][]string, other map[string][]string, rootKey string) {
+	for key, codes := range other {
+		if key == "" {
+			key = rootKey
+		}
+		details[key] = append(details[key], codes...)
+	}
+}
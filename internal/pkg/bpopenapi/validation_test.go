@@ -0,0 +1,126 @@
+package bpopenapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpfilter"
+	"github.com/besasch88/blueprint/internal/pkg/bprouter"
+	"github.com/gin-gonic/gin"
+)
+
+type testValidationInputDto struct {
+	ID       string `uri:"articleID" json:"-" openapi:"format=uuid"`
+	PageSize int    `form:"pageSize" json:"-"`
+	OrderBy  string `form:"orderBy" json:"-" openapi:"enum=title|createdAt"`
+	Title    string `json:"title"`
+}
+
+type testValidationOutputDto struct {
+	ID    string `json:"id" openapi:"format=uuid"`
+	Title string `json:"title"`
+}
+
+const testArticleID = "7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d001"
+
+/*
+Describe a route in a document written to a file, then serve it validating requests and responses.
+The handler returns the given payload.
+*/
+func newValidationTestEngine(t *testing.T, payload gin.H) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	route := Route{
+		Request:  testValidationInputDto{},
+		Response: testValidationOutputDto{},
+		Filters: bpfilter.Fields{
+			"title": {Column: "title", Type: bpfilter.String, Operators: []bpfilter.Operator{bpfilter.Eq}},
+		},
+	}
+	handler := func(ctx *gin.Context) {
+		var request testValidationInputDto
+		if err := bprouter.BindParameters(ctx, &request); err != nil {
+			bprouter.ReturnError(ctx, err)
+			return
+		}
+		bprouter.ReturnOk(ctx, bprouter.ItemResponse(payload))
+	}
+	routes = map[string]describedRoute{}
+	Handle(gin.New().Group("api/v1"), http.MethodPut, "/articles/:articleID", route, handler)
+	document, err := Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := json.Marshal(document)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "openapi.json")
+	if err := os.WriteFile(file, content, 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	engine := gin.New()
+	group := engine.Group("api/v1")
+	group.Use(ValidationMiddleware(file, true))
+	Handle(group, http.MethodPut, "/articles/:articleID", route, handler)
+	return engine
+}
+
+func validationTestRequest(engine *gin.Engine, target string, body string) (*httptest.ResponseRecorder, bprouter.Problem) {
+	request := httptest.NewRequest(http.MethodPut, target, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	engine.ServeHTTP(response, request)
+	var problem bprouter.Problem
+	json.Unmarshal(response.Body.Bytes(), &problem)
+	return response, problem
+}
+
+func TestValidationMiddlewareAcceptsValidRequests(t *testing.T) {
+	engine := newValidationTestEngine(t, gin.H{"id": testArticleID, "title": "Title"})
+	response, _ := validationTestRequest(engine, "/api/v1/articles/"+testArticleID+"?pageSize=10&orderBy=title&filter[title]=a", `{"title": "Title"}`)
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), testArticleID) {
+		t.Errorf("expected the response of the handler, got %d %s", response.Code, response.Body.String())
+	}
+}
+
+func TestValidationMiddlewareRejectsInvalidRequests(t *testing.T) {
+	engine := newValidationTestEngine(t, gin.H{"id": testArticleID, "title": "Title"})
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		expected map[string][]string
+	}{
+		{"path", "/api/v1/articles/not-a-uuid", `{"title": "Title"}`, map[string][]string{"articleID": {"invalid-format"}}},
+		{"query type", "/api/v1/articles/" + testArticleID + "?pageSize=ten", `{"title": "Title"}`, map[string][]string{"pageSize": {"invalid-type"}}},
+		{"query enum", "/api/v1/articles/" + testArticleID + "?orderBy=views", `{"title": "Title"}`, map[string][]string{"orderBy": {"invalid-value"}}},
+		{"filter", "/api/v1/articles/" + testArticleID + "?filter[views][gt]=1", `{"title": "Title"}`, map[string][]string{"filter[views][gt]": {"unknown-field"}}},
+		{"body type", "/api/v1/articles/" + testArticleID, `{"title": 1}`, map[string][]string{"title": {"invalid-type"}}},
+		{"body required", "/api/v1/articles/" + testArticleID, `{}`, map[string][]string{"title": {"required"}}},
+		{"body missing", "/api/v1/articles/" + testArticleID, ``, map[string][]string{"body": {"required"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, problem := validationTestRequest(engine, test.target, test.body)
+			if response.Code != http.StatusUnprocessableEntity || !reflect.DeepEqual(problem.Errors, test.expected) {
+				t.Errorf("expected %v, got %d %s", test.expected, response.Code, response.Body.String())
+			}
+		})
+	}
+}
+
+func TestValidationMiddlewareRejectsInvalidResponses(t *testing.T) {
+	engine := newValidationTestEngine(t, gin.H{"id": "not-a-uuid"})
+	response, problem := validationTestRequest(engine, "/api/v1/articles/"+testArticleID, `{"title": "Title"}`)
+	expected := map[string][]string{"item.id": {"invalid-format"}, "item.title": {"required"}}
+	if response.Code != http.StatusInternalServerError || problem.Code != "invalid-response" || !reflect.DeepEqual(problem.Errors, expected) {
+		t.Errorf("expected the invalid response error, got %d %s", response.Code, response.Body.String())
+	}
+}
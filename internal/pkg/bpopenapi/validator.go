@@ -0,0 +1,185 @@
+package bpopenapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+/*
+Codes returned for the values not matching the document.
+*/
+const (
+	requiredCode      = "required"
+	invalidTypeCode   = "invalid-type"
+	invalidFormatCode = "invalid-format"
+	invalidValueCode  = "invalid-value"
+	unknownFieldCode  = "unknown-field"
+	// Operators of the filters described as deep objects
+	unsupportedOperatorCode = "unsupported-operator"
+)
+
+const schemaRefPrefix = "#/components/schemas/"
+
+/*
+SchemaValidator validates the values decoded from JSON, with numbers decoded as json.Number,
+against the schemas of a document, collecting the codes of the errors by path.
+*/
+type schemaValidator struct {
+	schemas map[string]*Schema
+}
+
+func newSchemaValidator(document *Document) schemaValidator {
+	return schemaValidator{schemas: document.Components.Schemas}
+}
+
+/*
+Validate the value and return the codes of the errors by path, e.g. `items.0.email`.
+*/
+func (v schemaValidator) validate(schema *Schema, value interface{}, path string) map[string][]string {
+	details := map[string][]string{}
+	v.validateInto(schema, value, path, details)
+	return details
+}
+
+func (v schemaValidator) validateInto(schema *Schema, value interface{}, path string, details map[string][]string) {
+	if schema == nil {
+		return
+	}
+	if schema.Ref != "" {
+		// References to unknown components cannot be checked
+		if target, exists := v.schemas[strings.TrimPrefix(schema.Ref, schemaRefPrefix)]; exists {
+			v.validateInto(target, value, path, details)
+		}
+		return
+	}
+	for _, part := range schema.AllOf {
+		v.validateInto(part, value, path, details)
+	}
+	if len(schema.OneOf) > 0 {
+		v.validateOneOf(schema.OneOf, value, path, details)
+	}
+	if len(schema.Type) > 0 && !slices.ContainsFunc(schema.Type, func(t string) bool { return matchesType(t, value) }) {
+		details[path] = append(details[path], invalidTypeCode)
+		return
+	}
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(item interface{}) bool { return fmt.Sprint(item) == fmt.Sprint(value) }) {
+		details[path] = append(details[path], invalidValueCode)
+	}
+	switch typed := value.(type) {
+	case string:
+		if !matchesFormat(schema.Format, typed) {
+			details[path] = append(details[path], invalidFormatCode)
+		}
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, exists := typed[name]; !exists {
+				details[joinPath(path, name)] = append(details[joinPath(path, name)], requiredCode)
+			}
+		}
+		for name, item := range typed {
+			if property, exists := schema.Properties[name]; exists {
+				v.validateInto(property, item, joinPath(path, name), details)
+			} else if schema.AdditionalProperties != nil {
+				v.validateInto(schema.AdditionalProperties, item, joinPath(path, name), details)
+			}
+		}
+	case []interface{}:
+		for i, item := range typed {
+			v.validateInto(schema.Items, item, joinPath(path, strconv.Itoa(i)), details)
+		}
+	}
+}
+
+/*
+The value must match at least one of the schemas. Otherwise, the errors of the first schema are returned,
+e.g. the ones of the referenced object for nullable objects.
+*/
+func (v schemaValidator) validateOneOf(schemas []*Schema, value interface{}, path string, details map[string][]string) {
+	var first map[string][]string
+	for _, schema := range schemas {
+		candidate := v.validate(schema, value, path)
+		if len(candidate) == 0 {
+			return
+		}
+		if first == nil {
+			first = candidate
+		}
+	}
+	for key, codes := range first {
+		details[key] = append(details[key], codes...)
+	}
+}
+
+func matchesType(schemaType string, value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return schemaType == "null"
+	case bool:
+		return schemaType == "boolean"
+	case string:
+		return schemaType == "string"
+	case json.Number:
+		if schemaType == "integer" {
+			_, err := typed.Int64()
+			return err == nil
+		}
+		return schemaType == "number"
+	case []interface{}:
+		return schemaType == "array"
+	case map[string]interface{}:
+		return schemaType == "object"
+	default:
+		return false
+	}
+}
+
+/*
+Only the formats generated for the DTOs are checked, the others are accepted as they are.
+*/
+func matchesFormat(format string, value string) bool {
+	switch format {
+	case "uuid":
+		return is.UUID.Validate(value) == nil
+	case "email":
+		return is.EmailFormat.Validate(value) == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	default:
+		return true
+	}
+}
+
+/*
+Convert a parameter into the type expected by its schema, so it can be validated as a JSON value.
+Values that cannot be converted are returned as strings, failing the validation of the type.
+*/
+func parameterValue(schema *Schema, raw string) interface{} {
+	if schema == nil {
+		return raw
+	}
+	switch {
+	case slices.Contains(schema.Type, "integer"), slices.Contains(schema.Type, "number"):
+		if _, err := strconv.ParseFloat(raw, 64); err == nil {
+			return json.Number(raw)
+		}
+	case slices.Contains(schema.Type, "boolean"):
+		if parsed, err := strconv.ParseBool(raw); err == nil {
+			return parsed
+		}
+	}
+	return raw
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,158 @@
+package bpquota
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/*
+Number of counters moved to Postgres in a single batch.
+*/
+const flushBatchSize = 500
+
+/*
+Interval of the flusher when the given one is not valid.
+*/
+const defaultFlushInterval = time.Minute
+
+/*
+QuotaUsageModel represents the usage of a subject in a period, as stored in Postgres.
+*/
+type quotaUsageModel struct {
+	Subject     string    `gorm:"primaryKey"`
+	Period      string    `gorm:"primaryKey"`
+	PeriodStart time.Time `gorm:"primaryKey;type:date"`
+	Units       int64
+	UpdatedAt   time.Time
+}
+
+func (quotaUsageModel) TableName() string {
+	return "bp_quota_usage"
+}
+
+/*
+Flush moves the counters updated since the last flush from Redis to Postgres, returning how many have been stored.
+Counters are stored with their absolute value, so flushing the same counter twice is harmless.
+In case of error, the counters not stored are kept for the next flush.
+*/
+func Flush(ctx context.Context, db *gorm.DB) (int, error) {
+	flushed := 0
+	for {
+		keys, err := store.client.SPopN(ctx, dirtyCountersKey, flushBatchSize).Result()
+		if err != nil {
+			return flushed, err
+		}
+		if len(keys) == 0 {
+			return flushed, nil
+		}
+		items, err := readCounters(ctx, store.client, keys)
+		if err == nil && len(items) > 0 {
+			err = db.WithContext(ctx).Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "subject"}, {Name: "period"}, {Name: "period_start"}},
+				DoUpdates: clause.AssignmentColumns([]string{"units", "updated_at"}),
+			}).Create(&items).Error
+		}
+		if err != nil {
+			members := make([]interface{}, len(keys))
+			for i, key := range keys {
+				members[i] = key
+			}
+			if errRestore := store.client.SAdd(ctx, dirtyCountersKey, members...).Err(); errRestore != nil {
+				zap.L().Error("Error restoring quota counters to flush", zap.String("service", "quota"), zap.Error(errRestore))
+			}
+			return flushed, err
+		}
+		flushed += len(items)
+	}
+}
+
+/*
+Read the value of the given counters, skipping the ones already expired or with an invalid key.
+*/
+func readCounters(ctx context.Context, client *redis.Client, keys []string) ([]quotaUsageModel, error) {
+	values, err := client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	items := []quotaUsageModel{}
+	for i, key := range keys {
+		value, ok := values[i].(string)
+		if !ok {
+			continue
+		}
+		// Keys have the format quota:<period>:<period-start>:<subject>
+		parts := strings.SplitN(key, ":", 4)
+		if len(parts) != 4 {
+			continue
+		}
+		period := Period(parts[1])
+		periodStart, errParse := time.Parse(period.layout(), parts[2])
+		if errParse != nil {
+			continue
+		}
+		var units int64
+		if _, errParse := fmt.Sscan(value, &units); errParse != nil {
+			continue
+		}
+		items = append(items, quotaUsageModel{
+			Subject:     parts[3],
+			Period:      string(period),
+			PeriodStart: periodStart,
+			Units:       units,
+			UpdatedAt:   now,
+		})
+	}
+	return items, nil
+}
+
+/*
+StartFlusher periodically flushes the counters to Postgres in background.
+It returns a function that stops the flusher, performing a last flush. E.g.
+
+	stopQuotaFlusher := bpquota.StartFlusher(dbConnection, time.Minute)
+	defer stopQuotaFlusher()
+*/
+func StartFlusher(db *gorm.DB, interval time.Duration) func() {
+	if interval <= 0 {
+		zap.L().Warn(fmt.Sprintf("Invalid quota flush interval %s, using %s", interval, defaultFlushInterval), zap.String("service", "quota"))
+		interval = defaultFlushInterval
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	flush := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		defer cancel()
+		flushed, err := Flush(ctx, db)
+		if err != nil {
+			zap.L().Error("Error flushing quota counters", zap.String("service", "quota"), zap.Error(err))
+			return
+		}
+		zap.L().Debug("Quota counters flushed", zap.String("service", "quota"), zap.Int("counters", flushed))
+	}
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				flush()
+			case <-stop:
+				flush()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
@@ -0,0 +1,81 @@
+package bpquota_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"github.com/besasch88/blueprint/internal/pkg/bpquota"
+	"github.com/besasch88/blueprint/internal/pkg/bprouter"
+	"github.com/besasch88/blueprint/internal/pkg/bptest"
+	"github.com/gin-gonic/gin"
+)
+
+var testDatabase *bptest.Database
+
+func TestMain(m *testing.M) {
+	os.Exit(bptest.Main(m, &testDatabase))
+}
+
+/*
+Build an engine exposing a route that consumes 1 unit, with a daily quota of 2 units.
+*/
+func newTestEngine(t *testing.T) *gin.Engine {
+	envs := bptest.NewEnvs()
+	envs.QuotaFile = filepath.Join(t.TempDir(), "quotas.yaml")
+	if err := os.WriteFile(envs.QuotaFile, []byte("default: { daily: 2, monthly: 100 }\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return bptest.NewEngine(t, envs, func(group *gin.RouterGroup) {
+		group.GET("/items", bpauth.AuthMiddleware([]string{bpauth.UserGet}), bpquota.QuotaMiddleware(1), func(ctx *gin.Context) {
+			bprouter.ReturnOk(ctx, &gin.H{})
+		})
+	})
+}
+
+func TestQuotaMiddleware(t *testing.T) {
+	engine := newTestEngine(t)
+	authUser := bptest.MintAuthUser(bpauth.UserGet)
+	for i := 0; i < 2; i++ {
+		bptest.AssertJSON(t, bptest.Request(t, engine, http.MethodGet, "/api/v1/items", nil, &authUser), http.StatusOK, `{}`)
+	}
+	response := bptest.Request(t, engine, http.MethodGet, "/api/v1/items", nil, &authUser)
+	bptest.AssertJSON(t, response, http.StatusTooManyRequests, `{"type": "about:blank", "title": "Too Many Requests", "status": 429, "code": "quota-exceeded", "detail": "The quota of the current period has been exhausted", "instance": "/api/v1/items"}`)
+	if response.Header().Get("Retry-After") == "" {
+		t.Error("expected the Retry-After header")
+	}
+	// Quotas are per user
+	otherUser := bptest.MintAuthUser(bpauth.UserGet)
+	bptest.AssertJSON(t, bptest.Request(t, engine, http.MethodGet, "/api/v1/items", nil, &otherUser), http.StatusOK, `{}`)
+}
+
+func TestFlush(t *testing.T) {
+	tx := bptest.RequireDatabase(t, testDatabase)
+	engine := newTestEngine(t)
+	authUser := bptest.MintAuthUser(bpauth.UserGet)
+	subject := "user:" + authUser.ID.String()
+	for i := 0; i < 2; i++ {
+		bptest.Request(t, engine, http.MethodGet, "/api/v1/items", nil, &authUser)
+	}
+
+	// Flushing twice must not duplicate the usage
+	for i := 0; i < 2; i++ {
+		if _, err := bpquota.Flush(context.Background(), tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now().UTC()
+	for _, period := range []bpquota.Period{bpquota.Daily, bpquota.Monthly} {
+		items, err := bpquota.GetUsageReport(tx, period, now.AddDate(0, -1, 0), now, subject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 1 || items[0].Units != 2 {
+			t.Errorf("expected 2 units in the %s report, got %+v", period, items)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package bpquota
+
+import (
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpredis"
+	"go.uber.org/zap"
+)
+
+var store quotaStore
+var quotas quotaFile
+
+/*
+Init initializes the Quota service, storing the counters in Redis and loading the limits from the given file.
+By default it uses the same Redis of the rate limit, sharing its client. If Redis is not ready, the connection is retried with an exponential backoff until the retry timeout is reached.
+Counters are moved to Postgres by the flusher, see StartFlusher.
+*/
+func Init(qConnectionURI string, qConnectRetryTimeoutSeconds int, qQuotasFile string) {
+	zap.L().Info("Initializing Quota Service on Redis. Connecting...", zap.String("service", "quota"))
+	loadedQuotas, err := loadQuotas(qQuotasFile)
+	if err != nil {
+		zap.L().Error("Error loading quotas", zap.String("service", "quota"), zap.Error(err))
+		panic(err)
+	}
+	client, err := bpredis.Connect(qConnectionURI, qConnectRetryTimeoutSeconds)
+	if err != nil {
+		zap.L().Error("Error during Quota Service initalization", zap.String("service", "quota"), zap.Error(err))
+		panic(err)
+	}
+	store = quotaStore{client: client, now: time.Now}
+	quotas = loadedQuotas
+	zap.L().Info("Quota Service initialized on Redis. Connected!", zap.String("service", "quota"))
+}
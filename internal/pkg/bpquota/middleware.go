@@ -0,0 +1,73 @@
+package bpquota
+
+import (
+	"fmt"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"github.com/besasch88/blueprint/internal/pkg/bprouter"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+/*
+QuotaMiddleware is a middleware for APIs consuming the given units of the daily and monthly quotas
+of the authenticated user, or of its API key when the request is authenticated via an API key
+(see quotaSubject). It must be placed after the AuthMiddleware, anonymous requests are not counted.
+Differently from the rate limit, quotas cover long periods and are generally bound to billing tiers.
+
+Example of usage of this middleware:
+router.GET(
+
+	"/users/:userID",
+	bpauth.AuthMiddleware([]string{bpauth.UserGet}),
+	bpquota.QuotaMiddleware(1),
+	... //other middlewares
+	func(ctx *gin.Context) {
+		... // your logic
+*/
+func QuotaMiddleware(units int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		retryAfter, err := Consume(ctx, units)
+		if err == ErrQuotaExceeded {
+			bprouter.ReturnQuotaExceededError(ctx, retryAfter)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+/*
+Consume counts custom units in the quotas of the authenticated user, e.g. when the cost of a request
+is known only after its processing. If a quota is exceeded, it returns ErrQuotaExceeded and the number
+of seconds before the quota reset, without counting any unit.
+If the store cannot be reached, units are not counted and the request can proceed.
+*/
+func Consume(ctx *gin.Context, units int64) (int64, error) {
+	authUser := bpauth.GetAuthUserFromSession(ctx)
+	if authUser == nil {
+		return 0, nil
+	}
+	subject := quotaSubject(*authUser)
+	usage, exceeded, retryAfter, err := store.consume(ctx, subject, units, quotas.resolve(authUser))
+	if err != nil {
+		zap.L().Error("Quota check failed", zap.String("service", "quota"), zap.Error(err))
+		return 0, nil
+	}
+	if exceeded != "" {
+		zap.L().Info(fmt.Sprintf("Quota exceeded for %s in the current %s. Abort...", subject, exceeded), zap.String("service", "quota"), zap.Int64("dailyUsage", usage.Daily), zap.Int64("monthlyUsage", usage.Monthly))
+		return retryAfter, ErrQuotaExceeded
+	}
+	return 0, nil
+}
+
+/*
+Return the subject whose quotas are consumed: `apikey:<id>` for the requests authenticated via an API key,
+so each key of a user has its own counters, otherwise `user:<uuid>`.
+*/
+func quotaSubject(authUser bpauth.AuthUser) string {
+	if authUser.APIKeyID != "" {
+		return fmt.Sprintf("apikey:%s", authUser.APIKeyID)
+	}
+	return fmt.Sprintf("user:%s", authUser.ID.String())
+}
@@ -0,0 +1,130 @@
+package bpquota
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+ErrQuotaExceeded is returned when the requester has consumed all the units of its daily or monthly quota.
+*/
+var ErrQuotaExceeded = bperr.ErrQuotaExceeded
+
+/*
+Period represents the time range a quota refers to. Periods follow the UTC calendar.
+*/
+type Period string
+
+/*
+List of available quota periods.
+*/
+const (
+	Daily   Period = "day"
+	Monthly Period = "month"
+)
+
+/*
+AvailablePeriods represents a list of available quota periods. It is generally used
+to validate inputs, e.g. in the usage report.
+*/
+var AvailablePeriods = []interface{}{Daily, Monthly}
+
+/*
+Return the start of the period containing the given time, and the start of the next one.
+*/
+func (p Period) bounds(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	if p == Monthly {
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
+
+/*
+Return the layout used to format the start of the period in the counter keys.
+*/
+func (p Period) layout() string {
+	if p == Monthly {
+		return "2006-01"
+	}
+	return "2006-01-02"
+}
+
+/*
+QuotaLimits represents the units allowed per period. Zero means unlimited.
+*/
+type quotaLimits struct {
+	Daily   int64 `yaml:"daily"`
+	Monthly int64 `yaml:"monthly"`
+}
+
+/*
+QuotaTier represents the limits applied to the users owning a specific claim, e.g. a billing tier.
+*/
+type quotaTier struct {
+	Claim       string `yaml:"claim"`
+	quotaLimits `yaml:",inline"`
+}
+
+/*
+QuotaFile represents the content of the file where quotas are configured. E.g.
+
+	default: { daily: 10000, monthly: 200000 }
+	tiers:
+	  - { claim: tier-premium, daily: 100000, monthly: 2000000 }
+*/
+type quotaFile struct {
+	Default quotaLimits `yaml:"default"`
+	Tiers   []quotaTier `yaml:"tiers"`
+}
+
+/*
+Load the quotas from a YAML (or JSON) file. An empty path means unlimited quotas,
+while usage is still counted for reporting.
+*/
+func loadQuotas(path string) (quotaFile, error) {
+	if path == "" {
+		return quotaFile{}, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return quotaFile{}, err
+	}
+	var file quotaFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return quotaFile{}, err
+	}
+	limits := []quotaLimits{file.Default}
+	for _, tier := range file.Tiers {
+		if tier.Claim == "" {
+			return quotaFile{}, fmt.Errorf("tier claim is required")
+		}
+		limits = append(limits, tier.quotaLimits)
+	}
+	for _, limit := range limits {
+		if limit.Daily < 0 || limit.Monthly < 0 {
+			return quotaFile{}, fmt.Errorf("quota limits cannot be negative")
+		}
+	}
+	return file, nil
+}
+
+/*
+Resolve the limits of a user: the first tier matching one of its claims, otherwise the default ones.
+*/
+func (f quotaFile) resolve(authUser *bpauth.AuthUser) quotaLimits {
+	for _, tier := range f.Tiers {
+		if slices.Contains(authUser.Claims, tier.Claim) {
+			return tier.quotaLimits
+		}
+	}
+	return f.Default
+}
@@ -0,0 +1,54 @@
+package bpquota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"github.com/google/uuid"
+)
+
+func TestLoadQuotas(t *testing.T) {
+	loaded, err := loadQuotas("../../../scripts/quotas.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits := loaded.resolve(&bpauth.AuthUser{}); limits != loaded.Default {
+		t.Errorf("expected the default limits, got %+v", limits)
+	}
+	if limits := loaded.resolve(&bpauth.AuthUser{Claims: []string{"tier-premium"}}); limits != loaded.Tiers[0].quotaLimits {
+		t.Errorf("expected the premium limits, got %+v", limits)
+	}
+}
+
+func TestPeriodBounds(t *testing.T) {
+	now := time.Date(2024, 2, 29, 13, 45, 0, 0, time.UTC)
+	cases := []struct {
+		period        Period
+		expectedStart time.Time
+		expectedNext  time.Time
+	}{
+		{Daily, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Monthly, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		start, next := c.period.bounds(now)
+		if !start.Equal(c.expectedStart) || !next.Equal(c.expectedNext) {
+			t.Errorf("unexpected bounds for %s: %s - %s", c.period, start, next)
+		}
+	}
+	if key := counterKey(Monthly, now, "user:1"); key != "quota:month:2024-02:user:1" {
+		t.Errorf("unexpected key %s", key)
+	}
+}
+
+func TestQuotaSubject(t *testing.T) {
+	authUser := bpauth.AuthUser{ID: uuid.MustParse("7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d001")}
+	if subject := quotaSubject(authUser); subject != "user:7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d001" {
+		t.Errorf("expected the user subject, got %s", subject)
+	}
+	authUser.APIKeyID = "key-1"
+	if subject := quotaSubject(authUser); subject != "apikey:key-1" {
+		t.Errorf("expected the API key subject, got %s", subject)
+	}
+}
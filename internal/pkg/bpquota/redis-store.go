@@ -0,0 +1,106 @@
+package bpquota
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+Counters are kept in Redis for some days after the end of their period,
+so they can be flushed to Postgres even after a long downtime.
+*/
+const counterRetention = 7 * 24 * time.Hour
+
+/*
+Set containing the keys of the counters updated since the last flush.
+*/
+const dirtyCountersKey = "quota:dirty"
+
+/*
+Check both the daily and the monthly quotas and, if the units fit in both of them, count them atomically.
+KEYS are the daily counter, the monthly counter and the set of counters to flush.
+ARGV are the units, the daily and the monthly limits (0 means unlimited) and the expiration
+of the daily and the monthly counters as Unix time in milliseconds.
+It returns 1 if the units have been counted (0 otherwise), the exceeded period (1 for the day, 2 for the month)
+and the daily and the monthly usage.
+*/
+var consumeScript = redis.NewScript(`
+local units = tonumber(ARGV[1])
+local dailyLimit = tonumber(ARGV[2])
+local monthlyLimit = tonumber(ARGV[3])
+local day = tonumber(redis.call('GET', KEYS[1]) or '0')
+local month = tonumber(redis.call('GET', KEYS[2]) or '0')
+if monthlyLimit > 0 and month + units > monthlyLimit then
+	return {0, 2, day, month}
+end
+if dailyLimit > 0 and day + units > dailyLimit then
+	return {0, 1, day, month}
+end
+if units > 0 then
+	day = redis.call('INCRBY', KEYS[1], units)
+	redis.call('PEXPIREAT', KEYS[1], ARGV[4])
+	month = redis.call('INCRBY', KEYS[2], units)
+	redis.call('PEXPIREAT', KEYS[2], ARGV[5])
+	redis.call('SADD', KEYS[3], KEYS[1], KEYS[2])
+end
+return {1, 0, day, month}
+`)
+
+/*
+QuotaUsage represents the units consumed by a subject in the current day and month.
+*/
+type quotaUsage struct {
+	Daily   int64
+	Monthly int64
+}
+
+/*
+QuotaStore represents the store of the quota counters, based on Redis.
+*/
+type quotaStore struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+/*
+Build the key of the counter of a subject for the period containing the given time,
+e.g. `quota:day:2024-06-30:user:<uuid>`.
+*/
+func counterKey(period Period, t time.Time, subject string) string {
+	start, _ := period.bounds(t)
+	return fmt.Sprintf("quota:%s:%s:%s", period, start.Format(period.layout()), subject)
+}
+
+/*
+Consume the given units of the subject quotas. When a quota is exceeded, no unit is counted
+and the exceeded period is returned together with the number of seconds before its reset.
+*/
+func (s quotaStore) consume(ctx context.Context, subject string, units int64, limits quotaLimits) (quotaUsage, Period, int64, error) {
+	now := s.now()
+	_, nextDay := Daily.bounds(now)
+	_, nextMonth := Monthly.bounds(now)
+	result, err := consumeScript.Run(ctx, s.client,
+		[]string{counterKey(Daily, now, subject), counterKey(Monthly, now, subject), dirtyCountersKey},
+		units, limits.Daily, limits.Monthly,
+		nextDay.Add(counterRetention).UnixMilli(), nextMonth.Add(counterRetention).UnixMilli(),
+	).Int64Slice()
+	if err != nil {
+		return quotaUsage{}, "", 0, err
+	}
+	usage := quotaUsage{Daily: result[2], Monthly: result[3]}
+	switch {
+	case result[0] == 1:
+		return usage, "", 0, nil
+	case result[1] == 2:
+		return usage, Monthly, secondsUntil(now, nextMonth), nil
+	default:
+		return usage, Daily, secondsUntil(now, nextDay), nil
+	}
+}
+
+func secondsUntil(now time.Time, t time.Time) int64 {
+	return int64((t.Sub(now) + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,87 @@
+package bpquota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+The bptest package cannot be used here since it depends on this package.
+*/
+func newTestStore(t *testing.T, now time.Time) quotaStore {
+	server := miniredis.RunT(t)
+	server.SetTime(now)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return quotaStore{client: client, now: func() time.Time { return now }}
+}
+
+func TestQuotaStoreConsume(t *testing.T) {
+	now := time.Date(2024, 6, 30, 23, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	t.Run("Daily quota exceeded", func(t *testing.T) {
+		s := newTestStore(t, now)
+		limits := quotaLimits{Daily: 5, Monthly: 100}
+		if _, exceeded, _, err := s.consume(ctx, "user:1", 3, limits); err != nil || exceeded != "" {
+			t.Fatalf("expected the units to be counted, got %s %v", exceeded, err)
+		}
+		usage, exceeded, retryAfter, err := s.consume(ctx, "user:1", 3, limits)
+		if err != nil || exceeded != Daily || retryAfter != 3600 {
+			t.Fatalf("expected the daily quota to be exceeded for 1 hour, got %s %d %v", exceeded, retryAfter, err)
+		}
+		if usage.Daily != 3 || usage.Monthly != 3 {
+			t.Errorf("expected exceeding units not to be counted, got %+v", usage)
+		}
+		if _, exceeded, _, _ := s.consume(ctx, "user:1", 2, limits); exceeded != "" {
+			t.Error("expected the remaining units to be consumed")
+		}
+	})
+
+	t.Run("Monthly quota exceeded", func(t *testing.T) {
+		s := newTestStore(t, now)
+		limits := quotaLimits{Daily: 5, Monthly: 4}
+		s.consume(ctx, "user:1", 4, limits)
+		if _, exceeded, _, _ := s.consume(ctx, "user:1", 1, limits); exceeded != Monthly {
+			t.Errorf("expected the monthly quota to be exceeded, got %s", exceeded)
+		}
+	})
+
+	t.Run("Unlimited quotas", func(t *testing.T) {
+		s := newTestStore(t, now)
+		usage, exceeded, _, err := s.consume(ctx, "user:1", 1000, quotaLimits{})
+		if err != nil || exceeded != "" || usage.Daily != 1000 {
+			t.Errorf("expected unlimited quotas, got %+v %s %v", usage, exceeded, err)
+		}
+		members, _ := s.client.SMembers(ctx, dirtyCountersKey).Result()
+		if len(members) != 2 {
+			t.Errorf("expected the counters to be flushed, got %v", members)
+		}
+	})
+}
+
+func TestReadCounters(t *testing.T) {
+	now := time.Date(2024, 6, 30, 23, 0, 0, 0, time.UTC)
+	s := newTestStore(t, now)
+	s.consume(context.Background(), "user:1", 7, quotaLimits{})
+	keys := []string{counterKey(Daily, now, "user:1"), counterKey(Monthly, now, "user:1"), "quota:day:2024-06-29:user:expired"}
+	items, err := readCounters(context.Background(), s.client, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 counters, got %+v", items)
+	}
+	if items[0].Period != "day" || items[0].Subject != "user:1" || items[0].Units != 7 || !items[0].PeriodStart.Equal(time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected daily counter %+v", items[0])
+	}
+	if items[1].Period != "month" || !items[1].PeriodStart.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected monthly counter %+v", items[1])
+	}
+}
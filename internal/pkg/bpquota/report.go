@@ -0,0 +1,44 @@
+package bpquota
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+UsageReportItem represents the units consumed by a subject in a period.
+*/
+type UsageReportItem struct {
+	Subject     string
+	Period      Period
+	PeriodStart time.Time
+	Units       int64
+	UpdatedAt   time.Time
+}
+
+/*
+GetUsageReport returns the usage stored in Postgres for the periods starting between from and to (both included),
+optionally filtered by subject. The usage of the current periods is updated at each flush.
+*/
+func GetUsageReport(db *gorm.DB, period Period, from time.Time, to time.Time, subject string) ([]UsageReportItem, error) {
+	var models []quotaUsageModel
+	query := db.Where("period = ? AND period_start BETWEEN ? AND ?", string(period), from.UTC().Format("2006-01-02"), to.UTC().Format("2006-01-02"))
+	if subject != "" {
+		query = query.Where("subject = ?", subject)
+	}
+	if err := query.Order("period_start, subject").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	items := make([]UsageReportItem, len(models))
+	for i, model := range models {
+		items[i] = UsageReportItem{
+			Subject:     model.Subject,
+			Period:      Period(model.Period),
+			PeriodStart: model.PeriodStart,
+			Units:       model.Units,
+			UpdatedAt:   model.UpdatedAt,
+		}
+	}
+	return items, nil
+}
@@ -1,38 +1,70 @@
 package bpratelimit
 
 import (
-	"time"
+	"fmt"
+	"slices"
 
-	"github.com/redis/go-redis/v9"
+	"github.com/besasch88/blueprint/internal/pkg/bpredis"
 	"go.uber.org/zap"
 )
 
 /*
-Init initialies the Rate limit. In this implementation it leverages the Redis store but it can be quickly updated to use
-a different store implementation.
+Init initialies the Rate limit on the selected store. Redis shares the limits among all the application instances,
+while the in-memory store does not require any external service.
+If Redis is not ready, the connection is retried with an exponential backoff until the retry timeout is reached.
+Named policies are loaded from the given file, while the anonymous and user limits are applied
+to all the routes and users not covered by a policy.
 */
-func Init(rlConnectionURI string, rlAnonymousRate int, rlAnonymousMaxRequests int, rlUserRate int, rlUserMaxRequests int) {
-	zap.L().Info("Initializing Rate Limit Service on Redis. Connecting...", zap.String("service", "rate-limit"))
-	opt, err := redis.ParseURL(rlConnectionURI)
+func Init(rlStore string, rlConnectionURI string, rlConnectRetryTimeoutSeconds int, rlFailureMode string, rlMemoryMaxKeys int, rlAlgorithm string, rlHeadersStyle string, rlPoliciesFile string, rlAnonymousRate int, rlAnonymousMaxRequests int, rlUserRate int, rlUserMaxRequests int) {
+	zap.L().Info(fmt.Sprintf("Initializing Rate Limit Service on %s store...", rlStore), zap.String("service", "rate-limit"))
+	algorithm := Algorithm(rlAlgorithm)
+	if !slices.Contains(AvailableAlgorithms, interface{}(algorithm)) {
+		zap.L().Error(fmt.Sprintf("Invalid Rate Limit algorithm %s", rlAlgorithm), zap.String("service", "rate-limit"))
+		panic(fmt.Sprintf("Invalid Rate Limit algorithm %s", rlAlgorithm))
+	}
+	style := HeadersStyle(rlHeadersStyle)
+	if !slices.Contains(AvailableHeadersStyles, interface{}(style)) {
+		zap.L().Error(fmt.Sprintf("Invalid Rate Limit headers style %s", rlHeadersStyle), zap.String("service", "rate-limit"))
+		panic(fmt.Sprintf("Invalid Rate Limit headers style %s", rlHeadersStyle))
+	}
+	loadedPolicies, err := loadPolicies(rlPoliciesFile)
 	if err != nil {
-		zap.L().Error("Error during Rate Limit Service initalization", zap.String("service", "rate-limit"), zap.Error(err))
+		zap.L().Error("Error loading Rate Limit policies", zap.String("service", "rate-limit"), zap.Error(err))
 		panic(err)
 	}
-	client := redis.NewClient(opt)
-
-	ipRateLimitStore := newRedisRateLimit(redisRateLimitConfiguration{
-		RedisClient: client,
-		Rate:        time.Second * time.Duration(rlAnonymousRate),
-		Limit:       int64(rlAnonymousMaxRequests),
-	})
-
-	userRateLimitStore := newRedisRateLimit(redisRateLimitConfiguration{
-		RedisClient: client,
-		Rate:        time.Second * time.Duration(rlUserRate),
-		Limit:       int64(rlUserMaxRequests),
-	})
-
-	iPBasedRateLimit = ipRateLimitStore
-	userBasedRateLimit = userRateLimitStore
-	zap.L().Info("Rate Limit Service initialized on Redis. Connected!", zap.String("service", "rate-limit"))
+	switch Store(rlStore) {
+	case RedisStore:
+		failureMode := FailureMode(rlFailureMode)
+		if !slices.Contains(AvailableFailureModes, interface{}(failureMode)) {
+			zap.L().Error(fmt.Sprintf("Invalid Rate Limit failure mode %s", rlFailureMode), zap.String("service", "rate-limit"))
+			panic(fmt.Sprintf("Invalid Rate Limit failure mode %s", rlFailureMode))
+		}
+		zap.L().Info("Connecting Rate Limit Service to Redis...", zap.String("service", "rate-limit"))
+		client, err := bpredis.Connect(rlConnectionURI, rlConnectRetryTimeoutSeconds)
+		if err != nil {
+			zap.L().Error("Error during Rate Limit Service initalization", zap.String("service", "rate-limit"), zap.Error(err))
+			panic(err)
+		}
+		zap.L().Info("Rate Limit Service connected to Redis!", zap.String("service", "rate-limit"))
+		store = newRedisRateLimit(redisRateLimitConfiguration{
+			RedisClient: client,
+			Algorithm:   algorithm,
+			FailureMode: failureMode,
+		})
+	case MemoryStore:
+		store = newMemoryRateLimit(memoryRateLimitConfiguration{
+			MaxKeys:   rlMemoryMaxKeys,
+			Algorithm: algorithm,
+		})
+	default:
+		zap.L().Error(fmt.Sprintf("Invalid Rate Limit store %s", rlStore), zap.String("service", "rate-limit"))
+		panic(fmt.Sprintf("Invalid Rate Limit store %s", rlStore))
+	}
+	policies = loadedPolicies
+	headersStyle = style
+	defaultRules = policyRules{
+		Anonymous:     &policyLimit{TimeRangeSeconds: rlAnonymousRate, MaxRequests: rlAnonymousMaxRequests},
+		Authenticated: &policyLimit{TimeRangeSeconds: rlUserRate, MaxRequests: rlUserMaxRequests},
+	}
+	zap.L().Info("Rate Limit Service initialized!", zap.String("service", "rate-limit"), zap.String("store", rlStore), zap.Int("policies", len(policies)))
 }
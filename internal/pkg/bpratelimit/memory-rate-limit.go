@@ -0,0 +1,321 @@
+package bpratelimit
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+/*
+Number of shards of the in-memory store. Each shard has its own lock,
+so concurrent requests for different keys rarely wait for each other.
+*/
+const memoryShards = 32
+
+/*
+Expired entries of a shard are swept at most once per interval, while serving a request.
+*/
+const memorySweepInterval = time.Minute
+
+/*
+Number of entries inspected to pick the one to evict when a shard is full,
+approximating the eviction of the entry closest to its expiration as Redis does.
+*/
+const memoryEvictionSamples = 5
+
+/*
+MemoryRateLimitConfiguration represents a rate limit configuration
+taking into account the max number of keys to keep in memory and the algorithm to apply.
+*/
+type memoryRateLimitConfiguration struct {
+	MaxKeys   int
+	Algorithm Algorithm
+}
+
+/*
+MemoryRateLimit represents an actual implementation of rate limit stored in the process memory.
+It is meant for single-instance deployments, local development and tests,
+since limits are not shared among application instances.
+*/
+type memoryRateLimit struct {
+	config memoryRateLimitConfiguration
+	shards []*memoryShard
+	now    func() time.Time
+}
+
+type memoryShard struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	maxKeys   int
+	lastSweep time.Time
+}
+
+/*
+MemoryEntry stores the state of a key for all the algorithms, only the fields
+of the configured algorithm are used.
+*/
+type memoryEntry struct {
+	expiresAt time.Time
+	// Fixed window
+	count     int
+	windowEnd time.Time
+	// Sliding window log
+	requests []time.Time
+	// Sliding window counter
+	window        int64
+	currentCount  int
+	previousCount int
+	// Token bucket
+	tat time.Time
+}
+
+/*
+NewMemoryRateLimit creates a new Rate Limit stored in memory.
+*/
+func newMemoryRateLimit(config memoryRateLimitConfiguration) rateLimitInterface {
+	shards := make([]*memoryShard, memoryShards)
+	for i := range shards {
+		shards[i] = &memoryShard{
+			entries: map[string]*memoryEntry{},
+			maxKeys: max(config.MaxKeys/memoryShards, 1),
+		}
+	}
+	return memoryRateLimit{
+		config: config,
+		shards: shards,
+		now:    time.Now,
+	}
+}
+
+/*
+CanProceed represents the actual implementation of the method that verify
+if the request can proceed or need to be blocked due to many requests.
+The check and the count of the request are performed atomically under the lock of the key shard.
+*/
+func (r memoryRateLimit) canProceed(_ context.Context, key string, limit policyLimit) rateLimitResult {
+	return r.run(key, limit, true)
+}
+
+/*
+Usage returns the current usage of the key without counting a new request.
+*/
+func (r memoryRateLimit) usage(_ context.Context, key string, limit policyLimit) rateLimitResult {
+	return r.run(key, limit, false)
+}
+
+func (r memoryRateLimit) run(key string, limit policyLimit, consume bool) rateLimitResult {
+	now := r.now()
+	shard := r.shards[shardIndex(key)]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	shard.sweep(now)
+	entry, exists := shard.entries[key]
+	if !exists || !now.Before(entry.expiresAt) {
+		entry = &memoryEntry{}
+		// Inspecting the usage must not allocate new entries
+		if consume {
+			shard.makeRoom(now)
+			shard.entries[key] = entry
+		}
+	}
+	var outcome memoryOutcome
+	switch r.config.Algorithm {
+	case SlidingWindowLog:
+		outcome = entry.slidingWindowLog(now, limit, consume)
+	case SlidingWindowCounter:
+		outcome = entry.slidingWindowCounter(now, limit, consume)
+	case TokenBucket:
+		outcome = entry.tokenBucket(now, limit, consume)
+	default:
+		outcome = entry.fixedWindow(now, limit, consume)
+	}
+	result := rateLimitResult{
+		Allowed:   outcome.allowed,
+		Limit:     limit.MaxRequests,
+		Remaining: max(outcome.remaining, 0),
+		Reset:     ceilSeconds(outcome.reset),
+	}
+	if !outcome.allowed {
+		result.Remaining = 0
+		result.RetryAfter = retryAfterSeconds(outcome.retry)
+	}
+	return result
+}
+
+/*
+MemoryOutcome represents the outcome of an algorithm on a single entry.
+*/
+type memoryOutcome struct {
+	allowed   bool
+	retry     time.Duration
+	remaining int
+	reset     time.Duration
+}
+
+func shardIndex(key string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % memoryShards)
+}
+
+/*
+Remove the expired entries, at most once per sweep interval.
+*/
+func (s *memoryShard) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+/*
+Ensure there is room for a new entry, bounding the memory used by the shard.
+Expired entries are removed first, then the entry closest to its expiration among a few samples.
+*/
+func (s *memoryShard) makeRoom(now time.Time) {
+	if len(s.entries) < s.maxKeys {
+		return
+	}
+	s.lastSweep = time.Time{}
+	s.sweep(now)
+	samples := 0
+	for len(s.entries) >= s.maxKeys {
+		var evictKey string
+		var evictEntry *memoryEntry
+		for key, entry := range s.entries {
+			if evictEntry == nil || entry.expiresAt.Before(evictEntry.expiresAt) {
+				evictKey, evictEntry = key, entry
+			}
+			samples++
+			if samples%memoryEvictionSamples == 0 {
+				break
+			}
+		}
+		delete(s.entries, evictKey)
+	}
+}
+
+/*
+Count the requests in the current window, starting with the first request.
+*/
+func (e *memoryEntry) fixedWindow(now time.Time, limit policyLimit, consume bool) memoryOutcome {
+	if !now.Before(e.windowEnd) {
+		if !consume {
+			return memoryOutcome{allowed: true, remaining: limit.MaxRequests}
+		}
+		e.count = 0
+		e.windowEnd = now.Add(limit.rate())
+		e.expiresAt = e.windowEnd
+	}
+	reset := e.windowEnd.Sub(now)
+	if e.count >= limit.MaxRequests {
+		return memoryOutcome{retry: reset, reset: reset}
+	}
+	if consume {
+		e.count++
+	}
+	return memoryOutcome{allowed: true, remaining: limit.MaxRequests - e.count, reset: reset}
+}
+
+/*
+Store the time of each accepted request, removing the ones out of the window.
+*/
+func (e *memoryEntry) slidingWindowLog(now time.Time, limit policyLimit, consume bool) memoryOutcome {
+	windowStart := now.Add(-limit.rate())
+	firstInWindow := 0
+	for firstInWindow < len(e.requests) && !e.requests[firstInWindow].After(windowStart) {
+		firstInWindow++
+	}
+	e.requests = e.requests[firstInWindow:]
+	allowed := len(e.requests) < limit.MaxRequests
+	if allowed && consume {
+		e.requests = append(e.requests, now)
+		e.expiresAt = now.Add(limit.rate())
+	}
+	var reset time.Duration
+	if len(e.requests) > 0 {
+		reset = e.requests[len(e.requests)-1].Sub(windowStart)
+	}
+	if !allowed {
+		return memoryOutcome{retry: e.requests[0].Sub(windowStart), reset: reset}
+	}
+	return memoryOutcome{allowed: true, remaining: limit.MaxRequests - len(e.requests), reset: reset}
+}
+
+/*
+Keep a counter for the current and the previous window, estimating the requests
+in the sliding window by weighting the previous counter on the elapsed time.
+*/
+func (e *memoryEntry) slidingWindowCounter(now time.Time, limit policyLimit, consume bool) memoryOutcome {
+	window := limit.rate().Milliseconds()
+	nowMs := now.UnixMilli()
+	current := nowMs / window
+	previousCount, currentCount := e.previousCount, e.currentCount
+	if current != e.window {
+		if current == e.window+1 {
+			previousCount = e.currentCount
+		} else {
+			previousCount = 0
+		}
+		currentCount = 0
+	}
+	elapsed := nowMs - current*window
+	estimated := float64(previousCount)*float64(window-elapsed)/float64(window) + float64(currentCount)
+	allowed := estimated+1 <= float64(limit.MaxRequests)
+	if allowed && consume {
+		currentCount++
+		estimated++
+		e.window, e.previousCount, e.currentCount = current, previousCount, currentCount
+		e.expiresAt = time.UnixMilli((current + 2) * window)
+	}
+	var reset int64
+	if currentCount > 0 {
+		reset = 2*window - elapsed
+	} else if previousCount > 0 {
+		reset = window - elapsed
+	}
+	if !allowed {
+		retry := window - elapsed
+		if currentCount+1 <= limit.MaxRequests && previousCount > 0 {
+			retry = int64(math.Ceil(float64(window) - float64(limit.MaxRequests-1-currentCount)*float64(window)/float64(previousCount) - float64(elapsed)))
+		}
+		return memoryOutcome{retry: time.Duration(max(retry, 1)) * time.Millisecond, reset: time.Duration(reset) * time.Millisecond}
+	}
+	return memoryOutcome{
+		allowed:   true,
+		remaining: int(math.Floor(float64(limit.MaxRequests) - estimated)),
+		reset:     time.Duration(reset) * time.Millisecond,
+	}
+}
+
+/*
+Store the Theoretical Arrival Time (TAT) of the next request. Each request moves it forward
+by the emission interval, and requests are rejected when it exceeds the current time by more than the window.
+*/
+func (e *memoryEntry) tokenBucket(now time.Time, limit policyLimit, consume bool) memoryOutcome {
+	interval := limit.rate() / time.Duration(limit.MaxRequests)
+	tat := e.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(interval)
+	allowAt := newTat.Add(-limit.rate())
+	allowed := !allowAt.After(now)
+	if allowed && consume {
+		tat = newTat
+		e.tat = newTat
+		e.expiresAt = newTat
+	}
+	reset := tat.Sub(now)
+	if !allowed {
+		return memoryOutcome{retry: allowAt.Sub(now), reset: reset}
+	}
+	return memoryOutcome{allowed: true, remaining: int((limit.rate() - reset) / interval), reset: reset}
+}
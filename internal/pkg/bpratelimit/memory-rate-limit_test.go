@@ -0,0 +1,77 @@
+package bpratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitConcurrency(t *testing.T) {
+	for _, algorithm := range AvailableAlgorithms {
+		algorithm := algorithm.(Algorithm)
+		t.Run(string(algorithm), func(t *testing.T) {
+			rateLimit := newMemoryRateLimit(memoryRateLimitConfiguration{MaxKeys: 1000, Algorithm: algorithm})
+			limit := policyLimit{TimeRangeSeconds: 60, MaxRequests: 10}
+			var accepted atomic.Int64
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if rateLimit.canProceed(context.Background(), "user:test", limit).Allowed {
+						accepted.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+			if accepted.Load() != 10 {
+				t.Errorf("expected 10 accepted requests, got %d", accepted.Load())
+			}
+			result := rateLimit.canProceed(context.Background(), "user:test", limit)
+			if result.Allowed || result.RetryAfter < 1 || result.RetryAfter > 60 {
+				t.Errorf("expected rejection with a retry within the window, got %v and %d", result.Allowed, result.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimitExpiration(t *testing.T) {
+	now := time.Now()
+	rateLimit := memoryRateLimit{
+		config: memoryRateLimitConfiguration{MaxKeys: 1000, Algorithm: FixedWindow},
+		shards: newMemoryRateLimit(memoryRateLimitConfiguration{MaxKeys: 1000}).(memoryRateLimit).shards,
+		now:    func() time.Time { return now },
+	}
+	limit := policyLimit{TimeRangeSeconds: 10, MaxRequests: 1}
+	if !rateLimit.canProceed(context.Background(), "ip:test", limit).Allowed {
+		t.Fatal("expected the first request to proceed")
+	}
+	if result := rateLimit.canProceed(context.Background(), "ip:test", limit); result.Allowed || result.RetryAfter != 10 {
+		t.Fatalf("expected rejection with a retry after 10 seconds, got %v and %d", result.Allowed, result.RetryAfter)
+	}
+	now = now.Add(10 * time.Second)
+	if !rateLimit.canProceed(context.Background(), "ip:test", limit).Allowed {
+		t.Error("expected the request to proceed in the next window")
+	}
+}
+
+func TestMemoryRateLimitBoundedKeys(t *testing.T) {
+	rateLimit := newMemoryRateLimit(memoryRateLimitConfiguration{MaxKeys: memoryShards, Algorithm: FixedWindow}).(memoryRateLimit)
+	limit := policyLimit{TimeRangeSeconds: 60, MaxRequests: 1}
+	for i := 0; i < 1000; i++ {
+		rateLimit.canProceed(context.Background(), time.Duration(i).String(), limit)
+	}
+	for _, shard := range rateLimit.shards {
+		if len(shard.entries) > 1 {
+			t.Fatalf("expected at most 1 key per shard, got %d", len(shard.entries))
+		}
+	}
+}
+
+func TestMemoryRateLimitUsage(t *testing.T) {
+	testStoreUsage(t, func(t *testing.T, algorithm Algorithm) rateLimitInterface {
+		return newMemoryRateLimit(memoryRateLimitConfiguration{MaxKeys: 1000, Algorithm: algorithm})
+	})
+}
@@ -2,57 +2,75 @@ package bpratelimit
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"github.com/besasch88/blueprint/internal/pkg/bpopenapi"
 	"github.com/besasch88/blueprint/internal/pkg/bprouter"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-var iPBasedRateLimit rateLimitInterface
-var userBasedRateLimit rateLimitInterface
+var store rateLimitInterface
+var policies map[string]policy
+var defaultRules policyRules
+var headersStyle HeadersStyle
 
 /*
-RateLimitMiddleware is a middleware for APIs based on authenticated or anonymous users
+Names of the policies used by the registered routes, so the usage can be reported across all of them.
+*/
+var usedPolicies []string
+var usedPoliciesMu sync.Mutex
+
+/*
+RateLimitMiddleware is a middleware for APIs based on authenticated or anonymous users,
+applying the limits of the given named policy.
 
 In case of an anonymous user, we leverage its Real-IP
 In case of an authenticated user, we leverage its UUID
 
 The idea is to have 2 different rate limits for better workload control under different scenarios.
 
-In particular, for authenticated users, we can leverage custom configurations for specific companies.
-This is true when we are going to allow adding more users for one company.
+Policies can override the limits per HTTP method and per user claim, so specific roles or
+API key tiers (e.g. the users of a company with a custom plan) can have custom limits.
+Each policy counts requests separately, so calls to a route do not consume the limits of the others.
+If the policy is not configured, the default limits are applied.
+
+Every response carries the rate limit headers, so clients can throttle themselves proactively.
 
 Example of usage of this middleware:
 router.GET(
 
 	"/users/:userID",
-	tc_middleware.RateLimitMiddleware(),
+	tc_middleware.RateLimitMiddleware("user-read"),
 	... //other middlewares
 	func(ctx *gin.Context) {
 		... // your logic
 */
-func RateLimitMiddleware() gin.HandlerFunc {
+func RateLimitMiddleware(policyName string) gin.HandlerFunc {
+	p, exists := policies[policyName]
+	if !exists {
+		zap.L().Warn(fmt.Sprintf("Rate Limit policy %s not configured. Default limits applied", policyName), zap.String("service", "rate-limit"))
+	}
+	usedPoliciesMu.Lock()
+	if !slices.Contains(usedPolicies, policyName) {
+		usedPolicies = append(usedPolicies, policyName)
+	}
+	usedPoliciesMu.Unlock()
 	return func(ctx *gin.Context) {
 		// First we check if the requester is authenticated, so we leverage the right
 		// rate limit.
 		authUser := bpauth.GetAuthUserFromSession(ctx)
-		var canProceed bool
-		var waitTimeSeconds int64
-		var key string
-		if authUser != nil {
-			// We refer to the User ID as unique requester. In this way we can block
-			key = fmt.Sprintf("user:%s", authUser.ID.String())
-			canProceed, waitTimeSeconds = userBasedRateLimit.canProceed(ctx, key)
-		} else {
-			// Please check the documentation of ClientIP, from Nginx we can send the
-			// Real-IP header that is automatically considered in this scenario
-			key = fmt.Sprintf("ip:%s", ctx.ClientIP())
-			canProceed, waitTimeSeconds = iPBasedRateLimit.canProceed(ctx, key)
-		}
-		if !canProceed {
+		limit, perMethod := p.resolve(ctx.Request.Method, authUser, defaultRules)
+		key := requesterKey(ctx, policyName, ctx.Request.Method, perMethod, authUser)
+		result := store.canProceed(ctx, key, limit)
+		setHeaders(ctx, limit, result)
+		if !result.Allowed {
 			zap.L().Info(fmt.Sprintf("Rate Limit reached for %s. Abort...", key), zap.String("service", "rate-limit"))
-			bprouter.ReturnTooManyRequests(ctx, waitTimeSeconds)
+			bprouter.ReturnTooManyRequests(ctx, result.RetryAfter)
 			ctx.Abort()
 		} else {
 			zap.L().Info("Rate Limit not reached. Proceed...", zap.String("service", "rate-limit"))
@@ -60,3 +78,115 @@ func RateLimitMiddleware() gin.HandlerFunc {
 		}
 	}
 }
+
+/*
+Build the key identifying the requester within a policy. The method is part of the key
+when the limit comes from the method rules, so each method is counted separately.
+*/
+func requesterKey(ctx *gin.Context, policyName string, method string, perMethod bool, authUser *bpauth.AuthUser) string {
+	scope := policyName
+	if perMethod {
+		scope = fmt.Sprintf("%s:%s", policyName, method)
+	}
+	if authUser != nil {
+		// We refer to the User ID as unique requester. In this way we can block
+		return fmt.Sprintf("%s:user:%s", scope, authUser.ID.String())
+	}
+	// Please check the documentation of ClientIP, from Nginx we can send the
+	// Real-IP header that is automatically considered in this scenario
+	return fmt.Sprintf("%s:ip:%s", scope, ctx.ClientIP())
+}
+
+/*
+Set the rate limit headers following the configured style. Headers are skipped
+when the store is not reachable, since the usage is unknown.
+*/
+func setHeaders(ctx *gin.Context, limit policyLimit, result rateLimitResult) {
+	if result.Unavailable {
+		return
+	}
+	switch headersStyle {
+	case IETFHeaders:
+		ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("RateLimit-Reset", strconv.FormatInt(result.Reset, 10))
+		ctx.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.MaxRequests, limit.TimeRangeSeconds))
+	case LegacyHeaders:
+		ctx.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix()+result.Reset, 10))
+	}
+}
+
+/*
+RateLimitUsage represents the usage of a policy by the requester.
+Method is empty for the rules applied to all the methods without a specific override.
+*/
+type rateLimitUsage struct {
+	Policy           string `json:"policy"`
+	Method           string `json:"method,omitempty"`
+	Limit            int    `json:"limit"`
+	Remaining        int    `json:"remaining"`
+	TimeRangeSeconds int    `json:"timeRangeSeconds"`
+	ResetSeconds     int64  `json:"resetSeconds"`
+}
+
+/*
+UsageRoute describes the route exposing the UsageHandler, protected by the given claims. E.g.
+
+	bpopenapi.Handle(router, http.MethodGet, "/me/rate-limit", bpratelimit.UsageRoute([]string{bpauth.RateLimitGet}), bpratelimit.UsageHandler())
+*/
+func UsageRoute(claims []string) bpopenapi.Route {
+	return bpopenapi.Route{
+		OperationID: "getRateLimitUsage",
+		Summary:     "Get the rate limit usage",
+		Description: "The usage of the requester across all the policies, without consuming any request.",
+		Tags:        []string{"rate-limit"},
+		Claims:      claims,
+		Response:    []rateLimitUsage{},
+	}
+}
+
+/*
+UsageHandler returns the current usage of the requester across all the policies
+used by the registered routes, without consuming any request.
+It allows clients to throttle themselves proactively. E.g.
+
+	router.GET("/me/rate-limit", bpauth.AuthMiddleware([]string{bpauth.RateLimitGet}), bpratelimit.UsageHandler())
+*/
+func UsageHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authUser := bpauth.GetAuthUserFromSession(ctx)
+		usedPoliciesMu.Lock()
+		names := slices.Clone(usedPolicies)
+		usedPoliciesMu.Unlock()
+		slices.Sort(names)
+		items := []rateLimitUsage{}
+		for _, name := range names {
+			p := policies[name]
+			methods := []string{""}
+			for method := range p.Methods {
+				methods = append(methods, method)
+			}
+			slices.Sort(methods)
+			for _, method := range methods {
+				limit, perMethod := p.resolve(method, authUser, defaultRules)
+				key := requesterKey(ctx, name, method, perMethod, authUser)
+				result := store.usage(ctx, key, limit)
+				if result.Unavailable {
+					bprouter.ReturnGenericError(ctx)
+					return
+				}
+				items = append(items, rateLimitUsage{
+					Policy:           name,
+					Method:           method,
+					Limit:            result.Limit,
+					Remaining:        result.Remaining,
+					TimeRangeSeconds: limit.TimeRangeSeconds,
+					ResetSeconds:     result.Reset,
+				})
+			}
+		}
+		bprouter.ReturnOk(ctx, &gin.H{"items": items})
+	}
+}
@@ -0,0 +1,131 @@
+package bpratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+Configure the package as Init does, on the in-memory store.
+*/
+func setupMiddlewareTest(t *testing.T, style HeadersStyle, loaded map[string]policy) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	store = newMemoryRateLimit(memoryRateLimitConfiguration{MaxKeys: 1000, Algorithm: FixedWindow})
+	policies = loaded
+	headersStyle = style
+	defaultRules = policyRules{
+		Anonymous:     &policyLimit{TimeRangeSeconds: 60, MaxRequests: 2},
+		Authenticated: &policyLimit{TimeRangeSeconds: 60, MaxRequests: 2},
+	}
+	usedPolicies = nil
+	engine := gin.New()
+	engine.GET("/items", RateLimitMiddleware("item-read"), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	engine.GET("/me/rate-limit", UsageHandler())
+	return engine
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	t.Run("IETF headers", func(t *testing.T) {
+		engine := setupMiddlewareTest(t, IETFHeaders, map[string]policy{})
+		response := httptest.NewRecorder()
+		engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/items", nil))
+		expected := map[string]string{"RateLimit-Limit": "2", "RateLimit-Remaining": "1", "RateLimit-Reset": "60", "RateLimit-Policy": "2;w=60"}
+		for header, value := range expected {
+			if got := response.Header().Get(header); got != value {
+				t.Errorf("expected %s %s, got %s", header, value, got)
+			}
+		}
+	})
+	t.Run("Legacy headers and rejection", func(t *testing.T) {
+		engine := setupMiddlewareTest(t, LegacyHeaders, map[string]policy{})
+		var response *httptest.ResponseRecorder
+		for i := 0; i < 3; i++ {
+			response = httptest.NewRecorder()
+			engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/items", nil))
+		}
+		if response.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status 429, got %d", response.Code)
+		}
+		if response.Header().Get("X-RateLimit-Remaining") != "0" || response.Header().Get("X-RateLimit-Reset") == "" || response.Header().Get("Retry-After") != "60" {
+			t.Errorf("unexpected headers %v", response.Header())
+		}
+	})
+	t.Run("No headers", func(t *testing.T) {
+		engine := setupMiddlewareTest(t, NoHeaders, map[string]policy{})
+		response := httptest.NewRecorder()
+		engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/items", nil))
+		if response.Header().Get("RateLimit-Limit") != "" || response.Header().Get("X-RateLimit-Limit") != "" {
+			t.Errorf("unexpected headers %v", response.Header())
+		}
+	})
+}
+
+func TestRateLimitPerMethod(t *testing.T) {
+	engine := setupMiddlewareTest(t, IETFHeaders, map[string]policy{
+		"item-write": {
+			policyRules: policyRules{Anonymous: &policyLimit{TimeRangeSeconds: 60, MaxRequests: 3}},
+			Methods: map[string]policyRules{
+				"DELETE": {Anonymous: &policyLimit{TimeRangeSeconds: 60, MaxRequests: 1}},
+			},
+		},
+	})
+	handler := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	engine.PUT("/items", RateLimitMiddleware("item-write"), handler)
+	engine.DELETE("/items", RateLimitMiddleware("item-write"), handler)
+	send := func(method string) int {
+		response := httptest.NewRecorder()
+		engine.ServeHTTP(response, httptest.NewRequest(method, "/items", nil))
+		return response.Code
+	}
+	for i := 0; i < 3; i++ {
+		if code := send(http.MethodPut); code != http.StatusOK {
+			t.Fatalf("expected PUT %d to proceed, got %d", i, code)
+		}
+	}
+	if code := send(http.MethodDelete); code != http.StatusOK {
+		t.Fatalf("expected DELETE not to share the PUT requests, got %d", code)
+	}
+	if code := send(http.MethodDelete); code != http.StatusTooManyRequests {
+		t.Errorf("expected the DELETE limit to be reached, got %d", code)
+	}
+	if code := send(http.MethodPut); code != http.StatusTooManyRequests {
+		t.Errorf("expected the PUT limit to be reached, got %d", code)
+	}
+}
+
+func TestUsageHandler(t *testing.T) {
+	engine := setupMiddlewareTest(t, IETFHeaders, map[string]policy{
+		"item-read": {Methods: map[string]policyRules{
+			"POST": {Anonymous: &policyLimit{TimeRangeSeconds: 10, MaxRequests: 5}},
+		}},
+	})
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+	for i := 0; i < 2; i++ {
+		response := httptest.NewRecorder()
+		engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/me/rate-limit", nil))
+		var body struct {
+			Items []rateLimitUsage `json:"items"`
+		}
+		if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		expected := []rateLimitUsage{
+			{Policy: "item-read", Limit: 2, Remaining: 1, TimeRangeSeconds: 60, ResetSeconds: 60},
+			{Policy: "item-read", Method: "POST", Limit: 5, Remaining: 5, TimeRangeSeconds: 10, ResetSeconds: 0},
+		}
+		if len(body.Items) != len(expected) {
+			t.Fatalf("expected %d items, got %+v", len(expected), body.Items)
+		}
+		for j := range expected {
+			if body.Items[j] != expected[j] {
+				t.Errorf("expected %+v, got %+v", expected[j], body.Items[j])
+			}
+		}
+	}
+}
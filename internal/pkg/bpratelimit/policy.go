@@ -0,0 +1,167 @@
+package bpratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+PolicyLimit represents the max number of requests allowed in a time range.
+*/
+type policyLimit struct {
+	TimeRangeSeconds int `yaml:"time_range_seconds"`
+	MaxRequests      int `yaml:"max_requests"`
+}
+
+/*
+PolicyClaimLimit represents a limit applied to the authenticated users owning a specific claim,
+e.g. a role or an API key tier.
+*/
+type policyClaimLimit struct {
+	Claim       string `yaml:"claim"`
+	policyLimit `yaml:",inline"`
+}
+
+/*
+PolicyRules represents the limits of a policy for anonymous and authenticated users.
+Each limit is optional, falling back to the less specific one when missing.
+*/
+type policyRules struct {
+	Anonymous     *policyLimit       `yaml:"anonymous"`
+	Authenticated *policyLimit       `yaml:"authenticated"`
+	Claims        []policyClaimLimit `yaml:"claims"`
+}
+
+/*
+Policy represents a named set of rules, optionally overridden per HTTP method.
+*/
+type policy struct {
+	policyRules `yaml:",inline"`
+	Methods     map[string]policyRules `yaml:"methods"`
+}
+
+/*
+PolicyFile represents the content of the file where policies are configured. E.g.
+
+	policies:
+	  user-write:
+	    anonymous: { time_range_seconds: 60, max_requests: 10 }
+	    authenticated: { time_range_seconds: 60, max_requests: 30 }
+	    claims:
+	      - { claim: tier-premium, time_range_seconds: 60, max_requests: 300 }
+	    methods:
+	      DELETE:
+	        authenticated: { time_range_seconds: 60, max_requests: 5 }
+*/
+type policyFile struct {
+	Policies map[string]policy `yaml:"policies"`
+}
+
+/*
+Load the policies from a YAML (or JSON) file. An empty path means no policies,
+so every route leverages the default limits.
+*/
+func loadPolicies(path string) (map[string]policy, error) {
+	if path == "" {
+		return map[string]policy{}, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file policyFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+	for name, p := range file.Policies {
+		if err := p.policyRules.validate(); err != nil {
+			return nil, fmt.Errorf("policy %s: %w", name, err)
+		}
+		methods := map[string]policyRules{}
+		for method, rules := range p.Methods {
+			method = strings.ToUpper(method)
+			if !slices.Contains(availableMethods, method) {
+				return nil, fmt.Errorf("policy %s: invalid method %s", name, method)
+			}
+			if err := rules.validate(); err != nil {
+				return nil, fmt.Errorf("policy %s, method %s: %w", name, method, err)
+			}
+			methods[method] = rules
+		}
+		p.Methods = methods
+		file.Policies[name] = p
+	}
+	return file.Policies, nil
+}
+
+var availableMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+func (r policyRules) validate() error {
+	limits := []*policyLimit{r.Anonymous, r.Authenticated}
+	for _, claimLimit := range r.Claims {
+		if claimLimit.Claim == "" {
+			return fmt.Errorf("claim is required")
+		}
+		limits = append(limits, &claimLimit.policyLimit)
+	}
+	for _, limit := range limits {
+		if limit != nil && (limit.TimeRangeSeconds <= 0 || limit.MaxRequests <= 0) {
+			return fmt.Errorf("time range and max requests must be greater than zero")
+		}
+	}
+	return nil
+}
+
+func (l policyLimit) rate() time.Duration {
+	return time.Duration(l.TimeRangeSeconds) * time.Second
+}
+
+/*
+Resolve the limit to apply to a request. The most specific rule wins:
+  - for authenticated users, the first claim limit matching one of their claims,
+    looking at the method rules before the policy ones;
+  - otherwise the anonymous or authenticated limit of the method, of the policy
+    and finally the default one.
+
+It also reports whether the limit comes from the method rules, since the requests
+of each method are counted separately in that case.
+*/
+func (p policy) resolve(method string, authUser *bpauth.AuthUser, defaults policyRules) (policyLimit, bool) {
+	rules := []policyRules{}
+	methodRules, perMethod := p.Methods[method]
+	if perMethod {
+		rules = append(rules, methodRules)
+	}
+	rules = append(rules, p.policyRules, defaults)
+	if authUser == nil {
+		for i, r := range rules {
+			if r.Anonymous != nil {
+				return *r.Anonymous, perMethod && i == 0
+			}
+		}
+		return policyLimit{}, false
+	}
+	for i, r := range rules {
+		for _, claimLimit := range r.Claims {
+			if slices.Contains(authUser.Claims, claimLimit.Claim) {
+				return claimLimit.policyLimit, perMethod && i == 0
+			}
+		}
+	}
+	for i, r := range rules {
+		if r.Authenticated != nil {
+			return *r.Authenticated, perMethod && i == 0
+		}
+	}
+	return policyLimit{}, false
+}
@@ -0,0 +1,86 @@
+package bpratelimit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+)
+
+func TestLoadPolicies(t *testing.T) {
+	t.Run("Example file is valid", func(t *testing.T) {
+		loaded, err := loadPolicies("../../../scripts/rate-limit-policies.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := loaded["user-write"].Methods["DELETE"]; !ok {
+			t.Error("expected the DELETE rules of the user-write policy")
+		}
+	})
+	t.Run("Invalid limits are rejected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "policies.yaml")
+		content := "policies:\n  user-write:\n    anonymous: { time_range_seconds: 60, max_requests: 0 }\n"
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadPolicies(path); err == nil {
+			t.Error("expected an error for a zero limit")
+		}
+	})
+	t.Run("Invalid methods are rejected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "policies.yaml")
+		content := "policies:\n  user-write:\n    methods:\n      FETCH: {}\n"
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadPolicies(path); err == nil {
+			t.Error("expected an error for an unknown method")
+		}
+	})
+}
+
+func TestPolicyResolve(t *testing.T) {
+	defaults := policyRules{
+		Anonymous:     &policyLimit{TimeRangeSeconds: 60, MaxRequests: 1},
+		Authenticated: &policyLimit{TimeRangeSeconds: 60, MaxRequests: 2},
+	}
+	p := policy{
+		policyRules: policyRules{
+			Authenticated: &policyLimit{TimeRangeSeconds: 60, MaxRequests: 3},
+			Claims:        []policyClaimLimit{{Claim: "tier-premium", policyLimit: policyLimit{TimeRangeSeconds: 60, MaxRequests: 4}}},
+		},
+		Methods: map[string]policyRules{
+			"DELETE": {Authenticated: &policyLimit{TimeRangeSeconds: 60, MaxRequests: 5}},
+		},
+	}
+	user := &bpauth.AuthUser{}
+	premiumUser := &bpauth.AuthUser{Claims: []string{"tier-premium"}}
+
+	cases := []struct {
+		name      string
+		policy    policy
+		method    string
+		authUser  *bpauth.AuthUser
+		expected  int
+		perMethod bool
+	}{
+		{"Anonymous falls back to defaults", p, "GET", nil, 1, false},
+		{"Authenticated uses the policy", p, "GET", user, 3, false},
+		{"Method overrides the policy", p, "DELETE", user, 5, true},
+		{"Claim overrides the policy", p, "GET", premiumUser, 4, false},
+		{"Claim overrides the method", p, "DELETE", premiumUser, 4, false},
+		{"Missing policy uses defaults", policy{}, "GET", user, 2, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			limit, perMethod := c.policy.resolve(c.method, c.authUser, defaults)
+			if limit.MaxRequests != c.expected {
+				t.Errorf("expected %d max requests, got %d", c.expected, limit.MaxRequests)
+			}
+			if perMethod != c.perMethod {
+				t.Errorf("expected per method %t, got %t", c.perMethod, perMethod)
+			}
+		})
+	}
+}
@@ -1,12 +1,122 @@
 package bpratelimit
 
-import "github.com/gin-gonic/gin"
+import "context"
 
 /*
 RateLimitInterface represents a generic interface to be implementeed
 that determins the rules to proceed in the request or stop it
-due to too many requests.
+due to too many requests, based on the limit resolved for the request.
+The usage method returns the same information without counting a new request.
 */
 type rateLimitInterface interface {
-	canProceed(ctx *gin.Context, key string) (bool, int64)
+	canProceed(ctx context.Context, key string, limit policyLimit) rateLimitResult
+	usage(ctx context.Context, key string, limit policyLimit) rateLimitResult
 }
+
+/*
+RateLimitResult represents the outcome of a rate limit check.
+When the request cannot proceed, RetryAfter contains the number of seconds to wait before retrying.
+Reset contains the number of seconds before the usage is fully restored.
+Unavailable is true when the store could not be reached, so the usage is unknown.
+*/
+type rateLimitResult struct {
+	Allowed     bool
+	Limit       int
+	Remaining   int
+	RetryAfter  int64
+	Reset       int64
+	Unavailable bool
+}
+
+/*
+Algorithm represents the strategy used to count requests and decide if a new one can proceed.
+*/
+type Algorithm string
+
+/*
+List of available rate limit algorithms.
+  - FixedWindow counts requests in consecutive windows of fixed size.
+  - SlidingWindowLog stores the time of each request in the last window, being the most accurate but memory expensive.
+  - SlidingWindowCounter estimates the requests in the last window by weighting the previous window counter.
+  - TokenBucket leverages the Generic Cell Rate Algorithm (GCRA), allowing bursts up to the limit
+    and then spreading requests evenly across the window.
+*/
+const (
+	FixedWindow          Algorithm = "fixed-window"
+	SlidingWindowLog     Algorithm = "sliding-window-log"
+	SlidingWindowCounter Algorithm = "sliding-window-counter"
+	TokenBucket          Algorithm = "token-bucket"
+)
+
+/*
+AvailableAlgorithms represents a list of available rate limit algorithms. It is generally used
+to validate the configuration provided at startup.
+*/
+var AvailableAlgorithms = []interface{}{FixedWindow, SlidingWindowLog, SlidingWindowCounter, TokenBucket}
+
+/*
+Store represents where the state of the rate limits is kept.
+*/
+type Store string
+
+/*
+List of available rate limit stores.
+  - RedisStore shares the limits among all the application instances.
+  - MemoryStore keeps the limits in the process memory, for single-instance deployments, local development and tests.
+*/
+const (
+	RedisStore  Store = "redis"
+	MemoryStore Store = "memory"
+)
+
+/*
+AvailableStores represents a list of available rate limit stores. It is generally used
+to validate the configuration provided at startup.
+*/
+var AvailableStores = []interface{}{RedisStore, MemoryStore}
+
+/*
+FailureMode represents the behaviour of the rate limit when the store is not reachable.
+*/
+type FailureMode string
+
+/*
+List of available failure modes.
+  - FailOpen lets requests proceed, preferring availability over protection.
+  - FailClosed rejects requests, preferring protection over availability.
+*/
+const (
+	FailOpen   FailureMode = "open"
+	FailClosed FailureMode = "closed"
+)
+
+/*
+AvailableFailureModes represents a list of available failure modes. It is generally used
+to validate the configuration provided at startup.
+*/
+var AvailableFailureModes = []interface{}{FailOpen, FailClosed}
+
+/*
+HeadersStyle represents the headers used to communicate the rate limit usage to clients.
+*/
+type HeadersStyle string
+
+/*
+List of available headers styles.
+  - IETFHeaders follows the IETF draft, with RateLimit-Limit, RateLimit-Remaining,
+    RateLimit-Reset (seconds until the reset) and RateLimit-Policy headers.
+  - LegacyHeaders uses the widespread X-RateLimit-Limit, X-RateLimit-Remaining and
+    X-RateLimit-Reset (Unix time of the reset) headers.
+  - NoHeaders does not send any header, except Retry-After when the request is rejected.
+*/
+const (
+	IETFHeaders   HeadersStyle = "ietf"
+	LegacyHeaders HeadersStyle = "x-ratelimit"
+	NoHeaders     HeadersStyle = "none"
+)
+
+/*
+AvailableHeadersStyles represents a list of available headers styles. It is generally used
+to validate the configuration provided at startup.
+*/
+var AvailableHeadersStyles = []interface{}{IETFHeaders, LegacyHeaders, NoHeaders}
@@ -0,0 +1,35 @@
+package bpratelimit
+
+import (
+	"context"
+	"testing"
+)
+
+/*
+Verify the usage reported by a store, for all the algorithms, both when counting
+requests and when only inspecting the usage.
+*/
+func testStoreUsage(t *testing.T, newStore func(t *testing.T, algorithm Algorithm) rateLimitInterface) {
+	for _, algorithm := range AvailableAlgorithms {
+		algorithm := algorithm.(Algorithm)
+		t.Run(string(algorithm), func(t *testing.T) {
+			rateLimit := newStore(t, algorithm)
+			limit := policyLimit{TimeRangeSeconds: 60, MaxRequests: 10}
+			if result := rateLimit.usage(context.Background(), "user:test", limit); !result.Allowed || result.Remaining != 10 || result.Reset != 0 {
+				t.Fatalf("expected a full quota, got %+v", result)
+			}
+			var result rateLimitResult
+			for i := 0; i < 3; i++ {
+				result = rateLimit.canProceed(context.Background(), "user:test", limit)
+			}
+			if !result.Allowed || result.Limit != 10 || result.Remaining != 7 || result.Reset < 1 || result.Reset > 120 {
+				t.Fatalf("expected 7 remaining requests, got %+v", result)
+			}
+			for i := 0; i < 2; i++ {
+				if result := rateLimit.usage(context.Background(), "user:test", limit); result.Remaining != 7 {
+					t.Fatalf("expected the usage not to consume requests, got %+v", result)
+				}
+			}
+		})
+	}
+}
@@ -1,20 +1,24 @@
 package bpratelimit
 
 import (
+	"context"
+	"fmt"
 	"time"
 
-	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 )
 
 /*
 RedisRateLimitConfiguration represents a rate limit configuration
-taking into account the client to store requests and their limits.
+taking into account the client to store requests, the algorithm to apply
+and how to behave when Redis is not reachable.
 */
 type redisRateLimitConfiguration struct {
 	RedisClient *redis.Client
-	Rate        time.Duration
-	Limit       int64
+	Algorithm   Algorithm
+	FailureMode FailureMode
 }
 
 /*
@@ -22,37 +26,71 @@ RedisRateLimit represents an actual implementation of rate limit based on Redis.
 */
 type redisRateLimit struct {
 	config redisRateLimitConfiguration
+	script *redis.Script
 }
 
 /*
 NewRedisRateLimit creates a new Rate Limit based on Redis.
 */
 func newRedisRateLimit(config redisRateLimitConfiguration) rateLimitInterface {
+	scripts := map[Algorithm]*redis.Script{
+		FixedWindow:          fixedWindowScript,
+		SlidingWindowLog:     slidingWindowLogScript,
+		SlidingWindowCounter: slidingWindowCounterScript,
+		TokenBucket:          tokenBucketScript,
+	}
 	return redisRateLimit{
 		config: config,
+		script: scripts[config.Algorithm],
 	}
 }
 
 /*
 CanProceed represents the actual implementation of the method that verify
 if the request can proceed or need to be blocked due to many requests.
+The check and the count of the request are performed atomically via a Lua script.
 */
-func (r redisRateLimit) canProceed(ctx *gin.Context, key string) (bool, int64) {
-	now := time.Now().Unix()
-	redisClient := r.config.RedisClient
-	numberOfRequests := redisClient.LLen(ctx, key).Val()
-	if numberOfRequests >= r.config.Limit {
-		wait := redisClient.TTL(ctx, key).Val().Seconds()
-		return false, int64(wait)
+func (r redisRateLimit) canProceed(ctx context.Context, key string, limit policyLimit) rateLimitResult {
+	return r.run(ctx, key, limit, 1)
+}
+
+/*
+Usage returns the current usage of the key without counting a new request.
+*/
+func (r redisRateLimit) usage(ctx context.Context, key string, limit policyLimit) rateLimitResult {
+	return r.run(ctx, key, limit, 0)
+}
+
+func (r redisRateLimit) run(ctx context.Context, key string, limit policyLimit, cost int) rateLimitResult {
+	// Keys are prefixed by the algorithm, since each one stores a different data structure.
+	redisKey := fmt.Sprintf("rate-limit:%s:%s", r.config.Algorithm, key)
+	result, err := r.script.Run(ctx, r.config.RedisClient, []string{redisKey}, limit.MaxRequests, limit.rate().Milliseconds(), uuid.NewString(), cost).Int64Slice()
+	if err != nil {
+		zap.L().Error("Rate Limit check failed", zap.String("service", "rate-limit"), zap.String("failureMode", string(r.config.FailureMode)), zap.Error(err))
+		if r.config.FailureMode == FailClosed {
+			return rateLimitResult{Allowed: false, Limit: limit.MaxRequests, RetryAfter: 1, Unavailable: true}
+		}
+		return rateLimitResult{Allowed: true, Limit: limit.MaxRequests, Unavailable: true}
 	}
-	if redisClient.Exists(ctx, key).Val() == 0 {
-		redisClient.TxPipelined(ctx, func(p redis.Pipeliner) error {
-			p.RPush(ctx, key, now)
-			p.Expire(ctx, key, r.config.Rate)
-			return nil
-		})
-	} else {
-		redisClient.RPushX(ctx, key, now)
+	outcome := rateLimitResult{
+		Allowed:   result[0] == 1,
+		Limit:     limit.MaxRequests,
+		Remaining: int(result[2]),
+		Reset:     ceilSeconds(time.Duration(result[3]) * time.Millisecond),
 	}
-	return true, 0
+	if !outcome.Allowed {
+		outcome.RetryAfter = retryAfterSeconds(time.Duration(result[1]) * time.Millisecond)
+	}
+	return outcome
+}
+
+/*
+Round up the time to wait to the next second, so clients never retry too early.
+*/
+func retryAfterSeconds(wait time.Duration) int64 {
+	return max(ceilSeconds(wait), 1)
+}
+
+func ceilSeconds(duration time.Duration) int64 {
+	return int64((duration + time.Second - 1) / time.Second)
 }
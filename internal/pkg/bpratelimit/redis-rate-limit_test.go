@@ -0,0 +1,98 @@
+package bpratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+The bptest package cannot be used here since it depends on this package.
+*/
+func newTestRedisClient(t *testing.T) *redis.Client {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return client
+}
+
+func TestRedisRateLimitConcurrency(t *testing.T) {
+	for _, algorithm := range AvailableAlgorithms {
+		algorithm := algorithm.(Algorithm)
+		t.Run(string(algorithm), func(t *testing.T) {
+			rateLimit := newRedisRateLimit(redisRateLimitConfiguration{
+				RedisClient: newTestRedisClient(t),
+				Algorithm:   algorithm,
+			})
+
+			limit := policyLimit{TimeRangeSeconds: 60, MaxRequests: 10}
+			var accepted atomic.Int64
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if rateLimit.canProceed(context.Background(), "user:test", limit).Allowed {
+						accepted.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+			if accepted.Load() != 10 {
+				t.Errorf("expected 10 accepted requests, got %d", accepted.Load())
+			}
+
+			result := rateLimit.canProceed(context.Background(), "user:test", limit)
+			if result.Allowed || result.RetryAfter < 1 || result.RetryAfter > 60 {
+				t.Errorf("expected rejection with a retry within the window, got %v and %d", result.Allowed, result.RetryAfter)
+			}
+			// Other keys are not affected
+			if !rateLimit.canProceed(context.Background(), "user:other", limit).Allowed {
+				t.Error("expected a different key to proceed")
+			}
+		})
+	}
+}
+
+func TestTokenBucketRetryAfter(t *testing.T) {
+	rateLimit := newRedisRateLimit(redisRateLimitConfiguration{
+		RedisClient: newTestRedisClient(t),
+		Algorithm:   TokenBucket,
+	})
+	limit := policyLimit{TimeRangeSeconds: 10, MaxRequests: 2}
+	rateLimit.canProceed(context.Background(), "ip:test", limit)
+	rateLimit.canProceed(context.Background(), "ip:test", limit)
+	// One token is given back every 5 seconds
+	result := rateLimit.canProceed(context.Background(), "ip:test", limit)
+	if result.Allowed || result.RetryAfter != 5 {
+		t.Errorf("expected rejection with a retry after 5 seconds, got %v and %d", result.Allowed, result.RetryAfter)
+	}
+}
+
+func TestRedisRateLimitFailureMode(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	limit := policyLimit{TimeRangeSeconds: 60, MaxRequests: 10}
+	failOpen := newRedisRateLimit(redisRateLimitConfiguration{RedisClient: client, Algorithm: FixedWindow, FailureMode: FailOpen})
+	if !failOpen.canProceed(context.Background(), "ip:test", limit).Allowed {
+		t.Error("expected the request to proceed when failing open")
+	}
+	failClosed := newRedisRateLimit(redisRateLimitConfiguration{RedisClient: client, Algorithm: FixedWindow, FailureMode: FailClosed})
+	if result := failClosed.canProceed(context.Background(), "ip:test", limit); result.Allowed || result.RetryAfter != 1 {
+		t.Errorf("expected rejection when failing closed, got %v and %d", result.Allowed, result.RetryAfter)
+	}
+}
+
+func TestRedisRateLimitUsage(t *testing.T) {
+	testStoreUsage(t, func(t *testing.T, algorithm Algorithm) rateLimitInterface {
+		return newRedisRateLimit(redisRateLimitConfiguration{RedisClient: newTestRedisClient(t), Algorithm: algorithm})
+	})
+}
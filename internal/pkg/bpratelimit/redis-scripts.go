@@ -0,0 +1,147 @@
+package bpratelimit
+
+import "github.com/redis/go-redis/v9"
+
+/*
+All the scripts are executed atomically by Redis, so concurrent requests cannot exceed the limit.
+They receive the limit as ARGV[1], the window in milliseconds as ARGV[2] and the cost of the request as ARGV[4]:
+1 to count the request, 0 to only inspect the current usage without consuming it.
+They return a list containing 1 if the request can proceed (0 otherwise), the milliseconds to wait before retrying,
+the remaining requests and the milliseconds before the usage is fully reset.
+Time is read from Redis to avoid clock skews between application instances. Big numbers are always
+formatted via string.format, since Redis converts Lua numbers to strings with a limited precision.
+*/
+
+/*
+Count the requests in the current window, starting with the first request.
+*/
+var fixedWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local cost = tonumber(ARGV[4])
+local count
+if cost > 0 then
+	count = redis.call('INCR', KEYS[1])
+	if count == 1 then
+		redis.call('PEXPIRE', KEYS[1], window)
+	end
+else
+	count = tonumber(redis.call('GET', KEYS[1]) or '0')
+end
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl == -1 then
+	redis.call('PEXPIRE', KEYS[1], window)
+	ttl = window
+elseif ttl < 0 then
+	ttl = 0
+end
+if count > limit or (cost == 0 and count == limit) then
+	return {0, ttl, 0, ttl}
+end
+return {1, 0, limit - count, ttl}
+`)
+
+/*
+Store the time of each accepted request in a sorted set, removing the ones out of the window.
+ARGV[3] is a unique value to avoid collisions between requests received in the same microsecond.
+*/
+var slidingWindowLogScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2]) * 1000
+local cost = tonumber(ARGV[4])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', string.format('%.0f', now - window))
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = count < limit
+if allowed and cost > 0 then
+	redis.call('ZADD', KEYS[1], string.format('%.0f', now), time[1] .. time[2] .. ARGV[3])
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	count = count + 1
+end
+local reset = 0
+if count > 0 then
+	local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+	reset = math.ceil((tonumber(newest[2]) + window - now) / 1000)
+end
+if allowed then
+	return {1, 0, limit - count, reset}
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {0, math.ceil((tonumber(oldest[2]) + window - now) / 1000), 0, reset}
+`)
+
+/*
+Keep a counter for the current and the previous window in a hash, estimating the requests
+in the sliding window by weighting the previous counter on the elapsed time.
+*/
+var slidingWindowCounterScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local cost = tonumber(ARGV[4])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local current = math.floor(now / window)
+local currentField = string.format('%.0f', current)
+local previousField = string.format('%.0f', current - 1)
+local elapsed = now - current * window
+local previousCount = tonumber(redis.call('HGET', KEYS[1], previousField) or '0')
+local currentCount = tonumber(redis.call('HGET', KEYS[1], currentField) or '0')
+local estimated = previousCount * (window - elapsed) / window + currentCount
+local allowed = estimated + 1 <= limit
+if allowed and cost > 0 then
+	redis.call('HINCRBY', KEYS[1], currentField, 1)
+	for _, field in ipairs(redis.call('HKEYS', KEYS[1])) do
+		if field ~= currentField and field ~= previousField then
+			redis.call('HDEL', KEYS[1], field)
+		end
+	end
+	redis.call('PEXPIRE', KEYS[1], window * 2)
+	currentCount = currentCount + 1
+	estimated = estimated + 1
+end
+local reset = 0
+if currentCount > 0 then
+	reset = 2 * window - elapsed
+elseif previousCount > 0 then
+	reset = window - elapsed
+end
+if allowed then
+	return {1, 0, math.max(math.floor(limit - estimated), 0), reset}
+end
+local retry = window - elapsed
+if currentCount + 1 <= limit and previousCount > 0 then
+	retry = math.ceil(window - (limit - 1 - currentCount) * window / previousCount - elapsed)
+end
+return {0, math.max(retry, 1), 0, reset}
+`)
+
+/*
+Store the Theoretical Arrival Time (TAT) of the next request. Each request moves it forward
+by the emission interval, and requests are rejected when it exceeds the current time by more than the window.
+*/
+var tokenBucketScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local cost = tonumber(ARGV[4])
+local interval = window / limit
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + tonumber(time[2]) / 1000
+local tat = tonumber(redis.call('GET', KEYS[1]) or '0')
+if tat < now then
+	tat = now
+end
+local newTat = tat + interval
+local allowAt = newTat - window
+local allowed = allowAt <= now
+if allowed and cost > 0 then
+	redis.call('SET', KEYS[1], string.format('%.3f', newTat), 'PX', math.ceil(newTat - now))
+	tat = newTat
+end
+local remaining = math.max(math.floor((now + window - tat) / interval + 0.000001), 0)
+local reset = math.ceil(tat - now)
+if allowed then
+	return {1, 0, remaining, reset}
+end
+return {0, math.ceil(allowAt - now), 0, reset}
+`)
@@ -0,0 +1,56 @@
+package bpredis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bputils"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+/*
+Clients already connected, by connection URI.
+*/
+var clients = map[string]*redis.Client{}
+var clientsMu sync.Mutex
+
+/*
+Connect returns a client of the Redis at the given URI. If Redis is not ready, the connection is retried
+with an exponential backoff until the retry timeout is reached.
+Clients are shared by URI, so the services configured on the same Redis (e.g. the cache and the idempotency,
+using the one of the rate limit by default) share a single connection pool. E.g.
+
+	client, err := bpredis.Connect(envs.CacheRedisConnectionURI, envs.CacheRedisConnectRetryTimeoutSeconds)
+*/
+func Connect(connectionURI string, connectRetryTimeoutSeconds int) (*redis.Client, error) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	client, exists := clients[connectionURI]
+	if !exists {
+		opt, err := redis.ParseURL(connectionURI)
+		if err != nil {
+			return nil, err
+		}
+		client = redis.NewClient(opt)
+	}
+	// Shared clients are pinged too, as the connections of their pool may be broken in the meantime.
+	err := bputils.RetryWithBackoff(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		errPing := client.Ping(ctx).Err()
+		if errPing != nil {
+			zap.L().Warn("Redis not ready yet. Retry...", zap.String("service", "redis"), zap.Error(errPing))
+		}
+		return errPing
+	}, 500*time.Millisecond, 10*time.Second, time.Duration(connectRetryTimeoutSeconds)*time.Second)
+	if err != nil {
+		if !exists {
+			client.Close()
+		}
+		return nil, err
+	}
+	clients[connectionURI] = client
+	return client, nil
+}
@@ -0,0 +1,36 @@
+package bpredis
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestConnect(t *testing.T) {
+	server := miniredis.RunT(t)
+	uri := "redis://" + server.Addr() + "/0"
+	client, err := Connect(uri, 0)
+	if err != nil {
+		t.Fatalf("unable to connect: %v", err)
+	}
+	shared, err := Connect(uri, 0)
+	if err != nil {
+		t.Fatalf("unable to connect again: %v", err)
+	}
+	if shared != client {
+		t.Errorf("expected the client to be shared by URI")
+	}
+	other, err := Connect("redis://"+server.Addr()+"/1", 0)
+	if err != nil {
+		t.Fatalf("unable to connect to another database: %v", err)
+	}
+	if other == client {
+		t.Errorf("expected a different client for a different URI")
+	}
+	if _, err := Connect("http://"+server.Addr(), 0); err == nil {
+		t.Errorf("expected an error for an invalid URI")
+	}
+	if _, err := Connect("redis://127.0.0.1:1/0", 0); err == nil {
+		t.Errorf("expected an error when Redis is not reachable")
+	}
+}
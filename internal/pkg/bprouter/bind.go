@@ -0,0 +1,245 @@
+package bprouter
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"github.com/gin-gonic/gin"
+)
+
+/*
+Code returned for the parameters whose value cannot be converted into the type of the DTO field.
+*/
+const invalidTypeCode = "invalid-type"
+
+/*
+Code returned for the JSON fields not defined in the DTO, when the strict binding is enabled.
+*/
+const unknownFieldCode = "unknown-field"
+
+var maxBodyBytes int64 = 1 << 20
+var strictBinding = false
+
+/*
+Binder can be implemented by the DTOs requiring a custom binding. When implemented,
+it replaces the default binding, while the limit on the body size is still applied. E.g.
+
+	func (r *getUserInputDto) BindRequest(ctx *gin.Context) error {
+		r.id = ctx.Param("userID")
+		return nil
+	}
+*/
+type Binder interface {
+	BindRequest(ctx *gin.Context) error
+}
+
+/*
+BindParameters accept a pointer to a DTO and tries to populate it with all the
+parameters found in the JSON payload, URI, Query params and Headers, in this order,
+so parameters in the URI cannot be overridden by the payload.
+
+The JSON payload is decoded in the exported fields following the `json` tags, while URI, Query params
+and Headers are bound via the `uri`, `form` and `header` tags. Only exported fields are bound, so parameters
+not coming from the payload are tagged with `json:"-"`, while DTOs needing private state can implement Binder.
+
+It returns a bad request error for malformed payloads, a request too large error for payloads bigger
+than the max size and a validation error for values not matching the type of the fields, so the result
+can be returned as is via ReturnError. E.g.
+
+	var request getUserInputDto
+	if err := bprouter.BindParameters(ctx, &request); err != nil {
+		bprouter.ReturnError(ctx, err)
+		return
+	}
+*/
+func BindParameters(ctx *gin.Context, obj any) error {
+	if ctx.Request.Body != nil && maxBodyBytes > 0 {
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBodyBytes)
+	}
+	if binder, ok := obj.(Binder); ok {
+		return binder.BindRequest(ctx)
+	}
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return bperr.ErrGeneric.WithCause(fmt.Errorf("cannot bind parameters into %T, a pointer to a struct is required", obj))
+	}
+	details := map[string][]string{}
+	if err := bindJSON(ctx, obj, details); err != nil {
+		return err
+	}
+	bindValues(value.Elem(), "uri", func(name string) []string {
+		if param, ok := ctx.Params.Get(name); ok {
+			return []string{param}
+		}
+		return nil
+	}, details)
+	bindValues(value.Elem(), "form", func(name string) []string {
+		return ctx.QueryArray(name)
+	}, details)
+	bindValues(value.Elem(), "header", func(name string) []string {
+		return ctx.Request.Header.Values(name)
+	}, details)
+	if len(details) > 0 {
+		return bperr.ErrValidation.WithDetails(details)
+	}
+	return nil
+}
+
+/*
+ReadBody reads the whole payload, applying the limit on the body size, and restores it so it can still
+be bound via BindParameters. It is meant for middlewares inspecting the payload before the handler.
+*/
+func ReadBody(ctx *gin.Context) ([]byte, error) {
+	if ctx.Request.Body == nil || ctx.Request.Body == http.NoBody {
+		return nil, nil
+	}
+	reader := io.Reader(ctx.Request.Body)
+	if maxBodyBytes > 0 {
+		reader = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBodyBytes)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, bperr.ErrRequestTooLarge.WithCause(err)
+		}
+		return nil, bperr.ErrBadRequest.WithCause(err)
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+/*
+Decode the JSON payload, if any. Type mismatches and unknown fields are collected as details.
+*/
+func bindJSON(ctx *gin.Context, obj any, details map[string][]string) error {
+	if ctx.Request.Body == nil || ctx.Request.Body == http.NoBody {
+		return nil
+	}
+	decoder := json.NewDecoder(ctx.Request.Body)
+	if strictBinding {
+		decoder.DisallowUnknownFields()
+	}
+	err := decoder.Decode(obj)
+	if err == nil {
+		// A valid payload contains a single JSON value
+		if decoder.Decode(&json.RawMessage{}) != io.EOF {
+			return bperr.ErrBadRequest.WithMessage("The request body must contain a single JSON value")
+		}
+		return nil
+	}
+	var maxBytesErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return nil
+	case errors.As(err, &maxBytesErr):
+		return bperr.ErrRequestTooLarge.WithCause(err)
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return bperr.ErrBadRequest.WithMessage("The request body is not a valid JSON").WithCause(err)
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			return bperr.ErrBadRequest.WithMessage("The request body must be a JSON object").WithCause(err)
+		}
+		details[field] = append(details[field], invalidTypeCode)
+		return nil
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// The JSON decoder does not provide a typed error for unknown fields
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		details[field] = append(details[field], unknownFieldCode)
+		return nil
+	default:
+		return bperr.ErrBadRequest.WithCause(err)
+	}
+}
+
+/*
+Bind the values returned by the lookup function into the fields with the given tag.
+*/
+func bindValues(value reflect.Value, tag string, lookup func(name string) []string, details map[string][]string) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		target := value.Field(i)
+		if field.Anonymous && field.IsExported() && field.Type.Kind() == reflect.Struct {
+			bindValues(target, tag, lookup, details)
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		values := lookup(name)
+		if len(values) == 0 {
+			continue
+		}
+		if err := setValue(target, values); err != nil {
+			details[name] = append(details[name], invalidTypeCode)
+		}
+	}
+}
+
+/*
+Convert the values into the type of the target. Slices receive all the values, while other types
+receive only the first one. Types implementing encoding.TextUnmarshaler, e.g. uuid.UUID, are supported.
+*/
+func setValue(target reflect.Value, values []string) error {
+	if unmarshaler, ok := target.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(values[0]))
+	}
+	switch target.Kind() {
+	case reflect.Pointer:
+		item := reflect.New(target.Type().Elem())
+		if err := setValue(item.Elem(), values); err != nil {
+			return err
+		}
+		target.Set(item)
+	case reflect.Slice:
+		items := reflect.MakeSlice(target.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setValue(items.Index(i), []string{v}); err != nil {
+				return err
+			}
+		}
+		target.Set(items)
+	case reflect.String:
+		target.SetString(values[0])
+	case reflect.Bool:
+		v, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return err
+		}
+		target.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(values[0], 10, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(values[0], 10, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(values[0], target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetFloat(v)
+	default:
+		return fmt.Errorf("unsupported type %s", target.Type())
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package bprouter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"github.com/gin-gonic/gin"
+)
+
+type testBindDto struct {
+	ID      string   `uri:"itemID" json:"-"`
+	Name    string   `json:"name"`
+	Age     int      `json:"age"`
+	Page    int      `form:"page" json:"-"`
+	Tags    []string `form:"tag" json:"-"`
+	TraceID string   `header:"X-Trace-ID" json:"-"`
+	traceID string   `header:"X-Trace-ID"`
+}
+
+/*
+Bind the request into the DTO from a handler registered on `/items/:itemID`.
+*/
+func bindTestRequest(t *testing.T, method string, target string, body string, strict bool) (testBindDto, error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	Init(64, strict, "")
+	var dto testBindDto
+	var bindErr error
+	engine := gin.New()
+	engine.Handle(method, "/items/:itemID", func(ctx *gin.Context) {
+		bindErr = BindParameters(ctx, &dto)
+	})
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Trace-ID", "trace-1")
+	engine.ServeHTTP(httptest.NewRecorder(), request)
+	return dto, bindErr
+}
+
+func TestBindParameters(t *testing.T) {
+	dto, err := bindTestRequest(t, http.MethodPut, "/items/item-1?page=2&tag=a&tag=b", `{"name": "Alice", "age": 30}`, true)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := testBindDto{ID: "item-1", Name: "Alice", Age: 30, Page: 2, Tags: []string{"a", "b"}, TraceID: "trace-1"}
+	if !reflect.DeepEqual(dto, expected) {
+		t.Errorf("expected %+v, got %+v", expected, dto)
+	}
+	if _, err := bindTestRequest(t, http.MethodGet, "/items/item-1", "", true); err != nil {
+		t.Errorf("expected requests without body to be accepted, got %v", err)
+	}
+}
+
+func TestBindParametersErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		strict   bool
+		expected *bperr.Error
+		details  map[string][]string
+	}{
+		{name: "malformed", target: "/items/1", body: `{"name": `, expected: bperr.ErrBadRequest},
+		{name: "multiple values", target: "/items/1", body: `{} {}`, expected: bperr.ErrBadRequest},
+		{name: "too large", target: "/items/1", body: `{"name": "` + strings.Repeat("a", 100) + `"}`, expected: bperr.ErrRequestTooLarge},
+		{name: "json type", target: "/items/1", body: `{"age": "thirty"}`, expected: bperr.ErrValidation, details: map[string][]string{"age": {"invalid-type"}}},
+		{name: "query type", target: "/items/1?page=first", body: `{}`, expected: bperr.ErrValidation, details: map[string][]string{"page": {"invalid-type"}}},
+		{name: "unknown field", target: "/items/1", body: `{"nickname": "Al"}`, strict: true, expected: bperr.ErrValidation, details: map[string][]string{"nickname": {"unknown-field"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := bindTestRequest(t, http.MethodPost, test.target, test.body, test.strict)
+			if !errors.Is(err, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, err)
+			}
+			if test.details != nil && !reflect.DeepEqual(bperr.From(err).Details, test.details) {
+				t.Errorf("expected details %v, got %v", test.details, bperr.From(err).Details)
+			}
+		})
+	}
+	if _, err := bindTestRequest(t, http.MethodPost, "/items/1", `{"nickname": "Al"}`, false); err != nil {
+		t.Errorf("expected unknown fields to be ignored when the binding is not strict, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package bprouter
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+ResponseBuffer keeps the response in memory, so middlewares can inspect, validate or rewrite it once
+the handlers are done. Headers are written directly, so they are kept by the responses sent in place
+of the buffered one, while the status and the body are sent by Send. E.g.
+
+	writer := bprouter.NewResponseBuffer(ctx.Writer)
+	ctx.Writer = writer
+	ctx.Next()
+	ctx.Writer = writer.ResponseWriter
+	... // read writer.Status() and writer.Body()
+	writer.Send(nil)
+*/
+type ResponseBuffer struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func NewResponseBuffer(writer gin.ResponseWriter) *ResponseBuffer {
+	return &ResponseBuffer{ResponseWriter: writer, status: http.StatusOK}
+}
+
+func (w *ResponseBuffer) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *ResponseBuffer) WriteHeaderNow() {}
+
+func (w *ResponseBuffer) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *ResponseBuffer) WriteString(data string) (int, error) {
+	return w.body.WriteString(data)
+}
+
+func (w *ResponseBuffer) Status() int {
+	return w.status
+}
+
+func (w *ResponseBuffer) Size() int {
+	return w.body.Len()
+}
+
+func (w *ResponseBuffer) Written() bool {
+	return false
+}
+
+// Buffered responses cannot be streamed, they are sent as a whole by Send.
+func (w *ResponseBuffer) Flush() {}
+
+/*
+Body returns the buffered body.
+*/
+func (w *ResponseBuffer) Body() []byte {
+	return w.body.Bytes()
+}
+
+/*
+Send the buffered status and body to the client, or the given body if not nil.
+*/
+func (w *ResponseBuffer) Send(body []byte) {
+	if body == nil {
+		body = w.body.Bytes()
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
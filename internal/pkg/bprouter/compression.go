@@ -0,0 +1,184 @@
+package bprouter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"go.uber.org/zap"
+)
+
+/*
+Encoding represents a content coding the responses can be compressed with.
+*/
+type Encoding string
+
+/*
+List of available encodings.
+*/
+const (
+	Brotli Encoding = "br"
+	Zstd   Encoding = "zstd"
+	Gzip   Encoding = "gzip"
+)
+
+/*
+AvailableEncodings represents a list of available encodings, from the preferred one
+when the client accepts more of them with the same quality.
+*/
+var AvailableEncodings = []interface{}{Brotli, Zstd, Gzip}
+
+/*
+CompressibleContentTypes represents the list of content types of the responses that are compressed.
+Other types, e.g. images, are generally compressed already.
+*/
+var CompressibleContentTypes = []string{"application/json", ProblemContentType, "application/javascript", "text/html", "text/plain", "text/css"}
+
+var gzipWriters = sync.Pool{New: func() any {
+	return gzip.NewWriter(io.Discard)
+}}
+
+var brotliWriters = sync.Pool{New: func() any {
+	return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+}}
+
+/*
+The zstd encoder can compress whole buffers concurrently, so a single one is shared,
+created on the first compression.
+*/
+var zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+	return zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+})
+
+/*
+CompressionMiddleware compresses the responses with the best encoding accepted by the client via Accept-Encoding,
+among brotli, zstd and gzip. Strong ETags of the compressed responses are weakened, as they no longer
+match the bytes sent. Only the responses of the compressible content types and of at least
+the given number of bytes are compressed, since compressing small payloads costs more than it saves.
+It must be applied before the middlewares reading the responses, so they handle them uncompressed. E.g.
+
+	v1Api := r.Group("api/v1")
+	v1Api.Use(bprouter.CompressionMiddleware(envs.AppCompressionMinBytes))
+*/
+func CompressionMiddleware(minBytes int) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(ctx.GetHeader("Accept-Encoding"))
+		if encoding == "" || ctx.Request.Method == http.MethodHead {
+			ctx.Next()
+			return
+		}
+		writer := NewResponseBuffer(ctx.Writer)
+		ctx.Writer = writer
+		ctx.Next()
+		ctx.Writer = writer.ResponseWriter
+		if len(writer.Body()) < minBytes || !isCompressible(writer.Header()) {
+			writer.Send(nil)
+			return
+		}
+		compressed, err := compress(encoding, writer.Body())
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("Response not compressed with %s", encoding), zap.String("service", "router"), zap.Error(err))
+			writer.Send(nil)
+			return
+		}
+		writer.Header().Set("Content-Encoding", string(encoding))
+		writer.Header().Del("Content-Length")
+		// Strong ETags identify the exact bytes of the response, so they are weakened for the compressed ones
+		if etag := writer.Header().Get("ETag"); strings.HasPrefix(etag, "\"") {
+			writer.Header().Set("ETag", "W/"+etag)
+		}
+		writer.Send(compressed)
+	}
+}
+
+/*
+Choose the encoding with the highest quality in the Accept-Encoding header, e.g. `gzip;q=0.8, br`.
+Encodings with a zero quality are refused, while `*` covers the ones not listed.
+It returns an empty encoding if none of the available ones is accepted.
+*/
+func negotiateEncoding(acceptEncoding string) Encoding {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if name != "" {
+			qualities[strings.ToLower(strings.TrimSpace(name))] = quality
+		}
+	}
+	best, bestQuality := Encoding(""), 0.0
+	for _, available := range AvailableEncodings {
+		encoding := available.(Encoding)
+		quality, listed := qualities[string(encoding)]
+		if !listed {
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+/*
+Responses already encoded, or without a body by definition, are not compressed.
+*/
+func isCompressible(header http.Header) bool {
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && slices.Contains(CompressibleContentTypes, contentType)
+}
+
+func compress(encoding Encoding, body []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	switch encoding {
+	case Brotli:
+		writer := brotliWriters.Get().(*brotli.Writer)
+		defer brotliWriters.Put(writer)
+		writer.Reset(&compressed)
+		if _, err := writer.Write(body); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+	case Zstd:
+		encoder, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		return encoder.EncodeAll(body, make([]byte, 0, len(body)/2)), nil
+	case Gzip:
+		writer := gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(writer)
+		writer.Reset(&compressed)
+		if _, err := writer.Write(body); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %s", encoding)
+	}
+	return compressed.Bytes(), nil
+}
@@ -0,0 +1,141 @@
+package bprouter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]Encoding{
+		"":                        "",
+		"identity":                "",
+		"gzip":                    Gzip,
+		"gzip, deflate, br, zstd": Brotli,
+		"gzip;q=1.0, br;q=0.5":    Gzip,
+		"zstd, gzip":              Zstd,
+		"*":                       Brotli,
+		"*, br;q=0":               Zstd,
+		"gzip;q=0":                "",
+	}
+	for acceptEncoding, expected := range tests {
+		if encoding := negotiateEncoding(acceptEncoding); encoding != expected {
+			t.Errorf("expected %q for %q, got %q", expected, acceptEncoding, encoding)
+		}
+	}
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(CompressionMiddleware(100))
+	payload := strings.Repeat("blueprint ", 50)
+	engine.GET("/large", func(ctx *gin.Context) {
+		ReturnOk(ctx, ItemResponse(payload))
+	})
+	engine.GET("/small", func(ctx *gin.Context) {
+		ReturnOk(ctx, ItemResponse("blueprint"))
+	})
+	engine.GET("/binary", func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "image/png", []byte(payload))
+	})
+	decoders := map[Encoding]func(io.Reader) (io.Reader, error){
+		Gzip: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		Brotli: func(r io.Reader) (io.Reader, error) {
+			return brotli.NewReader(r), nil
+		},
+		Zstd: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+	for encoding, decode := range decoders {
+		t.Run(string(encoding), func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/large", nil)
+			request.Header.Set("Accept-Encoding", string(encoding))
+			response := httptest.NewRecorder()
+			engine.ServeHTTP(response, request)
+			if response.Header().Get("Content-Encoding") != string(encoding) || response.Header().Get("Vary") != "Accept-Encoding" {
+				t.Fatalf("expected the %s encoding, got %v", encoding, response.Header())
+			}
+			reader, err := decode(bytes.NewReader(response.Body.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(reader)
+			if err != nil || !strings.Contains(string(body), payload) {
+				t.Errorf("expected the original body, got %s %v", body, err)
+			}
+		})
+	}
+	for _, target := range []string{"/small", "/binary"} {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		request.Header.Set("Accept-Encoding", "gzip")
+		response := httptest.NewRecorder()
+		engine.ServeHTTP(response, request)
+		if response.Header().Get("Content-Encoding") != "" || !strings.Contains(response.Body.String(), "blueprint") {
+			t.Errorf("expected %s not to be compressed, got %v", target, response.Header())
+		}
+	}
+}
+
+func TestCompressionWeakensStrongETags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(CompressionMiddleware(0))
+	engine.GET("/strong", func(ctx *gin.Context) {
+		ctx.Header("ETag", `"v1"`)
+		ReturnOk(ctx, ItemResponse("blueprint"))
+	})
+	engine.GET("/weak", func(ctx *gin.Context) {
+		ctx.Header("ETag", `W/"v1"`)
+		ReturnOk(ctx, ItemResponse("blueprint"))
+	})
+	for target, expected := range map[string]string{"/strong": `W/"v1"`, "/weak": `W/"v1"`} {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		request.Header.Set("Accept-Encoding", "gzip")
+		response := httptest.NewRecorder()
+		engine.ServeHTTP(response, request)
+		if etag := response.Header().Get("ETag"); etag != expected || response.Header().Get("Content-Encoding") != "gzip" {
+			t.Errorf("expected the %s ETag for %s, got %v", expected, target, response.Header())
+		}
+	}
+}
+
+func TestCompressedETagMatchesVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(CompressionMiddleware(0))
+	engine.GET("/items/1", func(ctx *gin.Context) {
+		SetETag(ctx, VersionETag(3))
+		ReturnOk(ctx, ItemResponse("blueprint"))
+	})
+	engine.PUT("/items/1", func(ctx *gin.Context) {
+		version, ok := GetIfMatchVersion(ctx)
+		if !ok {
+			return
+		}
+		ReturnOk(ctx, ItemResponse(version))
+	})
+	request := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	response := httptest.NewRecorder()
+	engine.ServeHTTP(response, request)
+	etag := response.Header().Get("ETag")
+	if etag != `W/"3"` || response.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a compressed response with a weak ETag, got %v", response.Header())
+	}
+
+	request = httptest.NewRequest(http.MethodPut, "/items/1", nil)
+	request.Header.Set("If-Match", etag)
+	response = httptest.NewRecorder()
+	engine.ServeHTTP(response, request)
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), `"item":3`) {
+		t.Errorf("expected the update of version 3, got %d %s", response.Code, response.Body.String())
+	}
+}
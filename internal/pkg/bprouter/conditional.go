@@ -0,0 +1,95 @@
+package bprouter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+ConditionalMiddleware adds the validators of the JSON responses of GET requests and answers the conditional
+requests with a Not Modified status code (304) and no body, when the client already has the current response.
+The ETag set by the handler, e.g. via VersionETag, is kept, otherwise a weak ETag is generated from the hash
+of the body. The Last-Modified header is generated from the `updatedAt` of the returned `item`, while lists
+rely only on the ETag, since removing an item does not change the dates of the others.
+As defined by RFC 9110, If-None-Match takes precedence over If-Modified-Since. E.g.
+
+	v1Api := r.Group("api/v1")
+	v1Api.Use(bprouter.ConditionalMiddleware())
+*/
+func ConditionalMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method != http.MethodGet {
+			ctx.Next()
+			return
+		}
+		writer := NewResponseBuffer(ctx.Writer)
+		ctx.Writer = writer
+		ctx.Next()
+		ctx.Writer = writer.ResponseWriter
+		contentType, _, _ := mime.ParseMediaType(writer.Header().Get("Content-Type"))
+		if writer.status != http.StatusOK || contentType != "application/json" {
+			writer.Send(nil)
+			return
+		}
+		header := writer.Header()
+		if header.Get("ETag") == "" {
+			hash := sha256.Sum256(writer.Body())
+			header.Set("ETag", "W/\""+hex.EncodeToString(hash[:16])+"\"")
+		}
+		lastModified, hasLastModified := itemLastModified(writer.Body())
+		if hasLastModified && header.Get("Last-Modified") == "" {
+			header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+		}
+		if isNotModified(ctx.Request, header.Get("ETag"), lastModified, hasLastModified) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			writer.status = http.StatusNotModified
+			writer.Send([]byte{})
+			return
+		}
+		writer.Send(nil)
+	}
+}
+
+/*
+Return the `updatedAt` of the `item` of the response, if any.
+*/
+func itemLastModified(body []byte) (time.Time, bool) {
+	var response struct {
+		Item struct {
+			UpdatedAt *time.Time `json:"updatedAt"`
+		} `json:"item"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.Item.UpdatedAt == nil {
+		return time.Time{}, false
+	}
+	return *response.Item.UpdatedAt, true
+}
+
+/*
+Evaluate If-None-Match, comparing the ETags weakly, or If-Modified-Since when the former is missing.
+Dates are compared with a precision of one second, as sent by Last-Modified.
+*/
+func isNotModified(request *http.Request, etag string, lastModified time.Time, hasLastModified bool) bool {
+	if ifNoneMatch := request.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ifModifiedSince := request.Header.Get("If-Modified-Since"); ifModifiedSince != "" && hasLastModified {
+		since, err := http.ParseTime(ifModifiedSince)
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package bprouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+Serve a user updated at the given time, with the ETag of its version if not empty, and a list of users.
+*/
+func newConditionalTestEngine(etag string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(ConditionalMiddleware())
+	engine.GET("/users/1", func(ctx *gin.Context) {
+		if etag != "" {
+			SetETag(ctx, etag)
+		}
+		ReturnOk(ctx, ItemResponse(gin.H{"id": "1", "updatedAt": "2024-06-30T12:00:00.5Z"}))
+	})
+	engine.GET("/users", func(ctx *gin.Context) {
+		ReturnOk(ctx, &gin.H{"items": []gin.H{{"id": "1", "updatedAt": "2024-06-30T12:00:00Z"}}})
+	})
+	return engine
+}
+
+func conditionalTestRequest(engine *gin.Engine, target string, header map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	for name, value := range header {
+		request.Header.Set(name, value)
+	}
+	response := httptest.NewRecorder()
+	engine.ServeHTTP(response, request)
+	return response
+}
+
+func TestConditionalMiddlewareValidators(t *testing.T) {
+	response := conditionalTestRequest(newConditionalTestEngine(""), "/users/1", nil)
+	if response.Code != http.StatusOK || !strings.HasPrefix(response.Header().Get("ETag"), "W/\"") {
+		t.Errorf("expected a weak ETag from the body, got %d %s", response.Code, response.Header().Get("ETag"))
+	}
+	if lastModified := response.Header().Get("Last-Modified"); lastModified != "Sun, 30 Jun 2024 12:00:00 GMT" {
+		t.Errorf("expected the Last-Modified from the updatedAt, got %s", lastModified)
+	}
+	response = conditionalTestRequest(newConditionalTestEngine(VersionETag(3)), "/users/1", nil)
+	if etag := response.Header().Get("ETag"); etag != "\"3\"" {
+		t.Errorf("expected the ETag of the handler, got %s", etag)
+	}
+	response = conditionalTestRequest(newConditionalTestEngine(""), "/users", nil)
+	if response.Header().Get("ETag") == "" || response.Header().Get("Last-Modified") != "" {
+		t.Errorf("expected only the ETag for lists, got %v", response.Header())
+	}
+}
+
+func TestConditionalMiddlewareNotModified(t *testing.T) {
+	engine := newConditionalTestEngine(VersionETag(3))
+	tests := []struct {
+		name     string
+		header   map[string]string
+		expected int
+	}{
+		{"matching ETag", map[string]string{"If-None-Match": "\"2\", W/\"3\""}, http.StatusNotModified},
+		{"any ETag", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"changed ETag", map[string]string{"If-None-Match": "\"2\""}, http.StatusOK},
+		{"not modified since", map[string]string{"If-Modified-Since": "Sun, 30 Jun 2024 12:00:00 GMT"}, http.StatusNotModified},
+		{"modified since", map[string]string{"If-Modified-Since": "Sun, 30 Jun 2024 11:59:59 GMT"}, http.StatusOK},
+		{"ETag precedence", map[string]string{"If-None-Match": "\"2\"", "If-Modified-Since": "Sun, 30 Jun 2024 12:00:00 GMT"}, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := conditionalTestRequest(engine, "/users/1", test.header)
+			if response.Code != test.expected {
+				t.Fatalf("expected %d, got %d", test.expected, response.Code)
+			}
+			if test.expected == http.StatusNotModified && (response.Body.Len() != 0 || response.Header().Get("ETag") != "\"3\"") {
+				t.Errorf("expected no body and the ETag, got %s %v", response.Body.String(), response.Header())
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package bprouter
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+)
+
+var cursorSecret = newCursorSecret()
+
+/*
+Generate a random secret, used when none is configured. Cursors are then valid only
+for the current application instance and until it restarts.
+*/
+func newCursorSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}
+
+/*
+EncodeCursor generates an opaque cursor for listing APIs containing the given payload, e.g. a bpdb.Keyset.
+Cursors are signed, so clients cannot forge them to read arbitrary positions or inject values in queries.
+*/
+func EncodeCursor(payload any) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(data) + "." + encoding.EncodeToString(signCursor(data)), nil
+}
+
+/*
+DecodeCursor verifies the signature of the cursor generated by EncodeCursor and decodes its payload.
+It returns an invalid cursor error if the cursor is malformed or it has been tampered with.
+*/
+func DecodeCursor(cursor string, payload any) error {
+	encoding := base64.RawURLEncoding
+	encodedData, encodedSignature, found := strings.Cut(cursor, ".")
+	if !found {
+		return bperr.ErrInvalidCursor
+	}
+	data, err := encoding.DecodeString(encodedData)
+	if err != nil {
+		return bperr.ErrInvalidCursor.WithCause(err)
+	}
+	signature, err := encoding.DecodeString(encodedSignature)
+	if err != nil {
+		return bperr.ErrInvalidCursor.WithCause(err)
+	}
+	if !hmac.Equal(signature, signCursor(data)) {
+		return bperr.ErrInvalidCursor
+	}
+	// Numbers are kept as strings to not lose precision
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(payload); err != nil {
+		return bperr.ErrInvalidCursor.WithCause(err)
+	}
+	return nil
+}
+
+func signCursor(data []byte) []byte {
+	mac := hmac.New(sha256.New, cursorSecret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+/*
+EncodeOptionalCursor generates a cursor for the given payload, or nil if the payload is nil,
+e.g. for the next page of a listing when there are no more records.
+*/
+func EncodeOptionalCursor[T any](payload *T) (*string, error) {
+	if payload == nil {
+		return nil, nil
+	}
+	cursor, err := EncodeCursor(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+/*
+DecodeOptionalCursor decodes the payload of the given cursor, or returns nil if the cursor is empty,
+e.g. when the first page of a listing is requested.
+*/
+func DecodeOptionalCursor[T any](cursor string) (*T, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	payload := new(T)
+	if err := DecodeCursor(cursor, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,53 @@
+package bprouter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+)
+
+type testCursorPayload struct {
+	Value string `json:"v"`
+	ID    int64  `json:"i"`
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	Init(0, false, "test-secret")
+	payload := testCursorPayload{Value: "Rossi", ID: 9007199254740993}
+	cursor, err := EncodeOptionalCursor(&payload)
+	if err != nil || cursor == nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	decoded, err := DecodeOptionalCursor[testCursorPayload](*cursor)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if *decoded != payload {
+		t.Errorf("expected %+v, got %+v", payload, *decoded)
+	}
+	if cursor, _ := EncodeOptionalCursor[testCursorPayload](nil); cursor != nil {
+		t.Errorf("expected no cursor for a nil payload, got %s", *cursor)
+	}
+	if decoded, _ := DecodeOptionalCursor[testCursorPayload](""); decoded != nil {
+		t.Errorf("expected no payload for an empty cursor, got %+v", decoded)
+	}
+}
+
+func TestCursorInvalid(t *testing.T) {
+	Init(0, false, "test-secret")
+	cursor, _ := EncodeCursor(testCursorPayload{Value: "Rossi", ID: 1})
+	forged, _ := EncodeCursor(testCursorPayload{Value: "Bianchi", ID: 1})
+	Init(0, false, "another-secret")
+	rotated, _ := EncodeCursor(testCursorPayload{Value: "Rossi", ID: 1})
+	Init(0, false, "test-secret")
+	forgedData, _, _ := strings.Cut(forged, ".")
+	_, signature, _ := strings.Cut(cursor, ".")
+	for _, invalid := range []string{"not-a-cursor", "a.b", forgedData + "." + signature, rotated} {
+		var payload testCursorPayload
+		if err := DecodeCursor(invalid, &payload); !errors.Is(err, bperr.ErrInvalidCursor) {
+			t.Errorf("expected invalid cursor error for %s, got %v", invalid, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package bprouter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"github.com/gin-gonic/gin"
+)
+
+/*
+AnyVersion is returned by GetIfMatchVersion when the client accepts any version of the resource (If-Match: *).
+*/
+const AnyVersion int64 = 0
+
+/*
+VersionETag generates a strong ETag for a resource based on its version.
+*/
+func VersionETag(version int64) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+/*
+SetETag adds the ETag header to the response.
+*/
+func SetETag(ctx *gin.Context, etag string) {
+	ctx.Header("ETag", etag)
+}
+
+/*
+GetIfMatchVersion reads the version of the resource the client wants to update from the If-Match header,
+as generated by VersionETag. The weak form of the ETag is accepted too, since CompressionMiddleware weakens
+the ETags of the compressed responses while the version they carry is unchanged.
+In case the header is missing, it returns a Precondition Required error (428), while in case it is malformed
+it returns a Precondition Failed error (412). In both cases it returns false and the request must not proceed.
+*/
+func GetIfMatchVersion(ctx *gin.Context) (int64, bool) {
+	ifMatch := strings.TrimPrefix(strings.TrimSpace(ctx.GetHeader("If-Match")), "W/")
+	if ifMatch == "" {
+		ReturnPreconditionRequiredError(ctx)
+		return 0, false
+	}
+	if ifMatch == "*" {
+		return AnyVersion, true
+	}
+	version, err := strconv.ParseInt(strings.Trim(ifMatch, "\""), 10, 64)
+	if err != nil || version <= 0 || !strings.HasPrefix(ifMatch, "\"") {
+		ReturnPreconditionFailedError(ctx)
+		return 0, false
+	}
+	return version, true
+}
+
+/*
+ReturnPreconditionFailedError returns a Precondition Failed status code (412), used when
+the resource has been changed since the client read it.
+*/
+func ReturnPreconditionFailedError(ctx *gin.Context) {
+	ReturnError(ctx, bperr.ErrPreconditionFailed)
+}
+
+/*
+ReturnPreconditionRequiredError returns a Precondition Required status code (428), used when
+the client tries to update a resource without providing the version it read.
+*/
+func ReturnPreconditionRequiredError(ctx *gin.Context) {
+	ReturnError(ctx, bperr.ErrPreconditionRequired)
+}
@@ -0,0 +1,144 @@
+package bprouter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"github.com/besasch88/blueprint/internal/pkg/bpfilter"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+/*
+Max number of values accepted by the `in` operator, to keep queries cheap.
+*/
+const maxFilterValues = 100
+
+var filterParamRegex = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+/*
+ParseFilters reads the filters from the Query params, in the form `filter[field][operator]=value`,
+validating them against the whitelist of filterable fields of the module. The operator can be omitted
+for equality, e.g. `filter[email]=alice@example.com`. Repeated filters are all applied.
+
+It returns a validation error mapping each invalid parameter to the codes of its errors,
+so the result can be returned as is via ReturnError. E.g.
+
+	filters, err := bprouter.ParseFilters(ctx, userFilterableFields)
+	if err != nil {
+		bprouter.ReturnError(ctx, err)
+		return
+	}
+*/
+func ParseFilters(ctx *gin.Context, fields bpfilter.Fields) ([]bpfilter.Filter, error) {
+	return parseFilters(ctx.Request.URL.Query(), fields)
+}
+
+func parseFilters(query url.Values, fields bpfilter.Fields) ([]bpfilter.Filter, error) {
+	filters := []bpfilter.Filter{}
+	details := map[string][]string{}
+	params := []string{}
+	for param := range query {
+		if strings.HasPrefix(param, "filter[") {
+			params = append(params, param)
+		}
+	}
+	slices.Sort(params)
+	for _, param := range params {
+		matches := filterParamRegex.FindStringSubmatch(param)
+		if matches == nil {
+			details[param] = append(details[param], "invalid-filter")
+			continue
+		}
+		name, operator := matches[1], bpfilter.Operator(matches[2])
+		if operator == "" {
+			operator = bpfilter.Eq
+		}
+		field, exists := fields[name]
+		if !exists {
+			details[param] = append(details[param], "unknown-field")
+			continue
+		}
+		if !slices.Contains(field.Operators, operator) {
+			details[param] = append(details[param], "unsupported-operator")
+			continue
+		}
+		for _, value := range query[param] {
+			values, code := parseFilterValues(field.Type, operator, value)
+			if code != "" {
+				details[param] = append(details[param], code)
+				continue
+			}
+			filters = append(filters, bpfilter.Filter{
+				Field:    name,
+				Column:   field.Column,
+				Operator: operator,
+				Values:   values,
+			})
+		}
+	}
+	if len(details) > 0 {
+		return []bpfilter.Filter{}, bperr.ErrValidation.WithDetails(details)
+	}
+	return filters, nil
+}
+
+/*
+Convert the value of a filter in the type of the field, returning the error code if not valid.
+*/
+func parseFilterValues(fieldType bpfilter.Type, operator bpfilter.Operator, value string) ([]interface{}, string) {
+	if operator == bpfilter.IsNull {
+		isNull, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, invalidTypeCode
+		}
+		return []interface{}{isNull}, ""
+	}
+	rawValues := []string{value}
+	if operator == bpfilter.In {
+		rawValues = strings.Split(value, ",")
+		if len(rawValues) > maxFilterValues {
+			return nil, "too-many-values"
+		}
+	}
+	values := []interface{}{}
+	for _, rawValue := range rawValues {
+		converted, err := convertFilterValue(fieldType, strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, invalidTypeCode
+		}
+		values = append(values, converted)
+	}
+	return values, ""
+}
+
+func convertFilterValue(fieldType bpfilter.Type, value string) (interface{}, error) {
+	switch fieldType {
+	case bpfilter.Number:
+		return strconv.ParseFloat(value, 64)
+	case bpfilter.Bool:
+		return strconv.ParseBool(value)
+	case bpfilter.Time:
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, err
+		}
+		return parsed.UTC(), nil
+	case bpfilter.UUID:
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		return parsed.String(), nil
+	case bpfilter.String:
+		return value, nil
+	default:
+		return nil, fmt.Errorf("unsupported filter type %s", fieldType)
+	}
+}
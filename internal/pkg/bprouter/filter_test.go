@@ -0,0 +1,54 @@
+package bprouter
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"github.com/besasch88/blueprint/internal/pkg/bpfilter"
+)
+
+var testFilterableFields = bpfilter.Fields{
+	"email":     {Column: "email", Type: bpfilter.String, Operators: []bpfilter.Operator{bpfilter.Eq, bpfilter.Contains, bpfilter.In}},
+	"createdAt": {Column: "created_at", Type: bpfilter.Time, Operators: []bpfilter.Operator{bpfilter.Gte}},
+	"deletedBy": {Column: "deleted_by", Type: bpfilter.UUID, Operators: []bpfilter.Operator{bpfilter.IsNull}},
+}
+
+func TestParseFilters(t *testing.T) {
+	query, _ := url.ParseQuery("filter[email][contains]=rossi&filter[email][in]=a@example.com,b@example.com&filter[createdAt][gte]=2024-05-01T10:00:00%2B02:00&filter[deletedBy][isNull]=true&filter[email]=c@example.com&page=1")
+	filters, err := parseFilters(query, testFilterableFields)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []bpfilter.Filter{
+		{Field: "createdAt", Column: "created_at", Operator: bpfilter.Gte, Values: []interface{}{time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)}},
+		{Field: "deletedBy", Column: "deleted_by", Operator: bpfilter.IsNull, Values: []interface{}{true}},
+		{Field: "email", Column: "email", Operator: bpfilter.Eq, Values: []interface{}{"c@example.com"}},
+		{Field: "email", Column: "email", Operator: bpfilter.Contains, Values: []interface{}{"rossi"}},
+		{Field: "email", Column: "email", Operator: bpfilter.In, Values: []interface{}{"a@example.com", "b@example.com"}},
+	}
+	if !reflect.DeepEqual(filters, expected) {
+		t.Errorf("expected %v, got %v", expected, filters)
+	}
+}
+
+func TestParseFiltersInvalid(t *testing.T) {
+	query, _ := url.ParseQuery("filter[password]=secret&filter[email][gt]=a&filter[createdAt][gte]=yesterday&filter[deletedBy][isNull]=maybe&filter[email")
+	_, err := parseFilters(query, testFilterableFields)
+	if !errors.Is(err, bperr.ErrValidation) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	expected := map[string][]string{
+		"filter[password]":          {"unknown-field"},
+		"filter[email][gt]":         {"unsupported-operator"},
+		"filter[createdAt][gte]":    {"invalid-type"},
+		"filter[deletedBy][isNull]": {"invalid-type"},
+		"filter[email":              {"invalid-filter"},
+	}
+	if details := bperr.From(err).Details; !reflect.DeepEqual(details, expected) {
+		t.Errorf("expected details %v, got %v", expected, details)
+	}
+}
@@ -0,0 +1,22 @@
+package bprouter
+
+import "go.uber.org/zap"
+
+/*
+Init configures the binding of the requests and the signature of the cursors. Bodies bigger than the max size
+are rejected, while the strict mode rejects the JSON fields not defined in the DTOs.
+Cursors must be signed with the same secret by all the application instances. If the secret is empty,
+a random one is generated, so cursors are valid only until the application restarts.
+*/
+func Init(appMaxBodyBytes int64, appStrictBinding bool, appCursorSecret string) {
+	zap.L().Info("Initializing Router...", zap.String("service", "router"))
+	maxBodyBytes = appMaxBodyBytes
+	strictBinding = appStrictBinding
+	if appCursorSecret != "" {
+		cursorSecret = []byte(appCursorSecret)
+	} else {
+		cursorSecret = newCursorSecret()
+		zap.L().Warn("Cursor secret not configured. Cursors are valid only until the application restarts", zap.String("service", "router"))
+	}
+	zap.L().Info("Router initialized!", zap.String("service", "router"), zap.Int64("maxBodyBytes", maxBodyBytes), zap.Bool("strictBinding", strictBinding))
+}
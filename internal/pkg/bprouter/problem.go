@@ -0,0 +1,51 @@
+package bprouter
+
+import (
+	"net/http"
+
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+/*
+ProblemContentType is the content type of the error responses, as defined by RFC 7807.
+*/
+const ProblemContentType = "application/problem+json"
+
+/*
+Problem represents the body of an error response following RFC 7807.
+Code is an extension member containing the stable code of the error, while Errors
+maps each invalid field to the codes of its errors.
+*/
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Code     string              `json:"code"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Errors   map[string][]string `json:"errors,omitempty"`
+}
+
+/*
+ReturnError aborts the request returning the given error as `application/problem+json`.
+Errors not generated by the bperr package are returned as generic errors, so internal
+details never reach the clients. Server errors are logged together with their cause.
+*/
+func ReturnError(ctx *gin.Context, err error) {
+	appErr := bperr.From(err)
+	if appErr.Status >= http.StatusInternalServerError {
+		zap.L().Error("Something went wrong", zap.String("service", "router"), zap.String("path", ctx.FullPath()), zap.Error(err))
+	}
+	ctx.Header("Content-Type", ProblemContentType)
+	ctx.AbortWithStatusJSON(appErr.Status, Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(appErr.Status),
+		Status:   appErr.Status,
+		Code:     appErr.Code,
+		Detail:   appErr.Message,
+		Instance: ctx.Request.URL.Path,
+		Errors:   appErr.Details,
+	})
+}
@@ -1,10 +1,11 @@
 package bprouter
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,52 +18,117 @@ func HasNext(page int, pageSize int, totalCount int64) bool {
 }
 
 /*
-ReturnValidationError returns an Unprocessable Request status code (422) and all the errors generated by the input validator.
+OffsetPageMeta represents the `meta` of a page of items listed via page and pageSize.
+*/
+type OffsetPageMeta struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"pageSize"`
+	TotalCount int64 `json:"totalCount"`
+	HasNext    bool  `json:"hasNext"`
+}
+
+/*
+NewOffsetPageMeta creates the meta of a page of items listed via page and pageSize.
+*/
+func NewOffsetPageMeta(page int, pageSize int, totalCount int64) OffsetPageMeta {
+	return OffsetPageMeta{
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: totalCount,
+		HasNext:    HasNext(page, pageSize, totalCount),
+	}
+}
+
+/*
+CursorPageMeta represents the `meta` of a page of items listed via cursors.
+The total count is omitted if the client asked to skip it.
+*/
+type CursorPageMeta struct {
+	PageSize   int     `json:"pageSize"`
+	NextCursor *string `json:"nextCursor"`
+	PrevCursor *string `json:"prevCursor"`
+	TotalCount *int64  `json:"totalCount,omitempty"`
+}
+
+/*
+ItemResponse wraps a single item in the envelope shared by all the APIs. E.g.
+
+	bprouter.ReturnOk(ctx, bprouter.ItemResponse(newUserOutputDto(item)))
+*/
+func ItemResponse(item any) *gin.H {
+	return &gin.H{"item": item}
+}
+
+/*
+ItemsResponse wraps a list of items and the meta of their page in the envelope shared by all the APIs.
+*/
+func ItemsResponse(items any, meta any) *gin.H {
+	return &gin.H{"items": items, "meta": meta}
+}
+
+/*
+ReturnValidationError returns an Unprocessable Request status code (422) and all the errors generated by the input validator,
+mapping each field to the codes of its errors.
 */
 func ReturnValidationError(ctx *gin.Context, err error) {
-	ctx.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"errors": strings.Split(strings.ReplaceAll(err.Error(), ".", ""), "; ")})
+	ReturnError(ctx, bperr.NewValidationError(err))
 }
 
 /*
-ReturnBadRequestError returns a Bad Request status code (400) and all the errors generated by the input validator.
+ReturnBadRequestError returns a Bad Request status code (400), e.g. when the request cannot be parsed.
 */
 func ReturnBadRequestError(ctx *gin.Context, err error) {
-	ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"errors": strings.Split(strings.ReplaceAll(err.Error(), ".", ""), "; ")})
+	ReturnError(ctx, bperr.ErrBadRequest.WithCause(err))
 }
 
 /*
 ReturnUnauthorizedError returns an Unauthroized status code (401).
 */
 func ReturnUnauthorizedError(ctx *gin.Context) {
-	ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"errors": []string{"unauthorized"}})
+	ReturnError(ctx, bperr.ErrUnauthorized)
 }
 
 /*
 ReturnForbiddenError returns a Forbidden status code (403).
 */
 func ReturnForbiddenError(ctx *gin.Context) {
-	ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"errors": []string{"forbidden"}})
+	ReturnError(ctx, bperr.ErrForbidden)
 }
 
 /*
-ReturnNotFoundError returns a Not Found status code (404).
+ReturnNotFoundError returns a Not Found status code (404). The code of the given error is returned
+if it has been generated by the bperr package, otherwise the generic not-found code is used.
 */
 func ReturnNotFoundError(ctx *gin.Context, err error) {
-	ctx.AbortWithStatusJSON(http.StatusNotFound, gin.H{"errors": strings.Split(strings.ReplaceAll(err.Error(), ".", ""), "; ")})
+	var appErr *bperr.Error
+	if !errors.As(err, &appErr) {
+		appErr = bperr.ErrNotFound.WithCause(err)
+	}
+	ReturnError(ctx, appErr)
 }
 
 /*
-ReturnCreated returns a Created status code (201) with payload.
+ReturnCreated returns a Created status code (201) with payload, shaped as requested via ShapeMiddleware.
 */
 func ReturnCreated(ctx *gin.Context, data *gin.H) {
-	ctx.JSON(http.StatusCreated, data)
+	shaped, err := shapeResponse(ctx, data)
+	if err != nil {
+		ReturnError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, shaped)
 }
 
 /*
-ReturnOk returns a OK status code (200) with the given payload.
+ReturnOk returns a OK status code (200) with the given payload, shaped as requested via ShapeMiddleware.
 */
 func ReturnOk(ctx *gin.Context, data *gin.H) {
-	ctx.JSON(http.StatusOK, data)
+	shaped, err := shapeResponse(ctx, data)
+	if err != nil {
+		ReturnError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, shaped)
 }
 
 /*
@@ -83,7 +149,7 @@ func ReturnNoContent(ctx *gin.Context) {
 ReturnTimeOutError returns a Timeout status code (408) with the given payload.
 */
 func ReturnTimeOutError(ctx *gin.Context) {
-	ctx.JSON(http.StatusRequestTimeout, gin.H{"errors": []string{"request-timeout"}})
+	ReturnError(ctx, bperr.ErrRequestTimeout)
 }
 
 /*
@@ -91,22 +157,21 @@ ReturnTooManyRequests returns a Too Many Requests status code (429) with the giv
 */
 func ReturnTooManyRequests(ctx *gin.Context, retryAfter int64) {
 	ctx.Header("Retry-After", fmt.Sprintf("%d", retryAfter))
-	ctx.JSON(http.StatusTooManyRequests, gin.H{"errors": []string{"too-many-requests"}})
+	ReturnError(ctx, bperr.ErrTooManyRequests)
 }
 
 /*
-ReturnGenericError returns an Internal Server Error status code (500) with the given payload.
+ReturnQuotaExceededError returns a Too Many Requests status code (429) when the daily or monthly quota is exhausted.
+Differently from the rate limit, the retry is generally far in the future, so clients should not retry automatically.
 */
-func ReturnGenericError(ctx *gin.Context) {
-	ctx.JSON(http.StatusInternalServerError, gin.H{"errors": []string{"internal-server-error"}})
+func ReturnQuotaExceededError(ctx *gin.Context, retryAfter int64) {
+	ctx.Header("Retry-After", fmt.Sprintf("%d", retryAfter))
+	ReturnError(ctx, bperr.ErrQuotaExceeded)
 }
 
 /*
-BindParameters accept an object as DTO and tries to populate it with all the
-parameters found in the URI, Query params and JSON payload..
+ReturnGenericError returns an Internal Server Error status code (500) with the given payload.
 */
-func BindParameters(ctx *gin.Context, obj any) {
-	ctx.ShouldBindUri(&obj)
-	ctx.ShouldBindQuery(&obj)
-	ctx.ShouldBindJSON(&obj)
+func ReturnGenericError(ctx *gin.Context) {
+	ReturnError(ctx, bperr.ErrGeneric)
 }
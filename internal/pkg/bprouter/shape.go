@@ -0,0 +1,268 @@
+package bprouter
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/besasch88/blueprint/internal/pkg/bperr"
+	"github.com/gin-gonic/gin"
+)
+
+/*
+contextResponseShape represents a key where the requested fields and expansions
+are stored inside the context of the request.
+*/
+const contextResponseShape = "responseShape"
+
+/*
+Max number of related resources that can be expanded in a single request.
+*/
+const maxExpansions = 10
+
+/*
+Expander resolves a related resource for all the returned items at once, avoiding N+1 queries.
+KeyField is the field of the items containing the key of the related resource, e.g. `createdBy`
+to expand the user who created the item, or `id` to expand the resources referring to the item.
+Resolve receives the distinct keys and returns the related resources by key. Items whose key
+is not returned get a null value.
+*/
+type Expander struct {
+	KeyField string
+	Resolve  func(ctx *gin.Context, keys []string) (map[string]interface{}, error)
+}
+
+/*
+Expanders represents the expansions available for a route, by name. The name is also the field
+of the items where the related resource is returned.
+*/
+type Expanders map[string]Expander
+
+/*
+FieldsTree represents the requested fields of the items, where nil means the whole value.
+*/
+type fieldsTree map[string]fieldsTree
+
+type responseShape struct {
+	fields    fieldsTree
+	expand    []string
+	expanders Expanders
+}
+
+/*
+ShapeMiddleware allows clients to shape the `item` or `items` returned by ReturnOk and ReturnCreated
+via the `fields` and `expand` Query params. Fields trim the items to the requested ones, with nested fields
+separated by dots, while expansions replace or add related resources resolved by the given expanders. E.g.
+
+	router.GET(
+		"/users",
+		bpauth.AuthMiddleware([]string{bpauth.UserList}),
+		bprouter.ShapeMiddleware(bprouter.Expanders{"createdBy": {KeyField: "createdBy", Resolve: resolveUsers}}),
+		... //other middlewares
+		func(ctx *gin.Context) {
+			... // your logic
+
+	GET /users?fields=id,email,createdBy.email&expand=createdBy
+
+Unknown expansions are rejected with a validation error, while unknown fields are ignored.
+*/
+func ShapeMiddleware(expanders Expanders) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		shape, err := parseResponseShape(ctx.Query("fields"), ctx.Query("expand"), expanders)
+		if err != nil {
+			ReturnError(ctx, err)
+			return
+		}
+		if shape.fields != nil || len(shape.expand) > 0 {
+			ctx.Set(contextResponseShape, shape)
+		}
+		ctx.Next()
+	}
+}
+
+func parseResponseShape(fields string, expand string, expanders Expanders) (responseShape, error) {
+	shape := responseShape{expanders: expanders}
+	for _, name := range splitList(expand) {
+		if _, exists := expanders[name]; !exists {
+			return responseShape{}, bperr.ErrValidation.WithDetails(map[string][]string{"expand": {"unknown-expansion"}})
+		}
+		if !slices.Contains(shape.expand, name) {
+			shape.expand = append(shape.expand, name)
+		}
+	}
+	if len(shape.expand) > maxExpansions {
+		return responseShape{}, bperr.ErrValidation.WithDetails(map[string][]string{"expand": {"too-many-values"}})
+	}
+	for _, path := range splitList(fields) {
+		if shape.fields == nil {
+			shape.fields = fieldsTree{}
+		}
+		shape.fields.add(strings.Split(path, "."))
+	}
+	// Expanded resources are always returned, even if not requested among the fields
+	if shape.fields != nil {
+		for _, name := range shape.expand {
+			if _, exists := shape.fields[name]; !exists {
+				shape.fields[name] = nil
+			}
+		}
+	}
+	return shape, nil
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+/*
+Add the path to the tree. Requesting a field entirely overrides the requests of its nested fields.
+*/
+func (t fieldsTree) add(path []string) {
+	subtree, exists := t[path[0]]
+	if len(path) == 1 {
+		t[path[0]] = nil
+		return
+	}
+	if exists && subtree == nil {
+		return
+	}
+	if subtree == nil {
+		subtree = fieldsTree{}
+		t[path[0]] = subtree
+	}
+	subtree.add(path[1:])
+}
+
+func (t fieldsTree) trim(value interface{}) interface{} {
+	if t == nil {
+		return value
+	}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		trimmed := map[string]interface{}{}
+		for key, subtree := range t {
+			if fieldValue, exists := typed[key]; exists {
+				trimmed[key] = subtree.trim(fieldValue)
+			}
+		}
+		return trimmed
+	case []interface{}:
+		trimmed := make([]interface{}, len(typed))
+		for i, item := range typed {
+			trimmed[i] = t.trim(item)
+		}
+		return trimmed
+	default:
+		return value
+	}
+}
+
+/*
+IsResponseShaped returns true if the client asked for specific fields or expansions,
+so the returned items do not follow the representation of their DTOs.
+*/
+func IsResponseShaped(ctx *gin.Context) bool {
+	_, exists := ctx.Get(contextResponseShape)
+	return exists
+}
+
+/*
+Apply the shape requested by the client, if any, to the `item` and `items` of the payload.
+*/
+func shapeResponse(ctx *gin.Context, data *gin.H) (*gin.H, error) {
+	value, exists := ctx.Get(contextResponseShape)
+	if !exists || data == nil {
+		return data, nil
+	}
+	shape := value.(responseShape)
+	shaped := gin.H{}
+	var items []map[string]interface{}
+	for key, payload := range *data {
+		if key != "item" && key != "items" {
+			shaped[key] = payload
+			continue
+		}
+		// The payload is converted in its JSON representation, so fields are the ones returned to clients
+		generic, err := toGenericJSON(payload)
+		if err != nil {
+			return nil, bperr.ErrGeneric.WithCause(err)
+		}
+		switch typed := generic.(type) {
+		case map[string]interface{}:
+			items = append(items, typed)
+		case []interface{}:
+			for _, item := range typed {
+				if itemMap, ok := item.(map[string]interface{}); ok {
+					items = append(items, itemMap)
+				}
+			}
+		}
+		shaped[key] = generic
+	}
+	for _, name := range shape.expand {
+		if err := expand(ctx, name, shape.expanders[name], items); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range []string{"item", "items"} {
+		if payload, exists := shaped[key]; exists {
+			shaped[key] = shape.fields.trim(payload)
+		}
+	}
+	return &shaped, nil
+}
+
+/*
+Resolve the related resources of all the items with a single call to the expander.
+*/
+func expand(ctx *gin.Context, name string, expander Expander, items []map[string]interface{}) error {
+	keys := []string{}
+	for _, item := range items {
+		if key, ok := item[expander.KeyField].(string); ok && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	resolved := map[string]interface{}{}
+	if len(keys) > 0 {
+		var err error
+		if resolved, err = expander.Resolve(ctx, keys); err != nil {
+			return err
+		}
+	}
+	for _, item := range items {
+		key, _ := item[expander.KeyField].(string)
+		related, exists := resolved[key]
+		if !exists {
+			item[name] = nil
+			continue
+		}
+		generic, err := toGenericJSON(related)
+		if err != nil {
+			return bperr.ErrGeneric.WithCause(err)
+		}
+		item[name] = generic
+	}
+	return nil
+}
+
+func toGenericJSON(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	// Numbers are kept as they are, without losing precision
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
@@ -0,0 +1,98 @@
+package bprouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testShapeAuthor struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+type testShapeItem struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Views     int64  `json:"views"`
+	CreatedBy string `json:"createdBy"`
+}
+
+/*
+Serve the request from a handler returning a page of items, counting the calls to the expander.
+*/
+func shapeTestRequest(t *testing.T, target string) (*httptest.ResponseRecorder, int) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	expanders := Expanders{"createdBy": {KeyField: "createdBy", Resolve: func(ctx *gin.Context, keys []string) (map[string]interface{}, error) {
+		calls++
+		authors := map[string]interface{}{}
+		for _, key := range keys {
+			if key != "unknown" {
+				authors[key] = testShapeAuthor{ID: key, Email: key + "@example.com", Name: "Author " + key}
+			}
+		}
+		return authors, nil
+	}}}
+	engine := gin.New()
+	engine.GET("/items", ShapeMiddleware(expanders), func(ctx *gin.Context) {
+		ReturnOk(ctx, &gin.H{"items": []testShapeItem{
+			{ID: "1", Title: "First", Views: 9007199254740993, CreatedBy: "a"},
+			{ID: "2", Title: "Second", Views: 2, CreatedBy: "b"},
+			{ID: "3", Title: "Third", Views: 3, CreatedBy: "a"},
+			{ID: "4", Title: "Fourth", Views: 4, CreatedBy: "unknown"},
+		}, "hasNext": false})
+	})
+	response := httptest.NewRecorder()
+	engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, target, nil))
+	return response, calls
+}
+
+func TestShapeFieldsAndExpand(t *testing.T) {
+	response, calls := shapeTestRequest(t, "/items?fields=id,views,createdBy.email&expand=createdBy")
+	if calls != 1 {
+		t.Errorf("expected the expander to be called once, got %d", calls)
+	}
+	var body, expected interface{}
+	json.Unmarshal(response.Body.Bytes(), &body)
+	json.Unmarshal([]byte(`{"hasNext": false, "items": [
+		{"id": "1", "views": 9007199254740993, "createdBy": {"email": "a@example.com"}},
+		{"id": "2", "views": 2, "createdBy": {"email": "b@example.com"}},
+		{"id": "3", "views": 3, "createdBy": {"email": "a@example.com"}},
+		{"id": "4", "views": 4, "createdBy": null}
+	]}`), &expected)
+	if !reflect.DeepEqual(body, expected) {
+		t.Errorf("expected %v, got %s", expected, response.Body.String())
+	}
+	if !strings.Contains(response.Body.String(), "9007199254740993") {
+		t.Errorf("expected numbers to keep their precision, got %s", response.Body.String())
+	}
+}
+
+func TestShapeUnchanged(t *testing.T) {
+	response, calls := shapeTestRequest(t, "/items")
+	if calls != 0 {
+		t.Errorf("expected no expansion, got %d calls", calls)
+	}
+	var body struct {
+		Items []testShapeItem `json:"items"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &body)
+	if len(body.Items) != 4 || body.Items[1].Title != "Second" || body.Items[1].CreatedBy != "b" {
+		t.Errorf("expected the items unchanged, got %s", response.Body.String())
+	}
+}
+
+func TestShapeUnknownExpansion(t *testing.T) {
+	response, _ := shapeTestRequest(t, "/items?expand=roles")
+	if response.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, response.Code)
+	}
+}
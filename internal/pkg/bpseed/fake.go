@@ -0,0 +1,48 @@
+package bpseed
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+/*
+FakePerson represents a realistic person that can be used to generate fake records for load testing.
+*/
+type FakePerson struct {
+	Firstname string
+	Lastname  string
+	Email     string
+}
+
+var fakeFirstnames = []string{
+	"Alice", "Andrea", "Anna", "Benjamin", "Carlo", "Charlotte", "Daniel", "Elena", "Emma", "Francesca",
+	"Giulia", "Hannah", "Isabella", "Jacob", "James", "Laura", "Liam", "Lucas", "Marco", "Maria",
+	"Matteo", "Michael", "Noah", "Olivia", "Paolo", "Sara", "Sofia", "Thomas", "Valentina", "William",
+}
+
+var fakeLastnames = []string{
+	"Anderson", "Bianchi", "Brown", "Colombo", "Costa", "Davis", "Esposito", "Ferrari", "Garcia", "Greco",
+	"Johnson", "Jones", "Martin", "Martinez", "Miller", "Moore", "Ricci", "Romano", "Rossi", "Russo",
+	"Smith", "Taylor", "Thomas", "Thompson", "Wilson", "White", "Williams", "Bruno", "Gallo", "Conti",
+}
+
+var fakeEmailDomains = []string{"example.com", "example.org", "example.net"}
+
+/*
+NewFakePerson generates a person with a realistic name and a unique email address.
+*/
+func NewFakePerson() FakePerson {
+	firstname := fakeFirstnames[rand.Intn(len(fakeFirstnames))]
+	lastname := fakeLastnames[rand.Intn(len(fakeLastnames))]
+	domain := fakeEmailDomains[rand.Intn(len(fakeEmailDomains))]
+	// A random suffix prevents collisions on unique email constraints.
+	suffix := strings.Split(uuid.NewString(), "-")[0]
+	return FakePerson{
+		Firstname: firstname,
+		Lastname:  lastname,
+		Email:     strings.ToLower(fmt.Sprintf("%s.%s.%s@%s", firstname, lastname, suffix, domain)),
+	}
+}
@@ -0,0 +1,64 @@
+package bpseed
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+Fixture represents the content of a fixture file for a specific module.
+Files can be written in YAML or JSON, the content is always decoded
+by leveraging the JSON tags of the target struct.
+*/
+type Fixture struct {
+	Module  string
+	Path    string
+	content []byte
+	isYaml  bool
+}
+
+/*
+Decode populates the given object with the content of the fixture.
+*/
+func (f Fixture) Decode(obj any) error {
+	content := f.content
+	if f.isYaml {
+		var data interface{}
+		if err := yaml.Unmarshal(f.content, &data); err != nil {
+			return err
+		}
+		jsonContent, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		content = jsonContent
+	}
+	return json.Unmarshal(content, obj)
+}
+
+/*
+Read the fixture of a module for the given environment, looking for
+`<fixturesPath>/<env>/<module>.yaml`, `.yml` or `.json` in this order.
+*/
+func readFixture(fixturesPath string, env SeedEnv, module string) (Fixture, bool, error) {
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		path := filepath.Join(fixturesPath, string(env), module+ext)
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return Fixture{}, false, err
+		}
+		return Fixture{
+			Module:  module,
+			Path:    path,
+			content: content,
+			isYaml:  ext != ".json",
+		}, true, nil
+	}
+	return Fixture{}, false, nil
+}
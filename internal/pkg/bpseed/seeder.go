@@ -0,0 +1,164 @@
+package bpseed
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+/*
+SeedEnv represents a set of fixtures to be loaded for a specific environment.
+Each environment has its own folder inside the fixtures path.
+*/
+type SeedEnv string
+
+/*
+List of available fixture sets.
+*/
+const (
+	SeedEnvDev  SeedEnv = "dev"
+	SeedEnvDemo SeedEnv = "demo"
+	SeedEnvTest SeedEnv = "test"
+)
+
+/*
+AvailableSeedEnvs represents a list of available fixture sets. It is generally used
+to validate the input parameters provided via CLI.
+*/
+var AvailableSeedEnvs = []interface{}{SeedEnvDev, SeedEnvDemo, SeedEnvTest}
+
+var errModuleNotRegistered = errors.New("seed-module-not-registered")
+var errCircularDependency = errors.New("seed-circular-dependency")
+var errFakeNotSupported = errors.New("seed-fake-not-supported")
+
+/*
+ModuleSeeder represents the seeding capabilities of a module.
+LoadFixture receives the fixture file of the module and must upsert all its records by ID,
+so the same fixture can be loaded multiple times without side effects.
+GenerateFake is optional and generates the given number of fake records.
+Both functions return the number of records stored.
+*/
+type ModuleSeeder struct {
+	Module       string
+	DependsOn    []string
+	LoadFixture  func(fixture Fixture) (int, error)
+	GenerateFake func(count int) (int, error)
+}
+
+/*
+Seeder orchestrates the fixture loading across all the registered modules,
+ensuring modules are seeded after the ones they depend on.
+*/
+type Seeder struct {
+	modules map[string]ModuleSeeder
+}
+
+/*
+NewSeeder initializes a new Seeder without any registered module.
+*/
+func NewSeeder() *Seeder {
+	return &Seeder{
+		modules: make(map[string]ModuleSeeder),
+	}
+}
+
+/*
+Register adds the seeding capabilities of a module to the Seeder.
+*/
+func (s *Seeder) Register(module ModuleSeeder) {
+	zap.L().Info(fmt.Sprintf("Registering seeder for module %s", module.Module), zap.String("service", "seeder"))
+	s.modules[module.Module] = module
+}
+
+/*
+Seed loads all the fixtures available for the given environment from the fixtures path.
+Modules without a fixture file are skipped.
+*/
+func (s *Seeder) Seed(fixturesPath string, env SeedEnv) error {
+	modules, err := s.sortByDependencies()
+	if err != nil {
+		return err
+	}
+	for _, module := range modules {
+		fixture, found, err := readFixture(fixturesPath, env, module.Module)
+		if err != nil {
+			return err
+		}
+		if !found {
+			zap.L().Info(fmt.Sprintf("No fixture found for module %s. Skip...", module.Module), zap.String("service", "seeder"))
+			continue
+		}
+		count, err := module.LoadFixture(fixture)
+		if err != nil {
+			return fmt.Errorf("module %s: %w", module.Module, err)
+		}
+		zap.L().Info(fmt.Sprintf("Seeded %d records for module %s from %s", count, module.Module, fixture.Path), zap.String("service", "seeder"))
+	}
+	return nil
+}
+
+/*
+Fake generates the given number of fake records for a specific module.
+*/
+func (s *Seeder) Fake(moduleName string, count int) error {
+	module, ok := s.modules[moduleName]
+	if !ok {
+		return fmt.Errorf("%w: %s", errModuleNotRegistered, moduleName)
+	}
+	if module.GenerateFake == nil {
+		return fmt.Errorf("%w: %s", errFakeNotSupported, moduleName)
+	}
+	stored, err := module.GenerateFake(count)
+	if err != nil {
+		return fmt.Errorf("module %s: %w", moduleName, err)
+	}
+	zap.L().Info(fmt.Sprintf("Generated %d fake records for module %s", stored, moduleName), zap.String("service", "seeder"))
+	return nil
+}
+
+/*
+Sort the registered modules so each module comes after all its dependencies.
+Modules are visited by name to keep the order stable between executions.
+*/
+func (s *Seeder) sortByDependencies() ([]ModuleSeeder, error) {
+	var names []string
+	for name := range s.modules {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var sorted []ModuleSeeder
+	visited := make(map[string]bool)
+	visiting := make(map[string]bool)
+	var visit func(name string) error
+	visit = func(name string) error {
+		if visited[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("%w: %s", errCircularDependency, name)
+		}
+		module, ok := s.modules[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", errModuleNotRegistered, name)
+		}
+		visiting[name] = true
+		for _, dependency := range module.DependsOn {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+		visited[name] = true
+		sorted = append(sorted, module)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
@@ -0,0 +1,126 @@
+package bpseed
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func newTestSeeder(modules ...ModuleSeeder) *Seeder {
+	seeder := NewSeeder()
+	for _, module := range modules {
+		seeder.Register(module)
+	}
+	return seeder
+}
+
+func moduleNames(modules []ModuleSeeder) []string {
+	var names []string
+	for _, module := range modules {
+		names = append(names, module.Module)
+	}
+	return names
+}
+
+func TestSortByDependencies(t *testing.T) {
+	t.Run("Dependencies come first", func(t *testing.T) {
+		seeder := newTestSeeder(
+			ModuleSeeder{Module: "article", DependsOn: []string{"user", "category"}},
+			ModuleSeeder{Module: "comment", DependsOn: []string{"article"}},
+			ModuleSeeder{Module: "category"},
+			ModuleSeeder{Module: "user"},
+		)
+		sorted, err := seeder.sortByDependencies()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"user", "category", "article", "comment"}
+		if names := moduleNames(sorted); !slices.Equal(names, expected) {
+			t.Errorf("expected %v, got %v", expected, names)
+		}
+	})
+	t.Run("Circular dependencies are rejected", func(t *testing.T) {
+		seeder := newTestSeeder(
+			ModuleSeeder{Module: "article", DependsOn: []string{"comment"}},
+			ModuleSeeder{Module: "comment", DependsOn: []string{"article"}},
+		)
+		if _, err := seeder.sortByDependencies(); !errors.Is(err, errCircularDependency) {
+			t.Errorf("expected a circular dependency error, got %v", err)
+		}
+	})
+	t.Run("Missing dependencies are rejected", func(t *testing.T) {
+		seeder := newTestSeeder(ModuleSeeder{Module: "article", DependsOn: []string{"user"}})
+		if _, err := seeder.sortByDependencies(); !errors.Is(err, errModuleNotRegistered) {
+			t.Errorf("expected a missing module error, got %v", err)
+		}
+	})
+}
+
+func TestFake(t *testing.T) {
+	var generated int
+	seeder := newTestSeeder(
+		ModuleSeeder{Module: "user", GenerateFake: func(count int) (int, error) {
+			generated = count
+			return count, nil
+		}},
+		ModuleSeeder{Module: "category"},
+	)
+	if err := seeder.Fake("user", 10); err != nil || generated != 10 {
+		t.Errorf("expected 10 fake records, got %d %v", generated, err)
+	}
+	if err := seeder.Fake("article", 10); !errors.Is(err, errModuleNotRegistered) {
+		t.Errorf("expected a missing module error, got %v", err)
+	}
+	if err := seeder.Fake("category", 10); !errors.Is(err, errFakeNotSupported) {
+		t.Errorf("expected a fake not supported error, got %v", err)
+	}
+}
+
+func TestFixtureDecode(t *testing.T) {
+	type item struct {
+		ID        string `json:"id"`
+		Firstname string `json:"firstname"`
+		Age       int    `json:"age"`
+	}
+	fixturesPath := t.TempDir()
+	writeFixture := func(env SeedEnv, name string, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(fixturesPath, string(env)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(fixturesPath, string(env), name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFixture(SeedEnvDev, "user.yaml", "- id: 7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d001\n  firstname: Alice\n  age: 30\n")
+	writeFixture(SeedEnvDev, "user.json", `[{"id": "ignored"}]`)
+	writeFixture(SeedEnvDemo, "user.json", `[{"id": "7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d002", "firstname": "Marco", "age": 40}]`)
+
+	cases := []struct {
+		env      SeedEnv
+		expected item
+	}{
+		{SeedEnvDev, item{ID: "7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d001", Firstname: "Alice", Age: 30}},
+		{SeedEnvDemo, item{ID: "7c1f0a52-3b7e-4f43-9a55-0c7bb1a1d002", Firstname: "Marco", Age: 40}},
+	}
+	for _, c := range cases {
+		t.Run(string(c.env), func(t *testing.T) {
+			fixture, found, err := readFixture(fixturesPath, c.env, "user")
+			if err != nil || !found {
+				t.Fatalf("expected the fixture to be found, got %t %v", found, err)
+			}
+			var items []item
+			if err := fixture.Decode(&items); err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 1 || items[0] != c.expected {
+				t.Errorf("expected %+v, got %+v", c.expected, items)
+			}
+		})
+	}
+	if _, found, err := readFixture(fixturesPath, SeedEnvTest, "user"); found || err != nil {
+		t.Errorf("expected no fixture for the test environment, got %t %v", found, err)
+	}
+}
@@ -0,0 +1,75 @@
+package bptest
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+/*
+authUserHeader is the header used by tests to authenticate a request with a minted user or API key.
+*/
+const authUserHeader = "X-Test-Auth-User"
+
+var mintedUsers sync.Map
+
+/*
+MintAuthUser creates a new authenticated user owning the given claims.
+Requests can be authenticated as this user via Authenticate, once UseTestAuth is enabled.
+*/
+func MintAuthUser(claims ...string) bpauth.AuthUser {
+	now := time.Now().UTC()
+	id := uuid.New()
+	user := bpauth.AuthUser{
+		ID:        id,
+		Email:     id.String() + "@example.com",
+		Firstname: "Test",
+		Lastname:  "User",
+		CreatedAt: now,
+		UpdatedAt: now,
+		CreatedBy: id,
+		UpdatedBy: id,
+		Claims:    claims,
+	}
+	mintedUsers.Store(id.String(), user)
+	return user
+}
+
+/*
+MintAPIKey creates a new API key of the given minted user, owning the same claims.
+Requests authenticated via Authenticate with the returned user are performed with the API key,
+as the auth system does for the tokens of API keys.
+*/
+func MintAPIKey(user bpauth.AuthUser) bpauth.AuthUser {
+	user.APIKeyID = uuid.NewString()
+	mintedUsers.Store(user.APIKeyID, user)
+	return user
+}
+
+/*
+Authenticate marks the request as performed by the given minted user, or by its API key.
+*/
+func Authenticate(request *http.Request, user bpauth.AuthUser) {
+	if user.APIKeyID != "" {
+		request.Header.Set(authUserHeader, user.APIKeyID)
+		return
+	}
+	request.Header.Set(authUserHeader, user.ID.String())
+}
+
+/*
+UseTestAuth replaces the auth system with one recognizing only minted users and API keys.
+*/
+func UseTestAuth() {
+	bpauth.SetAuthUserProvider(func(ctx *gin.Context) (bpauth.AuthUser, error) {
+		value, ok := mintedUsers.Load(ctx.GetHeader(authUserHeader))
+		if !ok {
+			return bpauth.AuthUser{}, nil
+		}
+		return value.(bpauth.AuthUser), nil
+	})
+}
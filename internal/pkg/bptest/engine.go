@@ -0,0 +1,113 @@
+package bptest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpcache"
+	"github.com/besasch88/blueprint/internal/pkg/bpenv"
+	"github.com/besasch88/blueprint/internal/pkg/bpidempotency"
+	"github.com/besasch88/blueprint/internal/pkg/bpopenapi"
+	"github.com/besasch88/blueprint/internal/pkg/bppubsub"
+	"github.com/besasch88/blueprint/internal/pkg/bpquota"
+	"github.com/besasch88/blueprint/internal/pkg/bpratelimit"
+	"github.com/besasch88/blueprint/internal/pkg/bprouter"
+	"github.com/gin-gonic/gin"
+)
+
+/*
+NewEnvs returns the configuration used by tests, with limits high enough
+to never be reached.
+*/
+func NewEnvs() *bpenv.Envs {
+	return &bpenv.Envs{
+		AppMode:                              bpenv.TestMode,
+		AppMaxBodyBytes:                      1048576,
+		AppStrictBinding:                     true,
+		AppOpenAPIValidation:                 true,
+		AppOpenAPIFile:                       "api/openapi.json",
+		AppCompressionMinBytes:               1024,
+		SearchRelevanceThreshold:             0.05,
+		RateLimitStore:                       "memory",
+		RateLimitFailureMode:                 "open",
+		RateLimitMemoryMaxKeys:               10000,
+		RateLimitAlgorithm:                   "fixed-window",
+		RateLimitHeadersStyle:                "ietf",
+		RateLimitAnonymousTimeRangeSeconds:   60,
+		RateLimitAnonymousMaxRequestsInRange: 1000,
+		RateLimitAuthUserTimeRangeSeconds:    60,
+		RateLimitAuthUserMaxRequestsInRange:  1000,
+		IdempotencyTTLSeconds:                86400,
+		CacheStore:                           "memory",
+		CacheMemoryMaxKeys:                   10000,
+		CacheTTLSeconds:                      300,
+	}
+}
+
+/*
+NewPubSubAgent returns a pub-sub agent closed at the end of the test.
+*/
+func NewPubSubAgent(t testing.TB) *bppubsub.PubSubAgent {
+	t.Helper()
+	agent := bppubsub.NewPubSubAgent()
+	t.Cleanup(agent.Close)
+	return agent
+}
+
+/*
+NewEngine builds a GIN engine wired as the webapp does: the test auth system, the request binding, the rate limit
+and the cache (on Redis stand-ins when the Redis store is configured), the quotas and the idempotency keys
+on Redis stand-ins and the `api/v1` group, compressing the responses and answering conditional requests,
+where the init function registers the modules.
+When enabled, requests and responses are validated against the OpenAPI document, relative to the project root. E.g.
+
+	engine := bptest.NewEngine(t, envs, func(group *gin.RouterGroup) {
+		user.Init(envs, tx, pubSubAgent, group)
+	})
+*/
+func NewEngine(t testing.TB, envs *bpenv.Envs, init func(group *gin.RouterGroup)) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	UseTestAuth()
+	bprouter.Init(int64(envs.AppMaxBodyBytes), envs.AppStrictBinding, envs.AppCursorSecret)
+	redisURI := ""
+	if envs.RateLimitStore == string(bpratelimit.RedisStore) {
+		_, redisURI = NewRedis(t)
+	}
+	bpratelimit.Init(
+		envs.RateLimitStore,
+		redisURI,
+		0,
+		envs.RateLimitFailureMode,
+		envs.RateLimitMemoryMaxKeys,
+		envs.RateLimitAlgorithm,
+		envs.RateLimitHeadersStyle,
+		envs.RateLimitPoliciesFile,
+		envs.RateLimitAnonymousTimeRangeSeconds,
+		envs.RateLimitAnonymousMaxRequestsInRange,
+		envs.RateLimitAuthUserTimeRangeSeconds,
+		envs.RateLimitAuthUserMaxRequestsInRange,
+	)
+	_, quotaRedisURI := NewRedis(t)
+	bpquota.Init(quotaRedisURI, 0, envs.QuotaFile)
+	_, idempotencyRedisURI := NewRedis(t)
+	bpidempotency.Init(idempotencyRedisURI, 0, envs.IdempotencyTTLSeconds)
+	cacheRedisURI := ""
+	if envs.CacheStore == string(bpcache.RedisStore) {
+		_, cacheRedisURI = NewRedis(t)
+	}
+	bpcache.Init(envs.CacheStore, cacheRedisURI, 0, envs.CacheMemoryMaxKeys, envs.CacheTTLSeconds)
+	engine := gin.New()
+	v1Api := engine.Group("api/v1")
+	v1Api.Use(bprouter.CompressionMiddleware(envs.AppCompressionMinBytes))
+	if envs.AppOpenAPIValidation {
+		root, err := projectRoot()
+		if err != nil {
+			t.Fatalf("unable to find the OpenAPI document: %v", err)
+		}
+		v1Api.Use(bpopenapi.ValidationMiddleware(filepath.Join(root, envs.AppOpenAPIFile), true))
+	}
+	v1Api.Use(bprouter.ConditionalMiddleware())
+	init(v1Api)
+	return engine
+}
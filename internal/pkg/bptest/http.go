@@ -0,0 +1,84 @@
+package bptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/besasch88/blueprint/internal/pkg/bpauth"
+	"github.com/gin-gonic/gin"
+)
+
+/*
+Request performs an HTTP request against the engine, optionally authenticated as the given user.
+The body, if not nil, is sent as JSON.
+*/
+func Request(t testing.TB, engine *gin.Engine, method string, path string, body any, user *bpauth.AuthUser) *httptest.ResponseRecorder {
+	t.Helper()
+	return RequestWithHeaders(t, engine, method, path, body, user, nil)
+}
+
+/*
+RequestWithHeaders performs an HTTP request as Request does, adding the given headers.
+*/
+func RequestWithHeaders(t testing.TB, engine *gin.Engine, method string, path string, body any, user *bpauth.AuthUser, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("unable to marshal request body: %v", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	if user != nil {
+		Authenticate(request, *user)
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+/*
+AssertJSON checks the status code of the response and that its body is equivalent
+to the expected JSON, regardless of keys order and formatting.
+*/
+func AssertJSON(t testing.TB, response *httptest.ResponseRecorder, expectedStatus int, expectedBody string) {
+	t.Helper()
+	if response.Code != expectedStatus {
+		t.Fatalf("expected status %d, got %d with body %s", expectedStatus, response.Code, response.Body.String())
+	}
+	var expected, actual interface{}
+	if err := json.Unmarshal([]byte(expectedBody), &expected); err != nil {
+		t.Fatalf("invalid expected JSON: %v", err)
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &actual); err != nil {
+		t.Fatalf("response body is not a valid JSON: %v: %s", err, response.Body.String())
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected body %s, got %s", expectedBody, response.Body.String())
+	}
+}
+
+/*
+DecodeJSON checks the status code of the response and decodes its body into the given object.
+*/
+func DecodeJSON(t testing.TB, response *httptest.ResponseRecorder, expectedStatus int, obj any) {
+	t.Helper()
+	if response.Code != expectedStatus {
+		t.Fatalf("expected status %d, got %d with body %s", expectedStatus, response.Code, response.Body.String())
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), obj); err != nil {
+		t.Fatalf("response body is not a valid JSON: %v: %s", err, response.Body.String())
+	}
+}
@@ -0,0 +1,52 @@
+package bptest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+/*
+Main runs the tests of a package sharing a single test database across all of them.
+It is meant to be called from TestMain. When Postgres is not available, the database is left nil
+and the tests requiring it are skipped via RequireDatabase. E.g.
+
+	var testDatabase *bptest.Database
+
+	func TestMain(m *testing.M) {
+		os.Exit(bptest.Main(m, &testDatabase))
+	}
+*/
+func Main(m *testing.M, database **Database) int {
+	gin.SetMode(gin.TestMode)
+	db, err := NewDatabase()
+	if err != nil {
+		if !errors.Is(err, ErrPostgresUnavailable) {
+			fmt.Fprintf(os.Stderr, "bptest: unable to start test database: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "bptest: %v, database tests will be skipped\n", err)
+	}
+	*database = db
+	code := m.Run()
+	if db != nil {
+		db.Close()
+	}
+	return code
+}
+
+/*
+RequireDatabase skips the test if the test database is not available
+and returns a transaction rolled back at the end of the test.
+*/
+func RequireDatabase(t testing.TB, database *Database) *gorm.DB {
+	t.Helper()
+	if database == nil {
+		t.Skip("test database not available")
+	}
+	return database.Tx(t)
+}
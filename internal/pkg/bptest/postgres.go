@@ -0,0 +1,216 @@
+package bptest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+/*
+PostgresDsnEnv is the environment variable to set in order to run tests against an existing
+Postgres database instead of a disposable one. The database must be empty, migrations are applied on it.
+*/
+const PostgresDsnEnv = "BP_TEST_POSTGRES_DSN"
+
+/*
+ErrPostgresUnavailable is returned when neither an existing database is provided
+nor the Postgres binaries are available on the machine.
+*/
+var ErrPostgresUnavailable = errors.New("postgres-unavailable")
+
+/*
+Database represents a Postgres database dedicated to tests, with all the migrations applied.
+When the database is disposable, it is removed once closed.
+*/
+type Database struct {
+	storage *gorm.DB
+	dataDir string
+	pgCtl   string
+}
+
+/*
+NewDatabase starts a disposable Postgres server by leveraging the locally available binaries
+(initdb and pg_ctl), or connects to the database defined in the BP_TEST_POSTGRES_DSN env variable.
+All the migrations found in the `scripts/migrations` folder are applied.
+*/
+func NewDatabase() (*Database, error) {
+	database := &Database{}
+	dsn := os.Getenv(PostgresDsnEnv)
+	if dsn == "" {
+		var err error
+		dsn, err = database.startServer()
+		if err != nil {
+			database.Close()
+			return nil, err
+		}
+	}
+	storage, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+	database.storage = storage
+	if err := database.applyMigrations(); err != nil {
+		database.Close()
+		return nil, err
+	}
+	return database, nil
+}
+
+/*
+Storage returns the connection to the test database, outside of any transaction.
+*/
+func (d *Database) Storage() *gorm.DB {
+	return d.storage
+}
+
+/*
+Tx opens a new transaction that is rolled back at the end of the test,
+so each test starts from a clean database.
+*/
+func (d *Database) Tx(t testing.TB) *gorm.DB {
+	t.Helper()
+	tx := d.storage.Begin()
+	if tx.Error != nil {
+		t.Fatalf("unable to open test transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() {
+		tx.Rollback()
+	})
+	return tx
+}
+
+/*
+Close the connection and, in case of a disposable server, stop it and remove its data.
+*/
+func (d *Database) Close() {
+	if d.storage != nil {
+		if sqlDB, err := d.storage.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	if d.dataDir != "" {
+		if d.pgCtl != "" {
+			exec.Command(d.pgCtl, "-D", d.dataDir, "-m", "immediate", "-w", "stop").Run()
+		}
+		os.RemoveAll(d.dataDir)
+	}
+}
+
+/*
+Initialize and start a new Postgres server in a temporary folder, listening on a free local port.
+*/
+func (d *Database) startServer() (string, error) {
+	initDb, err := lookPostgresBinary("initdb")
+	if err != nil {
+		return "", err
+	}
+	pgCtl, err := lookPostgresBinary("pg_ctl")
+	if err != nil {
+		return "", err
+	}
+	dataDir, err := os.MkdirTemp("", "bptest-postgres-")
+	if err != nil {
+		return "", err
+	}
+	d.dataDir = dataDir
+	port, err := freePort()
+	if err != nil {
+		return "", err
+	}
+	if output, err := exec.Command(initDb, "-D", dataDir, "-U", "blueprint", "--auth=trust", "--no-sync").CombinedOutput(); err != nil {
+		return "", fmt.Errorf("initdb failed: %w: %s", err, output)
+	}
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off", port, dataDir)
+	if output, err := exec.Command(pgCtl, "-D", dataDir, "-o", options, "-l", filepath.Join(dataDir, "server.log"), "-w", "start").CombinedOutput(); err != nil {
+		return "", fmt.Errorf("pg_ctl start failed: %w: %s", err, output)
+	}
+	d.pgCtl = pgCtl
+	return fmt.Sprintf("host=127.0.0.1 user=blueprint dbname=postgres port=%d sslmode=disable", port), nil
+}
+
+/*
+Apply all the `up` migrations in their version order.
+*/
+func (d *Database) applyMigrations() error {
+	root, err := projectRoot()
+	if err != nil {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(root, "scripts", "migrations", "*.up.sql"))
+	if err != nil {
+		return err
+	}
+	slices.Sort(files)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := d.storage.Exec(string(content)).Error; err != nil {
+			return fmt.Errorf("migration %s failed: %w", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
+
+/*
+Look for a Postgres binary in the PATH or in the default installation folders.
+*/
+func lookPostgresBinary(name string) (string, error) {
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	candidates, _ := filepath.Glob(filepath.Join("/usr/lib/postgresql", "*", "bin", name))
+	candidates = append(candidates, filepath.Join("/opt/homebrew/bin", name), filepath.Join("/usr/local/bin", name))
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s not found", ErrPostgresUnavailable, name)
+}
+
+/*
+Find the root of the project by looking for the go.mod file in the parent folders.
+*/
+func projectRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("project root not found")
+		}
+		dir = parent
+	}
+}
+
+/*
+Ask the OS for a free local port.
+*/
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
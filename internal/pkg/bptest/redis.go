@@ -0,0 +1,30 @@
+package bptest
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+NewRedis starts an in-process Redis stand-in that is stopped at the end of the test.
+It returns the server, to manipulate time and data, and its connection URI.
+*/
+func NewRedis(t testing.TB) (*miniredis.Miniredis, string) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	return server, "redis://" + server.Addr() + "/0"
+}
+
+/*
+NewRedisClient returns a client connected to the given Redis stand-in, closed at the end of the test.
+*/
+func NewRedisClient(t testing.TB, server *miniredis.Miniredis) *redis.Client {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return client
+}
@@ -87,6 +87,13 @@ func GetTimeFromString(input string) time.Time {
 	return parsedTime.UTC()
 }
 
+/*
+GetStringFromTime transforms a Time in a RFC3339 String in UTC, the format of all the timestamps returned to clients.
+*/
+func GetStringFromTime(input time.Time) string {
+	return input.UTC().Format(time.RFC3339)
+}
+
 /*
 TransformToStrings transforms a list of inputs into list of strings represented as interfaces.
 */
@@ -125,3 +132,25 @@ func IsEmpty(data any) bool {
 	}
 	return true
 }
+
+/*
+RetryWithBackoff executes the operation until it succeeds, waiting between attempts an interval
+that doubles each time, starting from initialInterval up to maxInterval.
+When the timeout is reached, it gives up and returns the last error.
+*/
+func RetryWithBackoff(operation func() error, initialInterval time.Duration, maxInterval time.Duration, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	interval := initialInterval
+	for {
+		err := operation()
+		if err == nil {
+			return nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return err
+		}
+		time.Sleep(min(interval, remaining))
+		interval = min(interval*2, maxInterval)
+	}
+}
@@ -0,0 +1,34 @@
+package bputils
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryWithBackoff(t *testing.T) {
+	attempts := 0
+	err := RetryWithBackoff(func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("not-ready")
+		}
+		return nil
+	}, time.Millisecond, 2*time.Millisecond, time.Second)
+	if err != nil || attempts != 3 {
+		t.Errorf("expected success after 3 attempts, got %d attempts and error %v", attempts, err)
+	}
+}
+
+func TestRetryWithBackoffTimeout(t *testing.T) {
+	start := time.Now()
+	err := RetryWithBackoff(func() error {
+		return errors.New("not-ready")
+	}, time.Millisecond, 5*time.Millisecond, 50*time.Millisecond)
+	if err == nil || err.Error() != "not-ready" {
+		t.Errorf("expected last error to be returned, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected retry to stop at the deadline, took %s", elapsed)
+	}
+}
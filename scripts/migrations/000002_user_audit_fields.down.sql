@@ -0,0 +1,5 @@
+ALTER TABLE "bp_user" DROP COLUMN IF EXISTS "deleted_by";
+ALTER TABLE "bp_user" DROP COLUMN IF EXISTS "updated_by";
+ALTER TABLE "bp_user" DROP COLUMN IF EXISTS "created_by";
+
+ALTER TABLE "bp_user" ADD COLUMN "is_active" boolean NOT NULL DEFAULT true;
\ No newline at end of file
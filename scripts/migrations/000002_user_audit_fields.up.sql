@@ -0,0 +1,9 @@
+ALTER TABLE "bp_user" DROP COLUMN IF EXISTS "is_active";
+
+-- Existing users are attributed to the system user (nil UUID), the same actor of the seeder
+ALTER TABLE "bp_user" ADD COLUMN "created_by" varchar(36);
+ALTER TABLE "bp_user" ADD COLUMN "updated_by" varchar(36);
+ALTER TABLE "bp_user" ADD COLUMN "deleted_by" varchar(36);
+UPDATE "bp_user" SET "created_by" = '00000000-0000-0000-0000-000000000000', "updated_by" = '00000000-0000-0000-0000-000000000000';
+ALTER TABLE "bp_user" ALTER COLUMN "created_by" SET NOT NULL;
+ALTER TABLE "bp_user" ALTER COLUMN "updated_by" SET NOT NULL;
\ No newline at end of file
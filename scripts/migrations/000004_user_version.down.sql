@@ -0,0 +1 @@
+ALTER TABLE "bp_user" DROP COLUMN IF EXISTS "version";
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "bp_user" ADD COLUMN "version" integer NOT NULL DEFAULT 1;
\ No newline at end of file
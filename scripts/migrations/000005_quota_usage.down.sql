@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "bp_quota_usage";
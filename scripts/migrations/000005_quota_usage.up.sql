@@ -0,0 +1,10 @@
+CREATE TABLE "bp_quota_usage" (
+    "subject" varchar(255) NOT NULL,
+    "period" varchar(10) NOT NULL,
+    "period_start" date NOT NULL,
+    "units" bigint NOT NULL,
+    "updated_at" timestamp NOT NULL,
+    PRIMARY KEY ("subject", "period", "period_start")
+);
+
+CREATE INDEX "idx_bp_quota_usage_period" ON "bp_quota_usage" ("period", "period_start");